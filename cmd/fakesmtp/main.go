@@ -0,0 +1,32 @@
+// Command fakesmtp runs a fake SMTP server that prints every email it
+// receives, so notifications can be tried locally without a mail provider.
+// Point SMTP_HOST and SMTP_PORT at it.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+
+	"github.com/cmerin0/tasky/internal/notify/smtptest"
+)
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:1025", "address to listen on")
+	flag.Parse()
+
+	server, err := smtptest.Listen(*addr, func(msg smtptest.Message) {
+		fmt.Printf("--- from %s to %v\n%s\n", msg.From, msg.To, msg.Data)
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Println("Fake SMTP server listening on", server.Addr)
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt)
+	<-stop
+	server.Close()
+}
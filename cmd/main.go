@@ -1,16 +1,32 @@
 package main
 
 import (
-	"fmt"
+	"context"
+	"net"
 	"os"
+	"strings"
+	"time"
 
-	"github.com/cmerin0/tasky/internal/db"
+	"github.com/cmerin0/tasky/internal/events"
 	"github.com/cmerin0/tasky/internal/handlers"
+	"github.com/cmerin0/tasky/internal/inbox"
+	"github.com/cmerin0/tasky/internal/jobs"
+	"github.com/cmerin0/tasky/internal/middleware"
+	"github.com/cmerin0/tasky/internal/notify"
+	"github.com/cmerin0/tasky/internal/openapi"
+	"github.com/cmerin0/tasky/internal/outbox"
+	"github.com/cmerin0/tasky/internal/rpc"
+	"github.com/cmerin0/tasky/internal/scheduler"
+	"github.com/cmerin0/tasky/internal/storage"
+	"github.com/cmerin0/tasky/internal/store"
+	"github.com/cmerin0/tasky/internal/webhooks"
 
+	"github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/log"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/joho/godotenv"
+	"google.golang.org/grpc"
 )
 
 func main() {
@@ -19,62 +35,279 @@ func main() {
 		_ = godotenv.Load()
 	}
 
-	// Constructing the MongoDB URI
-	mongoURI := fmt.Sprintf("mongodb://%s:%s@%s:%s/%s?authSource=admin",
-		os.Getenv("MONGO_USERNAME"),
-		os.Getenv("MONGO_PASSWORD"),
-		os.Getenv("MONGO_HOST"),
-		os.Getenv("MONGO_PORT"),
-		os.Getenv("MONGO_DBNAME"),
-	)
+	// Users and tasks are stored in the backend set in STORAGE, MongoDB by default
+	repo, err := storage.Open(os.Getenv("STORAGE"))
+	if err != nil {
+		log.Fatal("Error opening storage: ", err)
+	}
 
-	// Connect to the database
-	// Note: The ConnectDB function should be called only once
-	// to avoid multiple connections to the database.
-	log.Info("Connecting to MongoDB...")
-	db.ConnectDB(mongoURI)
+	// Saved views are stored by any backend implementing them, the
+	// features in storage.MongoOnly need MongoDB
+	h := handlers.New(repo, repo)
+	if views, ok := repo.(store.ViewRepository); ok {
+		h.Views = views
+	}
+	mongoRepo, onMongo := repo.(*store.Mongo)
+	queue := jobs.New()
+	if onMongo {
+		h.Notifications = mongoRepo
+		h.Webhooks = webhooks.NewRepository()
+		h.Jobs = queue
+		h.Events = events.ChangeStreams{}
+	} else {
+		log.Warn("Without MongoDB these are disabled: ", strings.Join(storage.MongoOnly, "; "))
+	}
 
 	// Then create the app
 	app := fiber.New()
 	app.Use(logger.New())
 
 	// Routes setup
-	setupRoutes(app)
+	setupRoutes(app, h, onMongo)
+
+	if onMongo {
+		// Relay outbox events to webhooks, email and in-app notifications
+		notifier := notify.New(emailSender(), repo, reminderLead())
+		go outbox.Run(context.Background(), webhooks.Fanout, notifier.HandleEvent, inbox.HandleEvent)
+
+		// Background jobs, each type with its own limits
+		queue.Register(notify.JobAssignedEmail, jobs.Type{Handler: notifier.SendAssigned, Concurrency: 4})
+		queue.Register(webhooks.JobDeliver, jobs.Type{Handler: webhooks.Deliver, Concurrency: 4, MaxAttempts: webhooks.MaxAttempts, OnRetry: webhooks.Reset})
+		go func() {
+			if err := webhooks.Requeue(context.Background()); err != nil {
+				log.Error("Error requeuing webhook deliveries: ", err)
+			}
+		}()
+		go queue.Run(context.Background())
+
+		// Due date reminders and digests run on one replica at a time
+		sched := scheduler.New()
+		sched.Every("due-reminders", notify.SweepInterval, notifier.RemindDue)
+		sched.Every("digests", notify.SweepInterval, notifier.SendDigests)
+		go sched.Run(context.Background())
+	}
+
+	// The gRPC API listens on its own port next to Fiber
+	if port := os.Getenv("GRPC_PORT"); port != "" {
+		go serveGRPC(port, rpc.NewServer(repo, repo))
+	}
 
 	// Start the server
 	log.Info("Starting server on port ", os.Getenv("APP_PORT"))
 	log.Info(app.Listen(":" + os.Getenv("APP_PORT")))
 }
 
-func setupRoutes(app *fiber.App) {
+// emailSender sends emails through the SMTP server in SMTP_HOST,
+// without one emails are only logged
+func emailSender() notify.Sender {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		log.Warn("SMTP_HOST is not set, emails will only be logged")
+		return notify.LogSender{}
+	}
+
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "587"
+	}
+	return notify.NewSMTPSender(notify.SMTPConfig{
+		Addr:     net.JoinHostPort(host, port),
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     os.Getenv("SMTP_FROM"),
+	})
+}
+
+// reminderLead is how long before their due date tasks are reminded,
+// set in REMINDER_LEAD as a duration such as 24h or 90m
+func reminderLead() time.Duration {
+	lead := os.Getenv("REMINDER_LEAD")
+	if lead == "" {
+		return notify.DefaultRemindBefore
+	}
+	duration, err := time.ParseDuration(lead)
+	if err != nil || duration <= 0 {
+		log.Fatal("Invalid REMINDER_LEAD: ", lead)
+	}
+	return duration
+}
+
+// adminUsers returns the IDs of the users allowed on the admin routes,
+// listed in ADMIN_USER_IDS separated by commas
+func adminUsers() []string {
+	var admins []string
+	for _, id := range strings.Split(os.Getenv("ADMIN_USER_IDS"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			admins = append(admins, id)
+		}
+	}
+	if len(admins) == 0 {
+		log.Warn("ADMIN_USER_IDS is not set, the admin routes refuse every request")
+	}
+	return admins
+}
+
+func serveGRPC(port string, server *grpc.Server) {
+	listener, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		log.Fatal("Error listening for gRPC: ", err)
+	}
+
+	log.Info("Starting gRPC server on port ", port)
+	if err := server.Serve(listener); err != nil {
+		log.Fatal("gRPC server stopped: ", err)
+	}
+}
+
+// v1 users and tasks are deprecated in favour of v2 and retired at the sunset
+var (
+	v1DeprecatedSince = time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+	v1Sunset          = time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC)
+)
+
+// setupRoutes registers the routes, those of the optional repositories only
+// when h has them. Idempotency keys are only stored when onMongo.
+func setupRoutes(app *fiber.App, h *handlers.Handlers, onMongo bool) {
 
 	// Main Route
 	app.Get("/", func(c *fiber.Ctx) error {
 		return c.SendString("Welcome to Tasky API")
 	})
 
-	// Main API group
-	api := app.Group("/api/v1")
+	// Versioned API groups, requests are counted per version so we can
+	// tell when an old one is no longer used.
+	// POST requests can be retried safely with an Idempotency-Key on MongoDB.
+	api := app.Group("/api/v1", middleware.APIVersion("v1"))
+	apiV2 := app.Group("/api/v2", middleware.APIVersion("v2"))
+	for _, group := range []fiber.Router{api, apiV2} {
+		if os.Getenv("OPENAPI_VALIDATION") == "true" {
+			// Responses are only checked in tests, a drifting response becomes a 500
+			group.Use(middleware.OpenAPIValidation(middleware.OpenAPIConfig{
+				Responses: os.Getenv("GO_ENV") == "test",
+			}))
+		}
+		if onMongo {
+			group.Use(middleware.Idempotency())
+		}
+	}
+
+	// v1 user and task endpoints replaced by v2 announce their sunset
+	deprecated := middleware.Deprecation(middleware.DeprecationConfig{
+		Since:  v1DeprecatedSince,
+		Sunset: v1Sunset,
+		Successor: func(path string) string {
+			return strings.Replace(path, "/api/v1/", "/api/v2/", 1)
+		},
+	})
+
+	// API documentation
+	app.Get("/docs", openapi.SwaggerUI)
+	app.Get("/docs/:asset", openapi.SwaggerAsset)
+	api.Get("/openapi.json", openapi.Spec)
+
+	// Prometheus metrics
+	app.Get("/metrics", handlers.Metrics)
 
 	// Health check routes
 	app.Get("/health", handlers.Healthcheck)
-	app.Get("/readyz", handlers.ReadinessProbe)
+	app.Get("/readyz", h.ReadinessProbe)
 	app.Get("/healthz", handlers.LivenessProbe)
 
+	// GraphQL over users and tasks
+	app.Get("/graphql", h.GraphQLQuery)
+	app.Post("/graphql", h.GraphQL)
+
+	// Search routes
+	api.Get("/search", h.SearchTasks)
+
 	// User routes
 	users := api.Group("/users")
-	users.Get("/", handlers.GetUsers)
-	users.Post("/", handlers.CreateUser)
-	users.Get("/:userId", handlers.GetUser)
-	users.Put("/:userId", handlers.UpdateUser)
-	users.Delete("/:userId", handlers.DeleteUser)
+	users.Get("/", deprecated, h.GetUsers)
+	users.Post("/", deprecated, h.CreateUser)
+	users.Get("/:userId", deprecated, h.GetUser)
+	users.Put("/:userId", deprecated, h.UpdateUser)
+	users.Patch("/:userId", h.PatchUser)
+	users.Delete("/:userId", deprecated, h.DeleteUser)
 
 	// Task routes
 	tasks := api.Group("/tasks")
-	tasks.Get("/", handlers.ListTasks)
-	tasks.Post("/", handlers.CreateTask)
-	tasks.Get("/:taskId", handlers.GetTask)
-	tasks.Get("/user/:userId", handlers.GetUserTasks)
-	tasks.Put("/:taskId", handlers.UpdateTask)
-	tasks.Delete("/:taskId", handlers.DeleteTask)
+	tasks.Get("/", deprecated, h.ListTasks)
+	tasks.Post("/", deprecated, h.CreateTask)
+	tasks.Post("/bulk", h.BulkTasks)
+	tasks.Get("/:taskId", deprecated, h.GetTask)
+	tasks.Get("/user/:userId", h.GetUserTasks)
+	tasks.Put("/:taskId", deprecated, h.UpdateTask)
+	tasks.Patch("/:taskId", h.PatchTask)
+	tasks.Delete("/:taskId", deprecated, h.DeleteTask)
+	tasks.Get("/:taskId/history", h.GetTaskHistory)
+	tasks.Post("/:taskId/history/:historyId/revert", h.RevertTask)
+
+	// v2 user routes
+	usersV2 := apiV2.Group("/users")
+	usersV2.Get("/", h.ListUsersV2)
+	usersV2.Post("/", h.CreateUserV2)
+	usersV2.Get("/:userId", h.GetUserV2)
+	usersV2.Put("/:userId", h.UpdateUserV2)
+	usersV2.Delete("/:userId", h.DeleteUserV2)
+
+	// v2 task routes
+	tasksV2 := apiV2.Group("/tasks")
+	tasksV2.Get("/", h.ListTasksV2)
+	tasksV2.Post("/", h.CreateTaskV2)
+	tasksV2.Get("/:taskId", h.GetTaskV2)
+	tasksV2.Put("/:taskId", h.UpdateTaskV2)
+	tasksV2.Delete("/:taskId", h.DeleteTaskV2)
+
+	// Real-time task events
+	if h.Events != nil {
+		api.Get("/events", h.TaskEvents)
+		api.Get("/events/ws", websocket.New(h.TaskEventsSocket))
+	}
+
+	if h.Notifications != nil {
+		// Notification preferences and task watches
+		users.Get("/:userId/notification-preferences", h.GetNotificationPreferences)
+		users.Put("/:userId/notification-preferences", h.UpdateNotificationPreferences)
+		tasks.Put("/:taskId/watch", h.WatchTask)
+		tasks.Delete("/:taskId/watch", h.UnwatchTask)
+
+		// In-app notification routes
+		notifications := api.Group("/notifications")
+		notifications.Get("/", h.GetNotifications)
+		notifications.Post("/read-all", h.MarkAllNotificationsRead)
+		notifications.Post("/:notificationId/read", h.MarkNotificationRead)
+	}
+
+	// Webhook routes
+	if h.Webhooks != nil {
+		hooks := api.Group("/webhooks")
+		hooks.Get("/", h.GetWebhooks)
+		hooks.Post("/", h.CreateWebhook)
+		hooks.Get("/:webhookId", h.GetWebhook)
+		hooks.Put("/:webhookId", h.UpdateWebhook)
+		hooks.Delete("/:webhookId", h.DeleteWebhook)
+		hooks.Get("/:webhookId/deliveries", h.GetWebhookDeliveries)
+		hooks.Get("/:webhookId/deliveries/:deliveryId", h.GetWebhookDelivery)
+		hooks.Post("/:webhookId/deliveries/:deliveryId/redeliver", h.RedeliverWebhook)
+	}
+
+	// Background job admin routes, only for the users in ADMIN_USER_IDS
+	if h.Jobs != nil {
+		adminJobs := api.Group("/admin/jobs", middleware.Admin(adminUsers()))
+		adminJobs.Get("/", h.GetJobs)
+		adminJobs.Get("/:jobId", h.GetJob)
+		adminJobs.Post("/:jobId/retry", h.RetryJob)
+		adminJobs.Post("/:jobId/cancel", h.CancelJob)
+	}
+
+	// Saved view routes
+	if h.Views != nil {
+		views := api.Group("/views")
+		views.Get("/", h.GetViews)
+		views.Post("/", h.CreateView)
+		views.Get("/:viewId", h.GetView)
+		views.Put("/:viewId", h.UpdateView)
+		views.Delete("/:viewId", h.DeleteView)
+		views.Get("/:viewId/tasks", h.GetViewTasks)
+	}
 }
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/cmerin0/tasky/internal/handlers"
+	"github.com/cmerin0/tasky/internal/openapi"
+	"github.com/cmerin0/tasky/internal/replay"
+	"github.com/cmerin0/tasky/internal/store/memory"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// appTransport hands requests to a Fiber app in process
+type appTransport struct {
+	app *fiber.App
+}
+
+func (t appTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.app.Test(req, -1)
+}
+
+// TestReplayExamples replays the built in examples of cmd/replay against
+// the routes on the memory storage, so a broken example or endpoint fails
+// the build. Requests and responses are validated against the spec.
+func TestReplayExamples(t *testing.T) {
+	t.Setenv("OPENAPI_VALIDATION", "true")
+	t.Setenv("GO_ENV", "test")
+
+	repo := memory.New()
+	h := handlers.New(repo, repo)
+	h.Views = repo
+	app := fiber.New()
+	setupRoutes(app, h, false)
+
+	examples, err := replay.Examples(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := openapi.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &http.Client{Transport: appTransport{app: app}}
+	vars := replay.Vars()
+	for _, example := range examples {
+		for _, problem := range replay.Replay(client, doc, "http://tasky.test", example, vars) {
+			t.Errorf("%s: %s", example.Name, problem)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+// Command openapi-gen generates the OpenAPI document of the tasky API.
+//
+// Routes are read from setupRoutes in cmd/main.go and documented from the
+// annotations (@Summary, @Param, @Success, @Router, ...) on their handlers.
+// Every route must have a documented handler whose @Router matches it,
+// otherwise the command fails and lists the offending routes.
+//
+//	go run ./cmd/openapi-gen          # write internal/openapi/openapi.json
+//	go run ./cmd/openapi-gen -check   # fail if openapi.json is out of date
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/cmerin0/tasky/internal/openapi/gen"
+)
+
+func main() {
+	check := flag.Bool("check", false, "verify the committed document is up to date instead of writing it")
+	flag.Parse()
+
+	root, err := moduleRoot()
+	if err != nil {
+		fail(err)
+	}
+
+	out, problems, err := gen.Render(root)
+	if err != nil {
+		fail(err)
+	}
+	if len(problems) > 0 {
+		for _, problem := range problems {
+			fmt.Fprintln(os.Stderr, problem)
+		}
+		fail(fmt.Errorf("%d route(s) are not documented", len(problems)))
+	}
+
+	path := filepath.Join(root, gen.SpecFile)
+	if *check {
+		current, err := os.ReadFile(path)
+		if err != nil || !bytes.Equal(current, out) {
+			fail(errors.New(gen.SpecFile + " is out of date, run go generate ./internal/openapi"))
+		}
+		return
+	}
+
+	if err := os.WriteFile(path, out, 0o644); err != nil {
+		fail(err)
+	}
+}
+
+// moduleRoot finds the directory holding go.mod, starting from the working directory
+func moduleRoot() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return dir, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", errors.New("go.mod not found")
+		}
+		dir = parent
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "openapi-gen:", err)
+	os.Exit(1)
+}
@@ -0,0 +1,76 @@
+// Command replay sends the example requests in internal/replay to a
+// running server and checks every exchange against the OpenAPI document.
+// go test replays the same examples in process on the memory storage.
+//
+//	go run ./cmd/replay -base http://localhost:3030
+//	go run ./cmd/replay -examples my-examples.json -run 'tasks'
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/cmerin0/tasky/internal/openapi"
+	"github.com/cmerin0/tasky/internal/replay"
+)
+
+func main() {
+	base := flag.String("base", "http://localhost:3030", "base URL of the server")
+	file := flag.String("examples", "", "examples file, defaults to the built in examples")
+	only := flag.String("run", "", "only replay examples whose name matches this regular expression")
+	flag.Parse()
+
+	var data []byte
+	if *file != "" {
+		var err error
+		if data, err = os.ReadFile(*file); err != nil {
+			fail(err)
+		}
+	}
+	examples, err := replay.Examples(data)
+	if err != nil {
+		fail(err)
+	}
+	filter, err := regexp.Compile(*only)
+	if err != nil {
+		fail(err)
+	}
+
+	doc, err := openapi.Load()
+	if err != nil {
+		fail(err)
+	}
+
+	vars := replay.Vars()
+	client := &http.Client{Timeout: 10 * time.Second}
+	failed := 0
+
+	for _, example := range examples {
+		if !filter.MatchString(example.Name) {
+			continue
+		}
+		problems := replay.Replay(client, doc, *base, example, vars)
+		if len(problems) == 0 {
+			fmt.Printf("PASS %s\n", example.Name)
+			continue
+		}
+		failed++
+		fmt.Printf("FAIL %s\n", example.Name)
+		for _, problem := range problems {
+			fmt.Printf("     %s\n", problem)
+		}
+	}
+
+	if failed > 0 {
+		fail(fmt.Errorf("%d example(s) failed", failed))
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "replay:", err)
+	os.Exit(1)
+}
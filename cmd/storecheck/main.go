@@ -0,0 +1,47 @@
+// Command storecheck runs the storage conformance checks of
+// internal/store/storetest against a backend, to verify it behaves like
+// the others. The backend is chosen like the server does, from STORAGE
+// (and .env), or with -storage.
+//
+// Checks only touch the users and tasks they create, but they leave them
+// behind: point it at a scratch database.
+//
+//	go run ./cmd/storecheck                   # the configured backend
+//	go run ./cmd/storecheck -storage memory
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/cmerin0/tasky/internal/storage"
+	"github.com/cmerin0/tasky/internal/store/storetest"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	_ = godotenv.Load()
+	spec := flag.String("storage", os.Getenv("STORAGE"), "storage to check, as set in STORAGE")
+	flag.Parse()
+
+	repo, err := storage.Open(*spec)
+	if err != nil {
+		fail(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	if err := storetest.Check(ctx, repo); err != nil {
+		fail(err)
+	}
+	fmt.Println("storecheck: ok")
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "storecheck:", err)
+	os.Exit(1)
+}
@@ -0,0 +1,32 @@
+package db
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// changeStreamCollections are watched with the state of documents before a change
+var changeStreamCollections = []string{"tasks", "notifications"}
+
+// EnableChangeStreamImages makes the server record the state of documents
+// before they change, so change streams can tell whose task or notification
+// was deleted.
+// It needs MongoDB 6 or later on a replica set, anything else only logs
+// a warning since events then lack the state of deleted documents.
+func EnableChangeStreamImages() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for _, name := range changeStreamCollections {
+		err := GetCollection(name).Database().RunCommand(ctx, bson.D{
+			{Key: "collMod", Value: name},
+			{Key: "changeStreamPreAndPostImages", Value: bson.M{"enabled": true}},
+		}).Err()
+		if err != nil {
+			log.Println("Failed to enable change stream images on ", name, ": ", err)
+		}
+	}
+}
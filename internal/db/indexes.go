@@ -0,0 +1,99 @@
+package db
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// indexes lists the indexes the application relies on, per collection
+var indexes = map[string][]mongo.IndexModel{
+	"tasks": {
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "_id", Value: 1}}},
+		// Due date reminders look for open tasks by due date
+		{Keys: bson.D{{Key: "completed", Value: 1}, {Key: "dueAt", Value: 1}}},
+		{
+			// Full text search over tasks, matches in titles rank higher
+			Keys: bson.D{{Key: "title", Value: "text"}, {Key: "description", Value: "text"}},
+			Options: options.Index().
+				SetName("tasks_text").
+				SetWeights(bson.D{{Key: "title", Value: 10}, {Key: "description", Value: 1}}),
+		},
+	},
+	"task_history": {
+		{Keys: bson.D{{Key: "taskId", Value: 1}, {Key: "timestamp", Value: -1}}},
+	},
+	"idempotency_keys": {
+		// Stored responses are only replayed for a day
+		{Keys: bson.D{{Key: "createdAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(24 * 60 * 60)},
+	},
+	"outbox": {
+		{Keys: bson.D{{Key: "dispatchedAt", Value: 1}, {Key: "_id", Value: 1}}},
+		// Dispatched events are kept for a week, pending ones until relayed
+		{Keys: bson.D{{Key: "dispatchedAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(7 * 24 * 60 * 60)},
+	},
+	"webhooks": {
+		{Keys: bson.D{{Key: "ownerId", Value: 1}, {Key: "events", Value: 1}, {Key: "active", Value: 1}}},
+	},
+	"webhook_deliveries": {
+		{Keys: bson.D{{Key: "webhookId", Value: 1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}}},
+		// An outbox event is fanned out to each webhook only once
+		{Keys: bson.D{{Key: "fanoutKey", Value: 1}}, Options: options.Index().SetUnique(true).SetSparse(true)},
+	},
+	"notification_preferences": {
+		{Keys: bson.D{{Key: "digest", Value: 1}, {Key: "digestHour", Value: 1}}},
+	},
+	"jobs": {
+		// Workers claim the job of their type that is due first
+		{Keys: bson.D{{Key: "type", Value: 1}, {Key: "status", Value: 1}, {Key: "runAt", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "key", Value: 1}}, Options: options.Index().SetUnique(true).SetSparse(true)},
+		// Succeeded jobs are kept for a week, dead and cancelled ones until deleted
+		{
+			Keys: bson.D{{Key: "finishedAt", Value: 1}},
+			Options: options.Index().
+				SetExpireAfterSeconds(7 * 24 * 60 * 60).
+				SetPartialFilterExpression(bson.M{"status": "succeeded"}),
+		},
+	},
+	"notifications": {
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "read", Value: 1}}},
+		// A notification is added once per user and outbox event
+		{Keys: bson.D{{Key: "eventId", Value: 1}, {Key: "userId", Value: 1}}, Options: options.Index().SetUnique(true)},
+		// Retention: read notifications are kept 30 days, unread ones 90
+		{Keys: bson.D{{Key: "readAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(30 * 24 * 60 * 60)},
+		{Keys: bson.D{{Key: "createdAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(90 * 24 * 60 * 60)},
+	},
+	"task_watches": {
+		{Keys: bson.D{{Key: "taskId", Value: 1}, {Key: "userId", Value: 1}}, Options: options.Index().SetUnique(true)},
+	},
+	"notifications_sent": {
+		// Keys only need to outlive the windows reminders are sent in
+		{Keys: bson.D{{Key: "sentAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(30 * 24 * 60 * 60)},
+	},
+	"views": {
+		{Keys: bson.D{{Key: "ownerId", Value: 1}}},
+		{Keys: bson.D{{Key: "visibility", Value: 1}, {Key: "sharedWith", Value: 1}}},
+	},
+}
+
+// EnsureIndexes creates the indexes the application needs.
+// Creating an index that already exists is a no-op, so it is safe to
+// call on every start.
+func EnsureIndexes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	for name, models := range indexes {
+		if _, err := GetCollection(name).Indexes().CreateMany(ctx, models); err != nil {
+			log.Fatal("Failed to create indexes on ", name, ": ", err)
+		}
+	}
+	log.Println("MongoDB indexes ensured")
+}
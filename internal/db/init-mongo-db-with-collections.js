@@ -48,3 +48,4 @@ db.createCollection("tasks", {
     }
 });
 
+db.createCollection("task_history");
@@ -0,0 +1,188 @@
+// Package events turns MongoDB change streams on the tasks collection into
+// task events. Change streams are served by the replica set, so every app
+// replica sees every write, and each event carries the resume token a
+// reconnecting client sends back to continue where it left off.
+package events
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/cmerin0/tasky/internal/models"
+	"github.com/cmerin0/tasky/internal/store"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Event types, the same as the webhook events
+const (
+	TaskCreated = models.EventTaskCreated
+	TaskUpdated = models.EventTaskUpdated
+	TaskDeleted = models.EventTaskDeleted
+)
+
+var (
+	ErrInvalidResumeToken = errors.New("invalid resume token")
+	// ErrResumeTokenExpired is returned when the oplog no longer holds the
+	// position of a resume token, the client has to reload and start over
+	ErrResumeTokenExpired = errors.New("resume token is too old, reload and subscribe again")
+)
+
+// changeStreamHistoryLost is the server error code of a resume token
+// that fell off the oplog
+const changeStreamHistoryLost = 286
+
+// Event is a change to a task
+type Event struct {
+	// ID is the resume token of the event
+	ID     string             `json:"id"`
+	Type   string             `json:"type"`
+	TaskID primitive.ObjectID `json:"taskId"`
+	// Task is the task after the change, nil for deletions
+	Task *models.Task `json:"task"`
+	Time time.Time    `json:"time"`
+}
+
+// Options select the events of a subscription
+type Options struct {
+	// UserID limits the events to the tasks of a user, like the rest of the
+	// API does for identified users. Nil receives the events of every task.
+	UserID *primitive.ObjectID
+	// ResumeAfter is the ID of the last event received, empty to start now
+	ResumeAfter string
+}
+
+// changeEvent is the part of a change stream document events are built from
+type changeEvent struct {
+	// ID is the resume token of the change itself
+	ID struct {
+		Data string `bson:"_data"`
+	} `bson:"_id"`
+	OperationType string              `bson:"operationType"`
+	ClusterTime   primitive.Timestamp `bson:"clusterTime"`
+	DocumentKey   struct {
+		ID primitive.ObjectID `bson:"_id"`
+	} `bson:"documentKey"`
+	FullDocument             *models.Task `bson:"fullDocument"`
+	FullDocumentBeforeChange *models.Task `bson:"fullDocumentBeforeChange"`
+}
+
+// Stream is an open subscription to task events
+type Stream struct {
+	changes *mongo.ChangeStream
+	userID  *primitive.ObjectID
+}
+
+// Watch opens a stream of task events
+func Watch(ctx context.Context, opts Options) (*Stream, error) {
+	match := bson.M{"operationType": bson.M{"$in": bson.A{"insert", "update", "replace", "delete"}}}
+	if opts.UserID != nil {
+		match["$or"] = bson.A{
+			bson.M{"fullDocument.userId": *opts.UserID},
+			bson.M{"fullDocumentBeforeChange.userId": *opts.UserID},
+		}
+	}
+
+	// The state before a change tells whose task was deleted,
+	// it is only recorded once db.EnableChangeStreamImages has run
+	streamOpts := options.ChangeStream().
+		SetFullDocument(options.UpdateLookup).
+		SetFullDocumentBeforeChange(options.WhenAvailable)
+	if opts.ResumeAfter != "" {
+		if !isHex(opts.ResumeAfter) {
+			return nil, ErrInvalidResumeToken
+		}
+		streamOpts.SetStartAfter(bson.M{"_data": opts.ResumeAfter})
+	}
+
+	changes, err := store.Tasks().Watch(ctx, mongo.Pipeline{{{Key: "$match", Value: match}}}, streamOpts)
+	if err != nil {
+		return nil, streamError(err)
+	}
+	return &Stream{changes: changes, userID: opts.UserID}, nil
+}
+
+// Next blocks until the next event visible to the subscriber
+func (s *Stream) Next(ctx context.Context) (Event, error) {
+	for s.changes.Next(ctx) {
+		var change changeEvent
+		if err := s.changes.Decode(&change); err != nil {
+			return Event{}, err
+		}
+		if event, ok := s.event(change); ok {
+			return event, nil
+		}
+	}
+	if err := s.changes.Err(); err != nil {
+		return Event{}, streamError(err)
+	}
+	return Event{}, ctx.Err()
+}
+
+// Close releases the change stream
+func (s *Stream) Close(ctx context.Context) error {
+	return s.changes.Close(ctx)
+}
+
+// event builds the event of a change as the subscriber sees it.
+// A task moved to another user is a deletion for its previous owner.
+func (s *Stream) event(change changeEvent) (Event, bool) {
+	event := Event{
+		ID:     change.ID.Data,
+		TaskID: change.DocumentKey.ID,
+		Time:   time.Unix(int64(change.ClusterTime.T), 0).UTC(),
+	}
+
+	switch change.OperationType {
+	case "insert":
+		event.Type = TaskCreated
+	case "update", "replace":
+		event.Type = TaskUpdated
+	case "delete":
+		event.Type = TaskDeleted
+	}
+	if event.Type != TaskDeleted {
+		event.Task = change.FullDocument
+	}
+
+	if s.userID == nil {
+		return event, true
+	}
+	after := event.Task != nil && event.Task.UserID == *s.userID
+	before := change.FullDocumentBeforeChange != nil && change.FullDocumentBeforeChange.UserID == *s.userID
+	switch {
+	case after:
+		return event, true
+	case before:
+		event.Type, event.Task = TaskDeleted, nil
+		return event, true
+	}
+	return event, false
+}
+
+// streamError maps the server errors a client can act on
+func streamError(err error) error {
+	var serverErr mongo.ServerError
+	if errors.As(err, &serverErr) && serverErr.HasErrorCode(changeStreamHistoryLost) {
+		return ErrResumeTokenExpired
+	}
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && cmdErr.Code == 9 { // FailedToParse, a malformed token
+		return ErrInvalidResumeToken
+	}
+	return err
+}
+
+// isHex reports whether s looks like a resume token, which MongoDB encodes in hex
+func isHex(s string) bool {
+	for _, r := range s {
+		if !('0' <= r && r <= '9' || 'a' <= r && r <= 'f' || 'A' <= r && r <= 'F') {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,48 @@
+package events
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Source opens event streams, so their consumers can be run without MongoDB
+type Source interface {
+	Watch(ctx context.Context, opts Options) (TaskStream, error)
+	WatchUnread(ctx context.Context, userID primitive.ObjectID) (CountStream, error)
+}
+
+// TaskStream is an open subscription to task events, such as a *Stream
+type TaskStream interface {
+	Next(ctx context.Context) (Event, error)
+	Close(ctx context.Context) error
+}
+
+// CountStream follows an unread notification count, such as an *UnreadStream
+type CountStream interface {
+	Next(ctx context.Context) (UnreadCount, error)
+	Close(ctx context.Context) error
+}
+
+// ChangeStreams is the Source reading MongoDB change streams
+type ChangeStreams struct{}
+
+var _ Source = ChangeStreams{}
+
+// Watch opens a stream of task events
+func (ChangeStreams) Watch(ctx context.Context, opts Options) (TaskStream, error) {
+	stream, err := Watch(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return stream, nil
+}
+
+// WatchUnread opens a stream of the unread notification count of a user
+func (ChangeStreams) WatchUnread(ctx context.Context, userID primitive.ObjectID) (CountStream, error) {
+	stream, err := WatchUnread(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return stream, nil
+}
@@ -0,0 +1,77 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"github.com/cmerin0/tasky/internal/store"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// NotificationsUnread is the type of the messages carrying
+// the unread notification count of a subscriber
+const NotificationsUnread = "notifications.unread"
+
+// UnreadCount is the number of unread notifications of a subscriber
+type UnreadCount struct {
+	Type   string    `json:"type"`
+	Unread int64     `json:"unread"`
+	Time   time.Time `json:"time"`
+}
+
+// UnreadStream follows the unread notification count of a user.
+// Counts are state rather than history, so unlike task events
+// they aren't resumed: a new stream starts with the current count.
+type UnreadStream struct {
+	changes *mongo.ChangeStream
+	userID  primitive.ObjectID
+	last    int64 // -1 until the first count is returned
+}
+
+// WatchUnread opens a stream of the unread notification count of a user
+func WatchUnread(ctx context.Context, userID primitive.ObjectID) (*UnreadStream, error) {
+	match := bson.M{"$or": bson.A{
+		bson.M{"fullDocument.userId": userID},
+		bson.M{"fullDocumentBeforeChange.userId": userID},
+	}}
+
+	// Pruned notifications are only matched through the state before deletion
+	streamOpts := options.ChangeStream().
+		SetFullDocument(options.UpdateLookup).
+		SetFullDocumentBeforeChange(options.WhenAvailable)
+	changes, err := store.Notifications().Watch(ctx, mongo.Pipeline{{{Key: "$match", Value: match}}}, streamOpts)
+	if err != nil {
+		return nil, streamError(err)
+	}
+	return &UnreadStream{changes: changes, userID: userID, last: -1}, nil
+}
+
+// Next returns the current count on the first call, then blocks until it changes
+func (s *UnreadStream) Next(ctx context.Context) (UnreadCount, error) {
+	for {
+		if s.last >= 0 && !s.changes.Next(ctx) {
+			if err := s.changes.Err(); err != nil {
+				return UnreadCount{}, streamError(err)
+			}
+			return UnreadCount{}, ctx.Err()
+		}
+
+		count, err := store.CountUnread(ctx, s.userID)
+		if err != nil {
+			return UnreadCount{}, err
+		}
+		if count != s.last {
+			s.last = count
+			return UnreadCount{Type: NotificationsUnread, Unread: count, Time: time.Now().UTC()}, nil
+		}
+	}
+}
+
+// Close releases the change stream
+func (s *UnreadStream) Close(ctx context.Context) error {
+	return s.changes.Close(ctx)
+}
@@ -0,0 +1,52 @@
+package filter
+
+import (
+	"regexp"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// mongoOps maps comparison operators to their MongoDB query operator
+var mongoOps = map[Op]string{
+	Eq:  "$eq",
+	Ne:  "$ne",
+	Lt:  "$lt",
+	Lte: "$lte",
+	Gt:  "$gt",
+	Gte: "$gte",
+}
+
+// ToBSON compiles an expression into a MongoDB filter.
+// A nil expression matches every document.
+func ToBSON(e Expr) bson.M {
+	switch e := e.(type) {
+	case And:
+		return bson.M{"$and": compileTerms(e.Terms)}
+	case Or:
+		return bson.M{"$or": compileTerms(e.Terms)}
+	case Not:
+		// $not only applies to operator expressions, $nor negates whole filters
+		return bson.M{"$nor": bson.A{ToBSON(e.Term)}}
+	case In:
+		return bson.M{e.Field.Name: bson.M{"$in": e.Values}}
+	case Comparison:
+		if e.Op == Match {
+			// Values are quoted, so clients can't smuggle regex syntax in
+			return bson.M{e.Field.Name: bson.M{
+				"$regex":   regexp.QuoteMeta(e.Value.(string)),
+				"$options": "i",
+			}}
+		}
+		return bson.M{e.Field.Name: bson.M{mongoOps[e.Op]: e.Value}}
+	default:
+		return bson.M{}
+	}
+}
+
+func compileTerms(terms []Expr) bson.A {
+	compiled := make(bson.A, 0, len(terms))
+	for _, term := range terms {
+		compiled = append(compiled, ToBSON(term))
+	}
+	return compiled
+}
@@ -0,0 +1,114 @@
+package filter
+
+import (
+	"bytes"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Eval reports whether a document matches an expression, for backends that
+// filter in process. value returns the value of a stored field, nil when
+// the document doesn't have it. A nil expression matches every document.
+//
+// Results are the same as with ToBSON: values only compare with values of
+// the same type, missing fields only match !=, and times are compared to
+// the millisecond.
+func Eval(e Expr, value func(field string) interface{}) bool {
+	switch e := e.(type) {
+	case And:
+		for _, term := range e.Terms {
+			if !Eval(term, value) {
+				return false
+			}
+		}
+		return true
+	case Or:
+		for _, term := range e.Terms {
+			if Eval(term, value) {
+				return true
+			}
+		}
+		return false
+	case Not:
+		return !Eval(e.Term, value)
+	case In:
+		v := value(e.Field.Name)
+		for _, candidate := range e.Values {
+			if cmp, ok := Compare(v, candidate); ok && cmp == 0 {
+				return true
+			}
+		}
+		return false
+	case Comparison:
+		v := value(e.Field.Name)
+		if e.Op == Match {
+			s, ok := v.(string)
+			return ok && strings.Contains(strings.ToLower(s), strings.ToLower(e.Value.(string)))
+		}
+		cmp, ok := Compare(v, e.Value)
+		switch e.Op {
+		case Eq:
+			return ok && cmp == 0
+		case Ne:
+			return !ok || cmp != 0
+		case Lt:
+			return ok && cmp < 0
+		case Lte:
+			return ok && cmp <= 0
+		case Gt:
+			return ok && cmp > 0
+		case Gte:
+			return ok && cmp >= 0
+		}
+		return false
+	default:
+		return true
+	}
+}
+
+// Compare orders two field values of the same type. ok is false when
+// they can't be compared, because either is nil or their types differ.
+func Compare(a, b interface{}) (cmp int, ok bool) {
+	switch a := a.(type) {
+	case string:
+		if b, isString := b.(string); isString {
+			return strings.Compare(a, b), true
+		}
+	case bool:
+		if b, isBool := b.(bool); isBool {
+			switch {
+			case a == b:
+				return 0, true
+			case b:
+				return -1, true
+			}
+			return 1, true
+		}
+	case primitive.ObjectID:
+		if b, isID := b.(primitive.ObjectID); isID {
+			return bytes.Compare(a[:], b[:]), true
+		}
+	case time.Time:
+		if b, isTime := b.(time.Time); isTime {
+			return a.Truncate(time.Millisecond).Compare(b.Truncate(time.Millisecond)), true
+		}
+	case int64:
+		return Compare(float64(a), b)
+	case float64:
+		switch b := b.(type) {
+		case int64:
+			return Compare(a, float64(b))
+		case float64:
+			switch {
+			case a < b:
+				return -1, true
+			case a > b:
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+	return 0, false
+}
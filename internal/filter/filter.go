@@ -0,0 +1,138 @@
+// Package filter implements the small query language clients use to
+// filter listings, for example:
+//
+//	completed:false AND (title~"release" OR userId in (a, b)) AND NOT description:""
+//
+// Values containing spaces or operator characters, such as RFC 3339
+// timestamps, must be double quoted. Expressions are parsed against a
+// whitelist of fields, so only known fields and operators ever reach
+// the database.
+package filter
+
+import (
+	"fmt"
+	"time"
+)
+
+// Op is a comparison operator
+type Op string
+
+const (
+	Eq    Op = ":"
+	Ne    Op = "!="
+	Lt    Op = "<"
+	Lte   Op = "<="
+	Gt    Op = ">"
+	Gte   Op = ">="
+	Match Op = "~" // Case insensitive substring match
+)
+
+// Type is the type of a filterable field
+type Type int
+
+const (
+	String Type = iota
+	Bool
+	Number
+	ObjectID
+	Time
+)
+
+// Field describes a field clients may filter on.
+// Name is the name of the field in storage.
+type Field struct {
+	Name string
+	Type Type
+}
+
+// Fields whitelists filterable fields, keyed by the name clients use
+type Fields map[string]Field
+
+// Expr is a parsed filter expression
+type Expr interface {
+	expr()
+}
+
+// And matches when every term matches
+type And struct {
+	Terms []Expr
+}
+
+// Or matches when any term matches
+type Or struct {
+	Terms []Expr
+}
+
+// Not matches when its term doesn't
+type Not struct {
+	Term Expr
+}
+
+// Comparison compares a field against a value
+type Comparison struct {
+	Field Field
+	Op    Op
+	Value interface{}
+}
+
+// In matches when a field equals any of the values
+type In struct {
+	Field  Field
+	Values []interface{}
+}
+
+func (And) expr()        {}
+func (Or) expr()         {}
+func (Not) expr()        {}
+func (Comparison) expr() {}
+func (In) expr()         {}
+
+// AllOf matches when every expression matches, nil expressions match
+// anything. It returns nil when there is nothing to match.
+func AllOf(exprs ...Expr) Expr {
+	var terms []Expr
+	for _, e := range exprs {
+		if e != nil {
+			terms = append(terms, e)
+		}
+	}
+	switch len(terms) {
+	case 0:
+		return nil
+	case 1:
+		return terms[0]
+	}
+	return And{Terms: terms}
+}
+
+// SyntaxError reports an invalid filter and where it went wrong.
+// Pos is the zero based byte offset in the filter string.
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Msg, e.Pos)
+}
+
+// operators allowed for each field type
+var allowedOps = map[Type][]Op{
+	String:   {Eq, Ne, Lt, Lte, Gt, Gte, Match},
+	Bool:     {Eq, Ne},
+	Number:   {Eq, Ne, Lt, Lte, Gt, Gte},
+	ObjectID: {Eq, Ne},
+	Time:     {Eq, Ne, Lt, Lte, Gt, Gte},
+}
+
+func opAllowed(t Type, op Op) bool {
+	for _, allowed := range allowedOps[t] {
+		if allowed == op {
+			return true
+		}
+	}
+	return false
+}
+
+// timeLayouts are the accepted formats for time values
+var timeLayouts = []string{time.RFC3339, "2006-01-02"}
@@ -0,0 +1,115 @@
+package filter
+
+import (
+	"strconv"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// describe names a token for error messages
+func (t token) describe() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of filter"
+	case tokString:
+		return strconv.Quote(t.text)
+	default:
+		return "'" + t.text + "'"
+	}
+}
+
+// operators sorted so that longer ones match first
+var operators = []string{"!=", "<=", ">=", ":", "<", ">", "~"}
+
+// isWordByte reports whether b may appear in an unquoted word
+func isWordByte(b byte) bool {
+	return !strings.ContainsRune(" \t\r\n()\",:!<>=~", rune(b))
+}
+
+// lex splits a filter into tokens, always ending with an EOF token
+func lex(input string) ([]token, error) {
+	var tokens []token
+	i := 0
+
+	for i < len(input) {
+		b := input[i]
+		switch {
+		case b == ' ' || b == '\t' || b == '\r' || b == '\n':
+			i++
+		case b == '(':
+			tokens = append(tokens, token{kind: tokLParen, text: "(", pos: i})
+			i++
+		case b == ')':
+			tokens = append(tokens, token{kind: tokRParen, text: ")", pos: i})
+			i++
+		case b == ',':
+			tokens = append(tokens, token{kind: tokComma, text: ",", pos: i})
+			i++
+		case b == '"':
+			tok, end, err := lexString(input, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, tok)
+			i = end
+		case isWordByte(b):
+			start := i
+			for i < len(input) && isWordByte(input[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokWord, text: input[start:i], pos: start})
+		default:
+			op := ""
+			for _, candidate := range operators {
+				if strings.HasPrefix(input[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, &SyntaxError{Pos: i, Msg: "unexpected character " + strconv.QuoteRune(rune(b))}
+			}
+			tokens = append(tokens, token{kind: tokOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+
+	return append(tokens, token{kind: tokEOF, pos: len(input)}), nil
+}
+
+// lexString reads a double quoted string starting at start.
+// Backslash escapes the next character.
+func lexString(input string, start int) (token, int, error) {
+	var sb strings.Builder
+	for i := start + 1; i < len(input); i++ {
+		switch input[i] {
+		case '\\':
+			if i+1 < len(input) {
+				i++
+				sb.WriteByte(input[i])
+			}
+		case '"':
+			return token{kind: tokString, text: sb.String(), pos: start}, i + 1, nil
+		default:
+			sb.WriteByte(input[i])
+		}
+	}
+	return token{}, 0, &SyntaxError{Pos: start, Msg: "unterminated string"}
+}
@@ -0,0 +1,115 @@
+package filter
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestLex(t *testing.T) {
+	tests := []struct {
+		input  string
+		tokens []token
+	}{
+		{"", []token{{kind: tokEOF}}},
+		{
+			"completed:false",
+			[]token{
+				{kind: tokWord, text: "completed"},
+				{kind: tokOp, text: ":", pos: 9},
+				{kind: tokWord, text: "false", pos: 10},
+				{kind: tokEOF, pos: 15},
+			},
+		},
+		{
+			"a!=b AND c<=d",
+			[]token{
+				{kind: tokWord, text: "a"},
+				{kind: tokOp, text: "!=", pos: 1},
+				{kind: tokWord, text: "b", pos: 3},
+				{kind: tokWord, text: "AND", pos: 5},
+				{kind: tokWord, text: "c", pos: 9},
+				{kind: tokOp, text: "<=", pos: 10},
+				{kind: tokWord, text: "d", pos: 12},
+				{kind: tokEOF, pos: 13},
+			},
+		},
+		{
+			"id in (a, b)",
+			[]token{
+				{kind: tokWord, text: "id"},
+				{kind: tokWord, text: "in", pos: 3},
+				{kind: tokLParen, text: "(", pos: 6},
+				{kind: tokWord, text: "a", pos: 7},
+				{kind: tokComma, text: ",", pos: 8},
+				{kind: tokWord, text: "b", pos: 10},
+				{kind: tokRParen, text: ")", pos: 11},
+				{kind: tokEOF, pos: 12},
+			},
+		},
+		{
+			`title~"a \"quoted\" (value)"`,
+			[]token{
+				{kind: tokWord, text: "title"},
+				{kind: tokOp, text: "~", pos: 5},
+				{kind: tokString, text: `a "quoted" (value)`, pos: 6},
+				{kind: tokEOF, pos: 28},
+			},
+		},
+		{
+			`dueAt>="2026-11-01T00:00:00Z"`,
+			[]token{
+				{kind: tokWord, text: "dueAt"},
+				{kind: tokOp, text: ">=", pos: 5},
+				{kind: tokString, text: "2026-11-01T00:00:00Z", pos: 7},
+				{kind: tokEOF, pos: 29},
+			},
+		},
+		{
+			`title:""`,
+			[]token{
+				{kind: tokWord, text: "title"},
+				{kind: tokOp, text: ":", pos: 5},
+				{kind: tokString, pos: 6},
+				{kind: tokEOF, pos: 8},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			tokens, err := lex(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(tokens, tt.tokens) {
+				t.Errorf("got %+v, want %+v", tokens, tt.tokens)
+			}
+		})
+	}
+}
+
+func TestLexErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{`title:"open`, "unterminated string at position 6"},
+		{`title:"ends with \"`, "unterminated string at position 6"},
+		{"title=x", "unexpected character '=' at position 5"},
+		{"a AND !b", "unexpected character '!' at position 6"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := lex(tt.input)
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("got error %v, want a syntax error", err)
+			}
+			if err.Error() != tt.err {
+				t.Errorf("got error %q, want %q", err, tt.err)
+			}
+		})
+	}
+}
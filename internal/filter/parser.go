@@ -0,0 +1,241 @@
+package filter
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MaxLength is the longest filter accepted by Parse
+const MaxLength = 1024
+
+// maxDepth bounds how deeply expressions may nest
+const maxDepth = 32
+
+// Parse parses a filter expression, accepting only the given fields.
+// An empty filter parses to a nil expression.
+func Parse(input string, fields Fields) (Expr, error) {
+	if len(input) > MaxLength {
+		return nil, &SyntaxError{Pos: MaxLength, Msg: "filter is too long"}
+	}
+
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 1 {
+		return nil, nil
+	}
+
+	p := &parser{tokens: tokens, fields: fields}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, &SyntaxError{Pos: tok.pos, Msg: "unexpected " + tok.describe()}
+	}
+	return expr, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+	depth  int
+	fields Fields
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// keyword reports whether the next token is the given keyword
+func (p *parser) keyword(word string) bool {
+	tok := p.peek()
+	return tok.kind == tokWord && strings.EqualFold(tok.text, word)
+}
+
+// parseOr parses: and ("OR" and)*
+func (p *parser) parseOr() (Expr, error) {
+	first, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	terms := []Expr{first}
+	for p.keyword("OR") {
+		p.next()
+		term, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, term)
+	}
+	if len(terms) == 1 {
+		return first, nil
+	}
+	return Or{Terms: terms}, nil
+}
+
+// parseAnd parses: unary ("AND" unary)*
+func (p *parser) parseAnd() (Expr, error) {
+	first, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	terms := []Expr{first}
+	for p.keyword("AND") {
+		p.next()
+		term, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, term)
+	}
+	if len(terms) == 1 {
+		return first, nil
+	}
+	return And{Terms: terms}, nil
+}
+
+// parseUnary parses: "NOT" unary | "(" or ")" | term
+func (p *parser) parseUnary() (Expr, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > maxDepth {
+		return nil, &SyntaxError{Pos: p.peek().pos, Msg: "filter is nested too deeply"}
+	}
+
+	if p.keyword("NOT") {
+		p.next()
+		term, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not{Term: term}, nil
+	}
+
+	if p.peek().kind == tokLParen {
+		p.next()
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if tok := p.next(); tok.kind != tokRParen {
+			return nil, &SyntaxError{Pos: tok.pos, Msg: "expected ) but found " + tok.describe()}
+		}
+		return expr, nil
+	}
+
+	return p.parseTerm()
+}
+
+// parseTerm parses: field op value | field "in" "(" value ("," value)* ")"
+func (p *parser) parseTerm() (Expr, error) {
+	name := p.next()
+	if name.kind != tokWord {
+		return nil, &SyntaxError{Pos: name.pos, Msg: "expected a field name but found " + name.describe()}
+	}
+	field, ok := p.fields[name.text]
+	if !ok {
+		return nil, &SyntaxError{Pos: name.pos, Msg: "unsupported field " + strconv.Quote(name.text)}
+	}
+
+	if p.keyword("in") {
+		p.next()
+		return p.parseIn(field)
+	}
+
+	opTok := p.next()
+	if opTok.kind != tokOp {
+		return nil, &SyntaxError{Pos: opTok.pos, Msg: "expected an operator but found " + opTok.describe()}
+	}
+	op := Op(opTok.text)
+	if !opAllowed(field.Type, op) {
+		return nil, &SyntaxError{Pos: opTok.pos, Msg: "operator " + opTok.text + " is not supported for field " + strconv.Quote(name.text)}
+	}
+
+	valueTok := p.next()
+	if op == Match && field.Type == String {
+		// Text matches keep the raw string
+		if valueTok.kind != tokWord && valueTok.kind != tokString {
+			return nil, &SyntaxError{Pos: valueTok.pos, Msg: "expected a value but found " + valueTok.describe()}
+		}
+		return Comparison{Field: field, Op: op, Value: valueTok.text}, nil
+	}
+
+	value, err := convertValue(field.Type, valueTok)
+	if err != nil {
+		return nil, err
+	}
+	return Comparison{Field: field, Op: op, Value: value}, nil
+}
+
+// parseIn parses the value list of an "in" term
+func (p *parser) parseIn(field Field) (Expr, error) {
+	if tok := p.next(); tok.kind != tokLParen {
+		return nil, &SyntaxError{Pos: tok.pos, Msg: "expected ( but found " + tok.describe()}
+	}
+
+	var values []interface{}
+	for {
+		value, err := convertValue(field.Type, p.next())
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+
+		tok := p.next()
+		if tok.kind == tokRParen {
+			break
+		}
+		if tok.kind != tokComma {
+			return nil, &SyntaxError{Pos: tok.pos, Msg: "expected , or ) but found " + tok.describe()}
+		}
+	}
+
+	return In{Field: field, Values: values}, nil
+}
+
+// convertValue turns a value token into the Go value for a field type
+func convertValue(t Type, tok token) (interface{}, error) {
+	if tok.kind != tokWord && tok.kind != tokString {
+		return nil, &SyntaxError{Pos: tok.pos, Msg: "expected a value but found " + tok.describe()}
+	}
+
+	switch t {
+	case Bool:
+		if b, err := strconv.ParseBool(tok.text); err == nil {
+			return b, nil
+		}
+		return nil, &SyntaxError{Pos: tok.pos, Msg: "expected true or false"}
+	case Number:
+		if n, err := strconv.ParseFloat(tok.text, 64); err == nil {
+			return n, nil
+		}
+		return nil, &SyntaxError{Pos: tok.pos, Msg: "expected a number"}
+	case ObjectID:
+		if id, err := primitive.ObjectIDFromHex(tok.text); err == nil {
+			return id, nil
+		}
+		return nil, &SyntaxError{Pos: tok.pos, Msg: "expected an object ID"}
+	case Time:
+		for _, layout := range timeLayouts {
+			if ts, err := time.Parse(layout, tok.text); err == nil {
+				return ts.UTC(), nil
+			}
+		}
+		return nil, &SyntaxError{Pos: tok.pos, Msg: "expected a date (YYYY-MM-DD) or RFC 3339 timestamp"}
+	default:
+		return tok.text, nil
+	}
+}
@@ -0,0 +1,146 @@
+package filter
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var testFields = Fields{
+	"id":        {Name: "_id", Type: ObjectID},
+	"title":     {Name: "title", Type: String},
+	"completed": {Name: "completed", Type: Bool},
+	"priority":  {Name: "priority", Type: Number},
+	"dueAt":     {Name: "dueAt", Type: Time},
+}
+
+func TestParse(t *testing.T) {
+	id1, _ := primitive.ObjectIDFromHex("64b7f0c2a1b2c3d4e5f60718")
+	id2, _ := primitive.ObjectIDFromHex("64b7f0c2a1b2c3d4e5f60719")
+	title := testFields["title"]
+	completed := testFields["completed"]
+	dueAt := testFields["dueAt"]
+
+	tests := []struct {
+		input string
+		want  Expr
+	}{
+		{"", nil},
+		{"   ", nil},
+		{"completed:false", Comparison{Field: completed, Op: Eq, Value: false}},
+		{"priority>=2.5", Comparison{Field: testFields["priority"], Op: Gte, Value: 2.5}},
+		{"dueAt<2026-11-01", Comparison{Field: dueAt, Op: Lt, Value: time.Date(2026, time.November, 1, 0, 0, 0, 0, time.UTC)}},
+		{`dueAt>"2026-11-01T10:00:00+02:00"`, Comparison{Field: dueAt, Op: Gt, Value: time.Date(2026, time.November, 1, 8, 0, 0, 0, time.UTC)}},
+		{`title:"Ship AND release"`, Comparison{Field: title, Op: Eq, Value: "Ship AND release"}},
+		{`title~"a.b*"`, Comparison{Field: title, Op: Match, Value: "a.b*"}},
+		{"title~release", Comparison{Field: title, Op: Match, Value: "release"}},
+		{`title:""`, Comparison{Field: title, Op: Eq, Value: ""}},
+		{"id in (64b7f0c2a1b2c3d4e5f60718, 64b7f0c2a1b2c3d4e5f60719)", In{Field: testFields["id"], Values: []interface{}{id1, id2}}},
+		{
+			"completed:false AND title~bug AND dueAt<2026-11-01",
+			And{Terms: []Expr{
+				Comparison{Field: completed, Op: Eq, Value: false},
+				Comparison{Field: title, Op: Match, Value: "bug"},
+				Comparison{Field: dueAt, Op: Lt, Value: time.Date(2026, time.November, 1, 0, 0, 0, 0, time.UTC)},
+			}},
+		},
+		{
+			// AND binds tighter than OR, keywords are case insensitive
+			"title:a or title:b and not completed:true",
+			Or{Terms: []Expr{
+				Comparison{Field: title, Op: Eq, Value: "a"},
+				And{Terms: []Expr{
+					Comparison{Field: title, Op: Eq, Value: "b"},
+					Not{Term: Comparison{Field: completed, Op: Eq, Value: true}},
+				}},
+			}},
+		},
+		{
+			"(title:a OR title:b) AND completed!=true",
+			And{Terms: []Expr{
+				Or{Terms: []Expr{
+					Comparison{Field: title, Op: Eq, Value: "a"},
+					Comparison{Field: title, Op: Eq, Value: "b"},
+				}},
+				Comparison{Field: completed, Op: Ne, Value: true},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			expr, err := Parse(tt.input, testFields)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(expr, tt.want) {
+				t.Errorf("got %#v, want %#v", expr, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		pos   int
+		err   string
+	}{
+		{"unknown field", "completed:false AND tags:bug", 20, `unsupported field "tags" at position 20`},
+		{"unknown field in group", "(owner:x)", 1, `unsupported field "owner" at position 1`},
+		{"operator not allowed", "completed<true", 9, `operator < is not supported for field "completed" at position 9`},
+		{"match on a time", "dueAt~2026", 5, `operator ~ is not supported for field "dueAt" at position 5`},
+		{"unknown operator", "title=x", 5, "unexpected character '=' at position 5"},
+		{"missing operator", "title x", 6, "expected an operator but found 'x' at position 6"},
+		{"missing value", "title:", 6, "expected a value but found end of filter at position 6"},
+		{"missing field", ":x", 0, "expected a field name but found ':' at position 0"},
+		{"dangling AND", "title:x AND", 11, "expected a field name but found end of filter at position 11"},
+		{"trailing token", "title:x title:y", 8, "unexpected 'title' at position 8"},
+		{"unclosed group", "(title:x", 8, "expected ) but found end of filter at position 8"},
+		{"unbalanced paren", "title:x)", 7, "unexpected ')' at position 7"},
+		{"unterminated string", `title:"x`, 6, "unterminated string at position 6"},
+		{"bad bool", "completed:yes", 10, "expected true or false at position 10"},
+		{"bad number", "priority>high", 9, "expected a number at position 9"},
+		{"bad object id", "id:123", 3, "expected an object ID at position 3"},
+		{"bad time", "dueAt<tomorrow", 6, "expected a date (YYYY-MM-DD) or RFC 3339 timestamp at position 6"},
+		{"in without list", "id in 64b7f0c2a1b2c3d4e5f60718", 6, "expected ( but found '64b7f0c2a1b2c3d4e5f60718' at position 6"},
+		{"in without separator", "title in (a b)", 12, "expected , or ) but found 'b' at position 12"},
+		{"empty in list", "title in ()", 10, "expected a value but found ')' at position 10"},
+		{"too long", "title:" + strings.Repeat("x", MaxLength), MaxLength, "filter is too long at position 1024"},
+		{"too deep", strings.Repeat("(", maxDepth) + "title:x" + strings.Repeat(")", maxDepth), maxDepth, "filter is nested too deeply at position 32"},
+		{"too many NOTs", strings.Repeat("NOT ", maxDepth) + "title:x", maxDepth * 4, "filter is nested too deeply at position 128"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.input, testFields)
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("got error %v, want a syntax error", err)
+			}
+			if syntaxErr.Pos != tt.pos {
+				t.Errorf("got position %d, want %d", syntaxErr.Pos, tt.pos)
+			}
+			if err.Error() != tt.err {
+				t.Errorf("got error %q, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	// Right at the limits is still accepted
+	input := "title:" + strings.Repeat("x", MaxLength-len("title:"))
+	if _, err := Parse(input, testFields); err != nil {
+		t.Errorf("filter of %d bytes: %v", len(input), err)
+	}
+	input = strings.Repeat("(", maxDepth-1) + "title:x" + strings.Repeat(")", maxDepth-1)
+	if _, err := Parse(input, testFields); err != nil {
+		t.Errorf("filter in %d groups: %v", maxDepth-1, err)
+	}
+}
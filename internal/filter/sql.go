@@ -0,0 +1,69 @@
+package filter
+
+import "strings"
+
+// sqlOps maps comparison operators to their SQL operator
+var sqlOps = map[Op]string{
+	Eq:  "=",
+	Ne:  "<>",
+	Lt:  "<",
+	Lte: "<=",
+	Gt:  ">",
+	Gte: ">=",
+}
+
+// ToSQL compiles an expression into a SQL condition. column returns the
+// column of a stored field, arg adds a value to the query and returns its
+// placeholder. A nil expression is true.
+//
+// Results are the same as with ToBSON: NULL columns, the missing fields
+// of SQL, only match != and conditions are never NULL, so NOT inverts them.
+func ToSQL(e Expr, column func(field string) string, arg func(value interface{}) string) string {
+	switch e := e.(type) {
+	case And:
+		return joinSQL(e.Terms, " AND ", "1 = 1", column, arg)
+	case Or:
+		return joinSQL(e.Terms, " OR ", "1 = 0", column, arg)
+	case Not:
+		return "NOT " + ToSQL(e.Term, column, arg)
+	case In:
+		if len(e.Values) == 0 {
+			return "1 = 0"
+		}
+		placeholders := make([]string, len(e.Values))
+		for i, value := range e.Values {
+			placeholders[i] = arg(value)
+		}
+		col := column(e.Field.Name)
+		return "(" + col + " IS NOT NULL AND " + col + " IN (" + strings.Join(placeholders, ", ") + "))"
+	case Comparison:
+		col := column(e.Field.Name)
+		switch e.Op {
+		case Match:
+			// Values are escaped, so clients can't smuggle wildcards in
+			pattern := "%" + escapeLike(strings.ToLower(e.Value.(string))) + "%"
+			return "(" + col + " IS NOT NULL AND lower(" + col + ") LIKE " + arg(pattern) + ` ESCAPE '\')`
+		case Ne:
+			return "(" + col + " IS NULL OR " + col + " <> " + arg(e.Value) + ")"
+		}
+		return "(" + col + " IS NOT NULL AND " + col + " " + sqlOps[e.Op] + " " + arg(e.Value) + ")"
+	default:
+		return "1 = 1"
+	}
+}
+
+func joinSQL(terms []Expr, sep, empty string, column func(field string) string, arg func(value interface{}) string) string {
+	if len(terms) == 0 {
+		return empty
+	}
+	compiled := make([]string, len(terms))
+	for i, term := range terms {
+		compiled[i] = ToSQL(term, column, arg)
+	}
+	return "(" + strings.Join(compiled, sep) + ")"
+}
+
+// escapeLike escapes the LIKE wildcards of s, with \ as the escape character
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/cmerin0/tasky/internal/models"
+	"github.com/cmerin0/tasky/internal/store"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const maxBulkOperations = 100 // Maximum number of operations in a single bulk request
+
+// Bulk operation types
+const (
+	bulkCreate   = "create"
+	bulkUpdate   = "update"
+	bulkDelete   = "delete"
+	bulkComplete = "complete"
+)
+
+var errBulkFailed = errors.New("bulk operation failed")
+
+// BulkRequest is a list of task operations. In atomic mode the operations
+// run in a transaction and either all of them are applied or none is.
+type BulkRequest struct {
+	Atomic     bool            `json:"atomic"`
+	Operations []BulkOperation `json:"operations"`
+}
+
+// BulkOperation is a single operation of a bulk request.
+// Task is required for create and update (a full replacement),
+// ID for every operation but create.
+type BulkOperation struct {
+	Op   string      `json:"op"`
+	ID   string      `json:"id,omitempty"`
+	Task models.Task `json:"task"`
+}
+
+// BulkResult is the outcome of a single operation, Status is an HTTP status code
+type BulkResult struct {
+	Index  int    `json:"index"`
+	Op     string `json:"op"`
+	ID     string `json:"id,omitempty"`
+	Status int    `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// bulkKinds maps the operations of a request to the writes of the store
+var bulkKinds = map[string]string{
+	bulkCreate:   store.BulkCreate,
+	bulkUpdate:   store.BulkReplace,
+	bulkDelete:   store.BulkDelete,
+	bulkComplete: store.BulkComplete,
+}
+
+// plannedOperation is a validated operation waiting to be written
+type plannedOperation struct {
+	index int
+	op    store.BulkOp
+}
+
+// planBulk validates the operations, recording an error result for each invalid one
+func planBulk(operations []BulkOperation, results []BulkResult) []plannedOperation {
+	var planned []plannedOperation
+
+	for i, operation := range operations {
+		results[i] = BulkResult{Index: i, Op: operation.Op, ID: operation.ID}
+		p := plannedOperation{index: i, op: store.BulkOp{Kind: bulkKinds[operation.Op], Task: operation.Task}}
+
+		var err error
+		switch operation.Op {
+		case bulkCreate:
+			err = models.Validate(operation.Task)
+		case bulkUpdate, bulkDelete, bulkComplete:
+			if p.op.ID, err = primitive.ObjectIDFromHex(operation.ID); err != nil {
+				err = errors.New("invalid task ID")
+			} else if operation.Op == bulkUpdate {
+				err = models.Validate(operation.Task)
+			}
+		default:
+			err = errors.New("op must be create, update, delete or complete")
+		}
+
+		if err != nil {
+			results[i].Status = http.StatusBadRequest
+			results[i].Error = err.Error()
+			continue
+		}
+		planned = append(planned, p)
+	}
+
+	return planned
+}
+
+// executeBulk writes the planned operations as one batch and records the
+// outcome of each in results. With ordered set the batch stops at the first
+// failure. ctx may be the context of a transaction.
+func (h *Handlers) executeBulk(ctx context.Context, planned []plannedOperation, results []BulkResult, actor string, ordered bool) error {
+	ops := make([]store.BulkOp, len(planned))
+	for i, p := range planned {
+		ops[i] = p.op
+	}
+	written, err := h.Tasks.BulkTasks(ctx, ops, ordered, actor)
+	if err != nil {
+		return err
+	}
+
+	for i, p := range planned {
+		result := &results[p.index]
+		err := written[i].Err
+
+		var invalid *store.ValidationError
+		switch {
+		case err == nil:
+			result.Status = http.StatusOK
+			if p.op.Kind == store.BulkCreate {
+				result.Status = http.StatusCreated
+				result.ID = written[i].Task.ID.Hex()
+			}
+		case errors.Is(err, store.ErrSkipped):
+			result.Status = http.StatusFailedDependency
+			result.Error = "Not applied because an earlier operation failed"
+		case errors.Is(err, store.ErrNotFound):
+			result.Status = http.StatusNotFound
+			result.Error = "Task not found"
+		case errors.Is(err, store.ErrStale):
+			result.Status = http.StatusPreconditionFailed
+			result.Error = "The resource was modified, fetch it again and retry"
+		case errors.As(err, &invalid):
+			result.Status = http.StatusBadRequest
+			result.Error = err.Error()
+		default:
+			result.Status = http.StatusInternalServerError
+			result.Error = err.Error()
+		}
+	}
+	return nil
+}
+
+// bulkSummary renders the results of a bulk request
+func bulkSummary(results []BulkResult) fiber.Map {
+	succeeded := 0
+	for _, result := range results {
+		if result.Status < http.StatusBadRequest {
+			succeeded++
+		}
+	}
+	return fiber.Map{
+		"results":   results,
+		"succeeded": succeeded,
+		"failed":    len(results) - succeeded,
+	}
+}
+
+// BulkTasks handles a batch of task operations
+// @Summary Run task operations in bulk
+// @Description Create, update, delete and complete tasks in a single request. Each operation gets its own result. With atomic set, the operations run in a transaction and a single failure rolls all of them back.
+// @Param request body BulkRequest true "Bulk operations"
+// @Success 200 {object} fiber.Map
+// @Failure 400 Bad Request
+// @Failure 409 Conflict
+// @Failure 500 Internal Server Error
+// @Router /tasks/bulk [post]
+func (h *Handlers) BulkTasks(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	var req BulkRequest
+	defer cancel()
+
+	if err := c.BodyParser(&req); err != nil {
+		log.Error("Error parsing bulk request: ", err)
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+	if len(req.Operations) == 0 || len(req.Operations) > maxBulkOperations {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "operations must contain between 1 and 100 items",
+		})
+	}
+
+	results := make([]BulkResult, len(req.Operations))
+	planned := planBulk(req.Operations, results)
+	actor := requestActor(c)
+
+	if !req.Atomic {
+		if err := h.executeBulk(ctx, planned, results, actor, false); err != nil {
+			log.Error("Error running bulk operations: ", err)
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"message": err.Error()})
+		}
+
+		log.Info("Bulk operations completed")
+		return c.Status(http.StatusOK).JSON(bulkSummary(results))
+	}
+
+	// All or nothing: refuse the batch up front if anything is invalid
+	if len(planned) < len(req.Operations) {
+		return c.Status(http.StatusBadRequest).JSON(bulkSummary(results))
+	}
+
+	err := h.Tasks.InTransaction(ctx, func(ctx context.Context) error {
+		// The transaction may be retried, start from clean results every time
+		planned = planBulk(req.Operations, results)
+		if err := h.executeBulk(ctx, planned, results, actor, true); err != nil {
+			return err
+		}
+		for _, result := range results {
+			if result.Status >= http.StatusBadRequest {
+				return errBulkFailed
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Error("Bulk transaction rolled back: ", err)
+		for i := range results {
+			if results[i].Status < http.StatusBadRequest {
+				results[i].Status = http.StatusFailedDependency
+				results[i].Error = "Rolled back"
+			}
+		}
+		if !errors.Is(err, errBulkFailed) {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"message": err.Error(), "results": results})
+		}
+		return c.Status(http.StatusConflict).JSON(bulkSummary(results))
+	}
+
+	log.Info("Bulk transaction committed")
+	return c.Status(http.StatusOK).JSON(bulkSummary(results))
+}
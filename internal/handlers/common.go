@@ -1,32 +1,75 @@
 package handlers
 
 import (
-	"github.com/cmerin0/tasky/internal/db"
-	"go.mongodb.org/mongo-driver/mongo"
-)
+	"errors"
+	"net/http"
+
+	"github.com/cmerin0/tasky/internal/events"
+	"github.com/cmerin0/tasky/internal/middleware"
+	"github.com/cmerin0/tasky/internal/store"
 
-// Declare collections at package level
-// to avoid multiple calls to GetCollection
-// and to ensure they are initialized only once
-var (
-	userCollection *mongo.Collection
-	taskCollection *mongo.Collection
+	"github.com/gofiber/fiber/v2"
+	"github.com/graphql-go/graphql"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-// getUserCollection returns the user collection
-// from the database. It initializes it if not already done.
-func getUserCollection() *mongo.Collection {
-	if userCollection == nil {
-		userCollection = db.GetCollection("users")
+// actorHeader identifies the user performing a request.
+// It is recorded in the task history as the actor of a change
+// and scopes what the caller can see where access is restricted.
+const actorHeader = middleware.ActorHeader
+
+// Handlers serves the endpoints from the repositories it is built with,
+// so they don't depend on where the data is stored. Users and Tasks are
+// always set. The others are only set when the storage has them, their
+// routes aren't registered otherwise.
+type Handlers struct {
+	Users         store.UserRepository
+	Tasks         store.TaskRepository
+	Views         store.ViewRepository
+	Notifications store.NotificationRepository
+	Webhooks      store.WebhookRepository
+	Jobs          store.JobRepository
+	Events        events.Source
+
+	schema graphql.Schema
+}
+
+// New returns the handlers backed by the given repositories
+func New(users store.UserRepository, tasks store.TaskRepository) *Handlers {
+	h := &Handlers{Users: users, Tasks: tasks}
+	h.schema = newSchema(h)
+	return h
+}
+
+// requestActor returns who is performing the request,
+// falling back to "anonymous" when no actor header is sent.
+func requestActor(c *fiber.Ctx) string {
+	if actor := c.Get(actorHeader); actor != "" {
+		return actor
 	}
-	return userCollection
+	return "anonymous"
+}
+
+// requestUserID returns the ID of the user performing the request,
+// if the actor header holds a valid user ID.
+func requestUserID(c *fiber.Ctx) (primitive.ObjectID, bool) {
+	id, err := primitive.ObjectIDFromHex(c.Get(actorHeader))
+	return id, err == nil
 }
 
-// getTaskCollection returns the task collection
-// from the database. It initializes it if not already done.
-func getTaskCollection() *mongo.Collection {
-	if taskCollection == nil {
-		taskCollection = db.GetCollection("tasks")
+// storeError renders an error returned by the store: 400 for invalid input,
+// 404 with the notFound message, 412 for a stale version and 500 otherwise
+func storeError(c *fiber.Ctx, err error, notFound string) error {
+	var invalid *store.ValidationError
+	switch {
+	case errors.As(err, &invalid):
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	case errors.Is(err, store.ErrNotFound):
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"message": notFound})
+	case errors.Is(err, store.ErrStale):
+		return c.Status(http.StatusPreconditionFailed).JSON(fiber.Map{
+			"message": "The resource was modified, fetch it again and retry",
+		})
 	}
-	return taskCollection
+	return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"message": err.Error()})
 }
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var errInvalidETag = errors.New("invalid entity tag in If-Match")
+
+// etag returns the entity tag of a document version
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// parseETags parses a comma separated list of entity tags into versions.
+// Weak tags compare the same as strong ones, wildcard is true for "*".
+func parseETags(header string) (versions []int64, wildcard bool, err error) {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return nil, true, nil
+		}
+		tag = strings.TrimPrefix(tag, "W/")
+		version, err := strconv.ParseInt(strings.Trim(tag, `"`), 10, 64)
+		if err != nil || len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			return nil, false, errInvalidETag
+		}
+		versions = append(versions, version)
+	}
+	return versions, false, nil
+}
+
+// ifMatchVersions returns the versions listed by the If-Match header,
+// nil when the header is missing or "*"
+func ifMatchVersions(c *fiber.Ctx) ([]int64, error) {
+	header := c.Get(fiber.HeaderIfMatch)
+	if header == "" {
+		return nil, nil
+	}
+
+	versions, wildcard, err := parseETags(header)
+	if err != nil || wildcard {
+		return nil, err
+	}
+	return versions, nil
+}
+
+// notModified reports whether the If-None-Match header of a GET already
+// names the current version, setting the ETag header either way
+func notModified(c *fiber.Ctx, version int64) bool {
+	c.Set(fiber.HeaderETag, etag(version))
+
+	header := c.Get(fiber.HeaderIfNoneMatch)
+	if header == "" {
+		return false
+	}
+	versions, wildcard, err := parseETags(header)
+	if err != nil {
+		return false
+	}
+	if wildcard {
+		return true
+	}
+	for _, v := range versions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/cmerin0/tasky/internal/events"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// eventsHeartbeat is how often idle event connections are probed,
+// which also keeps proxies from closing them
+const eventsHeartbeat = 15 * time.Second
+
+// TaskEvents streams task events as Server-Sent Events
+// @Summary Stream task events
+// @Description Push task.created, task.updated and task.deleted events of the tasks of the caller as Server-Sent Events, plus a notifications.unread event with their unread notification count when connecting and whenever it changes. Each task event id is a resume token: EventSource sends it back in Last-Event-ID when it reconnects, other clients pass it as resumeAfter. Unread counts have no id and are not resumed.
+// @Produce text/event-stream
+// @Param X-User-ID header string false "User whose tasks to follow, required unless userId is given"
+// @Param userId query string false "Same as X-User-ID, for clients that can't set headers"
+// @Param Last-Event-ID header string false "ID of the last event received"
+// @Param resumeAfter query string false "ID of the last event received"
+// @Success 200 {string} string
+// @Failure 400 Bad Request
+// @Failure 401 Unauthorized
+// @Failure 410 Gone
+// @Failure 500 Internal Server Error
+// @Router /events [get]
+func (h *Handlers) TaskEvents(c *fiber.Ctx) error {
+	opts, ok := eventOptions(c.Get(actorHeader, c.Query("userId")), c.Get("Last-Event-ID", c.Query("resumeAfter")))
+	if !ok {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"message": errSubscriberRequired.Error()})
+	}
+
+	// The streams are opened before answering so a bad token still gets a status
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, unread, err := h.watchEvents(ctx, opts)
+	if err != nil {
+		cancel()
+		log.Error("Error watching task events: ", err)
+		return eventsError(c, err)
+	}
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no") // Disable proxy buffering
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer cancel()
+		defer closeStreams(stream, unread)
+
+		err := relayEvents(ctx, stream, unread, func(message interface{}) error {
+			if message == nil {
+				fmt.Fprint(w, ": ping\n\n")
+				return w.Flush()
+			}
+			data, err := json.Marshal(message)
+			if err != nil {
+				return err
+			}
+			switch message := message.(type) {
+			case *events.Event:
+				fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", message.ID, message.Type, data)
+			case *events.UnreadCount:
+				// No id, so Last-Event-ID keeps pointing at the last task event
+				fmt.Fprintf(w, "event: %s\ndata: %s\n\n", message.Type, data)
+			}
+			return w.Flush()
+		})
+
+		// Tell a client that is still there why the stream ended
+		if err != nil && !errors.Is(err, context.Canceled) {
+			log.Error("Task event stream ended: ", err)
+			data, _ := json.Marshal(fiber.Map{"message": err.Error()})
+			fmt.Fprintf(w, "event: error\ndata: %s\n\n", data)
+			w.Flush()
+		}
+	})
+	return nil
+}
+
+// TaskEventsSocket pushes task events over a WebSocket
+// @Summary Task events over WebSocket
+// @Description Upgrade to a WebSocket receiving the same JSON events and unread counts as /events, one per text message. Resume with the id of the last event in resumeAfter. Subscribers must be identified like on /events. When the subscription fails an error message is sent and the socket closed.
+// @Param X-User-ID header string false "User whose tasks to follow, required unless userId is given"
+// @Param userId query string false "Same as X-User-ID, for clients that can't set headers"
+// @Param resumeAfter query string false "ID of the last event received"
+// @Success 101 Switching Protocols
+// @Failure 426 Upgrade Required
+// @Router /events/ws [get]
+func (h *Handlers) TaskEventsSocket(conn *websocket.Conn) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	opts, ok := eventOptions(conn.Headers(actorHeader, conn.Query("userId")), conn.Query("resumeAfter"))
+	if !ok {
+		closeSocket(conn, websocket.ClosePolicyViolation, errSubscriberRequired)
+		return
+	}
+	stream, unread, err := h.watchEvents(ctx, opts)
+	if err != nil {
+		log.Error("Error watching task events: ", err)
+		closeSocket(conn, websocket.ClosePolicyViolation, err)
+		return
+	}
+	defer closeStreams(stream, unread)
+
+	// Clients only send control frames, reading notices when they leave
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	err = relayEvents(ctx, stream, unread, func(message interface{}) error {
+		if message == nil {
+			return conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(eventsHeartbeat))
+		}
+		return conn.WriteJSON(message)
+	})
+	if err != nil && !errors.Is(err, context.Canceled) {
+		log.Error("Task event socket ended: ", err)
+		closeSocket(conn, websocket.CloseInternalServerErr, err)
+	}
+}
+
+// errSubscriberRequired refuses subscribers that don't say who they are
+var errSubscriberRequired = errors.New(actorHeader + " header or userId with a valid user ID is required")
+
+// eventOptions builds the subscription of a client. Like search, users only
+// ever see their own tasks, so it fails without a valid user ID.
+func eventOptions(actor, resumeAfter string) (events.Options, bool) {
+	userId, err := primitive.ObjectIDFromHex(actor)
+	if err != nil {
+		return events.Options{}, false
+	}
+	return events.Options{UserID: &userId, ResumeAfter: resumeAfter}, true
+}
+
+// watchEvents opens the task event stream of a subscription and the stream
+// of the unread notification count of its user
+func (h *Handlers) watchEvents(ctx context.Context, opts events.Options) (events.TaskStream, events.CountStream, error) {
+	stream, err := h.Events.Watch(ctx, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	unread, err := h.Events.WatchUnread(ctx, *opts.UserID)
+	if err != nil {
+		stream.Close(context.Background())
+		return nil, nil, err
+	}
+	return stream, unread, nil
+}
+
+// closeStreams releases the streams opened by watchEvents
+func closeStreams(stream events.TaskStream, unread events.CountStream) {
+	stream.Close(context.Background())
+	if unread != nil {
+		unread.Close(context.Background())
+	}
+}
+
+// relayEvents hands the task events of stream and the counts of unread,
+// which may be nil, to send until send fails or a stream ends. Messages are
+// an *events.Event or an *events.UnreadCount, and send gets nil every
+// eventsHeartbeat so dead connections are noticed even when nothing changes.
+// The streams are no longer read once it returns and can be closed.
+func relayEvents(ctx context.Context, stream events.TaskStream, unread events.CountStream, send func(message interface{}) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	next := make(chan interface{})
+	failed := make(chan error, 2)
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	// follow reads a stream until it fails, handing its messages over to next
+	follow := func(read func(ctx context.Context) (interface{}, error)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				message, err := read(ctx)
+				if err != nil {
+					failed <- err
+					return
+				}
+				select {
+				case next <- message:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	follow(func(ctx context.Context) (interface{}, error) {
+		event, err := stream.Next(ctx)
+		return &event, err
+	})
+	if unread != nil {
+		follow(func(ctx context.Context) (interface{}, error) {
+			count, err := unread.Next(ctx)
+			return &count, err
+		})
+	}
+
+	heartbeat := time.NewTicker(eventsHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case message := <-next:
+			if err := send(message); err != nil {
+				return err
+			}
+		case <-heartbeat.C:
+			if err := send(nil); err != nil {
+				return err
+			}
+		case err := <-failed:
+			return err
+		}
+	}
+}
+
+// eventsError responds to a subscription that couldn't be opened
+func eventsError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, events.ErrInvalidResumeToken):
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	case errors.Is(err, events.ErrResumeTokenExpired):
+		return c.Status(http.StatusGone).JSON(fiber.Map{"message": err.Error()})
+	}
+	return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"message": err.Error()})
+}
+
+// closeSocket sends the reason a socket is closed, then closes it
+func closeSocket(conn *websocket.Conn, code int, err error) {
+	conn.WriteJSON(fiber.Map{"type": "error", "message": err.Error()})
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, ""), time.Now().Add(time.Second))
+	conn.Close()
+}
@@ -0,0 +1,106 @@
+package handlers_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cmerin0/tasky/internal/events"
+	"github.com/cmerin0/tasky/internal/handlers"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeSource hands out the same events to every subscriber, then ends
+// their stream. Subscriptions resuming after "expired" fail.
+type fakeSource struct {
+	events []events.Event
+	opts   []events.Options
+}
+
+func (f *fakeSource) Watch(ctx context.Context, opts events.Options) (events.TaskStream, error) {
+	if opts.ResumeAfter == "expired" {
+		return nil, events.ErrResumeTokenExpired
+	}
+	f.opts = append(f.opts, opts)
+	return &fakeStream{events: f.events}, nil
+}
+
+func (f *fakeSource) WatchUnread(ctx context.Context, userID primitive.ObjectID) (events.CountStream, error) {
+	return &fakeCounts{}, nil
+}
+
+var errStreamEnded = errors.New("stream ended")
+
+type fakeStream struct {
+	events []events.Event
+}
+
+func (s *fakeStream) Next(ctx context.Context) (events.Event, error) {
+	if len(s.events) == 0 {
+		return events.Event{}, errStreamEnded
+	}
+	event := s.events[0]
+	s.events = s.events[1:]
+	return event, nil
+}
+
+func (s *fakeStream) Close(ctx context.Context) error { return nil }
+
+// fakeCounts never changes, so it blocks until the subscription ends
+type fakeCounts struct{}
+
+func (fakeCounts) Next(ctx context.Context) (events.UnreadCount, error) {
+	<-ctx.Done()
+	return events.UnreadCount{}, ctx.Err()
+}
+
+func (fakeCounts) Close(ctx context.Context) error { return nil }
+
+func TestTaskEvents(t *testing.T) {
+	source := &fakeSource{events: []events.Event{{ID: "token-1", Type: events.TaskCreated, TaskID: primitive.NewObjectID()}}}
+	h := handlers.New(nil, nil)
+	h.Events = source
+
+	app := fiber.New()
+	app.Get("/events", h.TaskEvents)
+	user := primitive.NewObjectID()
+
+	status, body := send(t, app, "GET", "/events", "", nil)
+	expectStatus(t, "subscribe without actor", status, fiber.StatusUnauthorized, body)
+	status, body = send(t, app, "GET", "/events?resumeAfter=expired", user.Hex(), nil)
+	expectStatus(t, "resume an expired token", status, fiber.StatusGone, body)
+
+	req := httptest.NewRequest("GET", "/events?userId="+user.Hex(), nil)
+	req.Header.Set("Last-Event-ID", "token-0")
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK || resp.Header.Get(fiber.HeaderContentType) != "text/event-stream" {
+		t.Fatalf("subscribe: status %d, content type %q", resp.StatusCode, resp.Header.Get(fiber.HeaderContentType))
+	}
+
+	stream := string(data)
+	if !strings.HasPrefix(stream, "id: token-1\nevent: task.created\ndata: {") {
+		t.Fatalf("the event wasn't sent first:\n%s", stream)
+	}
+	// The end of the stream is reported to the client
+	if !strings.Contains(stream, "event: error\ndata: {\"message\":\""+errStreamEnded.Error()+"\"}") {
+		t.Fatalf("the end of the stream wasn't reported:\n%s", stream)
+	}
+
+	last := source.opts[len(source.opts)-1]
+	if last.UserID == nil || *last.UserID != user || last.ResumeAfter != "token-0" {
+		t.Fatalf("subscribed with %+v, want the user and Last-Event-ID", last)
+	}
+}
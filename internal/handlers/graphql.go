@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+// GraphQLRequest is the body of a GraphQL request
+type GraphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// graphQLContextKey keys the request scoped values resolvers need
+type graphQLContextKey int
+
+const (
+	actorKey graphQLContextKey = iota
+	loadersKey
+)
+
+// GraphQL executes a GraphQL query or mutation over users and tasks.
+// Writes follow the same rules as the REST routes: inputs are validated,
+// versions are bumped, task changes are recorded in the history with the
+// X-User-ID header as actor, and passwords are never returned.
+// Nested users and tasks are loaded in batches, one query per level.
+// @Summary GraphQL endpoint
+// @Description Execute a GraphQL query or mutation. Errors are reported in the errors field of a 200 response.
+// @Accept json
+// @Param request body GraphQLRequest true "GraphQL request"
+// @Success 200 {object} fiber.Map
+// @Failure 400 Bad Request
+// @Router /graphql [post]
+func (h *Handlers) GraphQL(c *fiber.Ctx) error {
+	var request GraphQLRequest
+	if err := c.BodyParser(&request); err != nil {
+		log.Error("Error parsing GraphQL request: ", err)
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+	return h.executeGraphQL(c, request)
+}
+
+// GraphQLQuery executes a GraphQL query sent in the query string.
+// Mutations must be sent with POST.
+// @Summary GraphQL endpoint for queries
+// @Description Execute a GraphQL query sent as the query parameter
+// @Param query query string true "GraphQL query"
+// @Param operationName query string false "Operation to run when the query holds several"
+// @Success 200 {object} fiber.Map
+// @Failure 400 Bad Request
+// @Router /graphql [get]
+func (h *Handlers) GraphQLQuery(c *fiber.Ctx) error {
+	request := GraphQLRequest{Query: c.Query("query"), OperationName: c.Query("operationName")}
+	if isMutation(request) {
+		return c.Status(http.StatusMethodNotAllowed).JSON(fiber.Map{"message": "Mutations must be sent with POST"})
+	}
+	return h.executeGraphQL(c, request)
+}
+
+func (h *Handlers) executeGraphQL(c *fiber.Ctx, request GraphQLRequest) error {
+	if request.Query == "" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": "query is required"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	ctx = context.WithValue(ctx, actorKey, requestActor(c))
+	ctx = context.WithValue(ctx, loadersKey, newLoaders(h))
+
+	result := graphql.Do(graphql.Params{
+		Schema:         h.schema,
+		RequestString:  request.Query,
+		OperationName:  request.OperationName,
+		VariableValues: request.Variables,
+		Context:        ctx,
+	})
+	if result.HasErrors() {
+		log.Error("GraphQL request failed: ", result.Errors)
+	}
+
+	return c.Status(http.StatusOK).JSON(result)
+}
+
+// isMutation reports whether the operation a request runs is a mutation
+func isMutation(request GraphQLRequest) bool {
+	doc, err := parser.Parse(parser.ParseParams{Source: request.Query})
+	if err != nil {
+		return false
+	}
+	for _, definition := range doc.Definitions {
+		op, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		name := ""
+		if op.Name != nil {
+			name = op.Name.Value
+		}
+		if (request.OperationName == "" || request.OperationName == name) && op.Operation == ast.OperationTypeMutation {
+			return true
+		}
+	}
+	return false
+}
+
+// graphQLActor returns who performs the request, as recorded in the history
+func graphQLActor(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey).(string)
+	return actor
+}
+
+// graphQLLoaders returns the batching loaders of the request
+func graphQLLoaders(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey).(*loaders)
+}
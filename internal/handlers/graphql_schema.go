@@ -0,0 +1,471 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/cmerin0/tasky/internal/filter"
+	"github.com/cmerin0/tasky/internal/models"
+	"github.com/cmerin0/tasky/internal/store"
+
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	errTaskNotFound = errors.New("Task not found")
+	errUserNotFound = errors.New("User not found")
+	errStaleVersion = errors.New("The resource was modified, fetch it again and retry")
+)
+
+// newSchema returns the schema served by the GraphQL endpoint.
+// Users and tasks resolve to models.UserResponse and models.Task,
+// so passwords can't be selected.
+//
+//	type User { id, name, email, version, tasks: [Task!]! }
+//	type Task { id, title, description, completed, userId, version, user: User }
+//	type Query { user(id), users(limit, cursor, sort), task(id), tasks(limit, cursor, sort, filter) }
+//	type Mutation { createUser, updateUser, deleteUser, createTask, updateTask, deleteTask }
+//
+// Writes take an optional version, the GraphQL counterpart of If-Match.
+// Nesting stops at tasks: there is no comment model to resolve below them.
+func newSchema(h *Handlers) graphql.Schema {
+	var userType, taskType *graphql.Object
+
+	userType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "User",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id":      &graphql.Field{Type: graphql.NewNonNull(graphql.ID), Resolve: resolveUserID},
+				"name":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"email":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"version": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+				"tasks": &graphql.Field{
+					Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(taskType))),
+					Description: "Tasks of the user, oldest first",
+					Resolve:     resolveUserTasks,
+				},
+			}
+		}),
+	})
+
+	taskType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Task",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id":          &graphql.Field{Type: graphql.NewNonNull(graphql.ID), Resolve: resolveTaskID},
+				"title":       &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"description": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"completed":   &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+				"userId":      &graphql.Field{Type: graphql.NewNonNull(graphql.ID), Resolve: resolveTaskUserID},
+				"dueAt":       &graphql.Field{Type: graphql.DateTime},
+				"version":     &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+				"user":        &graphql.Field{Type: userType, Resolve: resolveTaskUser},
+			}
+		}),
+	})
+
+	pageArgs := graphql.FieldConfigArgument{
+		"limit":  &graphql.ArgumentConfig{Type: graphql.Int},
+		"cursor": &graphql.ArgumentConfig{Type: graphql.String, Description: "Cursor taken from next or prev"},
+		"sort":   &graphql.ArgumentConfig{Type: graphql.String, Description: "Sort field, prefix with - for descending"},
+	}
+	taskPageArgs := graphql.FieldConfigArgument{
+		"filter": &graphql.ArgumentConfig{Type: graphql.String, Description: "Filter expression, as in the REST filter parameter"},
+	}
+	for name, arg := range pageArgs {
+		taskPageArgs[name] = arg
+	}
+
+	userInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "UserInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name":     &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"email":    &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"password": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		},
+	})
+	taskInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "TaskInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"title":       &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"description": &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"completed":   &graphql.InputObjectFieldConfig{Type: graphql.Boolean},
+			"userId":      &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.ID)},
+			"dueAt":       &graphql.InputObjectFieldConfig{Type: graphql.DateTime},
+		},
+	})
+
+	idArg := &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)}
+	versionArg := &graphql.ArgumentConfig{Type: graphql.Int, Description: "Only write if the document is still at this version"}
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"user": &graphql.Field{
+				Type:    userType,
+				Args:    graphql.FieldConfigArgument{"id": idArg},
+				Resolve: h.resolveUser,
+			},
+			"users": &graphql.Field{
+				Type:    graphql.NewNonNull(pageType("UserPage", userType)),
+				Args:    pageArgs,
+				Resolve: h.resolveUsers,
+			},
+			"task": &graphql.Field{
+				Type:    taskType,
+				Args:    graphql.FieldConfigArgument{"id": idArg},
+				Resolve: h.resolveTask,
+			},
+			"tasks": &graphql.Field{
+				Type:    graphql.NewNonNull(pageType("TaskPage", taskType)),
+				Args:    taskPageArgs,
+				Resolve: h.resolveTasks,
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createUser": &graphql.Field{
+				Type:    graphql.NewNonNull(userType),
+				Args:    graphql.FieldConfigArgument{"input": {Type: graphql.NewNonNull(userInput)}},
+				Resolve: h.resolveCreateUser,
+			},
+			"updateUser": &graphql.Field{
+				Type:    graphql.NewNonNull(userType),
+				Args:    graphql.FieldConfigArgument{"id": idArg, "input": {Type: graphql.NewNonNull(userInput)}, "version": versionArg},
+				Resolve: h.resolveUpdateUser,
+			},
+			"deleteUser": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.Boolean),
+				Args:    graphql.FieldConfigArgument{"id": idArg, "version": versionArg},
+				Resolve: h.resolveDeleteUser,
+			},
+			"createTask": &graphql.Field{
+				Type:    graphql.NewNonNull(taskType),
+				Args:    graphql.FieldConfigArgument{"input": {Type: graphql.NewNonNull(taskInput)}},
+				Resolve: h.resolveCreateTask,
+			},
+			"updateTask": &graphql.Field{
+				Type:    graphql.NewNonNull(taskType),
+				Args:    graphql.FieldConfigArgument{"id": idArg, "input": {Type: graphql.NewNonNull(taskInput)}, "version": versionArg},
+				Resolve: h.resolveUpdateTask,
+			},
+			"deleteTask": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.Boolean),
+				Args:    graphql.FieldConfigArgument{"id": idArg, "version": versionArg},
+				Resolve: h.resolveDeleteTask,
+			},
+		},
+	})
+
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: query, Mutation: mutation})
+	if err != nil {
+		log.Fatal("Invalid GraphQL schema: ", err)
+	}
+	return schema
+}
+
+// pageType is a page of items with the cursors of the neighbouring pages
+func pageType(name string, itemType *graphql.Object) *graphql.Object {
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: name,
+		Fields: graphql.Fields{
+			"items": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(itemType)))},
+			"next":  &graphql.Field{Type: graphql.String},
+			"prev":  &graphql.Field{Type: graphql.String},
+			"total": &graphql.Field{Type: graphql.Int, Description: "Only counted when selected"},
+		},
+	})
+}
+
+func resolveUserID(p graphql.ResolveParams) (interface{}, error) {
+	return p.Source.(*models.UserResponse).ID.Hex(), nil
+}
+
+func resolveTaskID(p graphql.ResolveParams) (interface{}, error) {
+	return p.Source.(models.Task).ID.Hex(), nil
+}
+
+func resolveTaskUserID(p graphql.ResolveParams) (interface{}, error) {
+	return p.Source.(models.Task).UserID.Hex(), nil
+}
+
+// resolveUserTasks queues the user for the batched task lookup
+func resolveUserTasks(p graphql.ResolveParams) (interface{}, error) {
+	thunk := graphQLLoaders(p.Context).tasksForUser.Load(p.Context, p.Source.(*models.UserResponse).ID)
+	return func() (interface{}, error) {
+		return thunk()
+	}, nil
+}
+
+// resolveTaskUser queues the owner for the batched user lookup
+func resolveTaskUser(p graphql.ResolveParams) (interface{}, error) {
+	return loadUser(p.Context, p.Source.(models.Task).UserID), nil
+}
+
+// loadUser returns a thunk resolving to the user or null
+func loadUser(ctx context.Context, id primitive.ObjectID) func() (interface{}, error) {
+	thunk := graphQLLoaders(ctx).users.Load(ctx, id)
+	return func() (interface{}, error) {
+		user, err := thunk()
+		if user == nil {
+			return nil, err
+		}
+		return user, err
+	}
+}
+
+func (h *Handlers) resolveUser(p graphql.ResolveParams) (interface{}, error) {
+	id, err := objectIDArg(p, "id")
+	if err != nil {
+		return nil, err
+	}
+	return loadUser(p.Context, id), nil
+}
+
+func (h *Handlers) resolveTask(p graphql.ResolveParams) (interface{}, error) {
+	id, err := objectIDArg(p, "id")
+	if err != nil {
+		return nil, err
+	}
+
+	task, err := h.Tasks.GetTask(p.Context, id)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return *task, nil
+}
+
+func (h *Handlers) resolveUsers(p graphql.ResolveParams) (interface{}, error) {
+	req, err := graphQLPageRequest(p, store.UserSortFields)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := h.Users.ListUsers(p.Context, nil, req)
+	if err != nil {
+		return nil, err
+	}
+
+	users := make([]*models.UserResponse, len(result.Items))
+	for i := range result.Items {
+		users[i] = &result.Items[i]
+	}
+	return graphQLPage(users, result.Next, result.Prev, result.Total), nil
+}
+
+func (h *Handlers) resolveTasks(p graphql.ResolveParams) (interface{}, error) {
+	req, err := graphQLPageRequest(p, store.TaskSortFields)
+	if err != nil {
+		return nil, err
+	}
+
+	expression, _ := p.Args["filter"].(string)
+	expr, err := filter.Parse(expression, store.TaskFilterFields)
+	if err != nil {
+		return nil, errors.New("Invalid filter: " + err.Error())
+	}
+
+	result, err := h.Tasks.ListTasks(p.Context, expr, req, nil)
+	if err != nil {
+		return nil, err
+	}
+	return graphQLPage(result.Items, result.Next, result.Prev, result.Total), nil
+}
+
+// graphQLPageRequest reads the pagination arguments. The total is only
+// counted when the query selects it.
+func graphQLPageRequest(p graphql.ResolveParams, sortFields map[string]string) (store.PageRequest, error) {
+	limit, _ := p.Args["limit"].(int)
+	token, _ := p.Args["cursor"].(string)
+	sort, _ := p.Args["sort"].(string)
+	return store.NewPageRequest(limit, token, sort, selects(p, "total"), sortFields)
+}
+
+// selects reports whether the resolved field selects a direct subfield
+func selects(p graphql.ResolveParams, name string) bool {
+	for _, field := range p.Info.FieldASTs {
+		if field.SelectionSet == nil {
+			continue
+		}
+		for _, selection := range field.SelectionSet.Selections {
+			if subfield, ok := selection.(*ast.Field); ok && subfield.Name.Value == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func graphQLPage(items interface{}, next, prev *store.Cursor, total *int64) map[string]interface{} {
+	result := map[string]interface{}{"items": items, "next": nil, "prev": nil, "total": nil}
+	if next != nil {
+		result["next"] = store.EncodeCursor(next)
+	}
+	if prev != nil {
+		result["prev"] = store.EncodeCursor(prev)
+	}
+	if total != nil {
+		result["total"] = *total
+	}
+	return result
+}
+
+func (h *Handlers) resolveCreateUser(p graphql.ResolveParams) (interface{}, error) {
+	user, err := h.Users.CreateUser(p.Context, userFromInput(p.Args["input"]))
+	if err != nil {
+		log.Error("Error creating user: ", err)
+		return nil, graphQLStoreError(err, errUserNotFound)
+	}
+
+	log.Info("User created successfully")
+	return user, nil
+}
+
+func (h *Handlers) resolveUpdateUser(p graphql.ResolveParams) (interface{}, error) {
+	objId, err := objectIDArg(p, "id")
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := h.Users.ReplaceUser(p.Context, objId, userFromInput(p.Args["input"]), versionArg(p))
+	if err != nil {
+		log.Error("Error updating user: ", err)
+		return nil, graphQLStoreError(err, errUserNotFound)
+	}
+
+	log.Info("User updated successfully")
+	return user, nil
+}
+
+func (h *Handlers) resolveDeleteUser(p graphql.ResolveParams) (interface{}, error) {
+	objId, err := objectIDArg(p, "id")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.Users.DeleteUser(p.Context, objId, versionArg(p)); err != nil {
+		log.Error("Error deleting user: ", err)
+		return nil, graphQLStoreError(err, errUserNotFound)
+	}
+
+	log.Info("User deleted successfully")
+	return true, nil
+}
+
+func (h *Handlers) resolveCreateTask(p graphql.ResolveParams) (interface{}, error) {
+	input, err := taskFromInput(p.Args["input"])
+	if err != nil {
+		return nil, err
+	}
+
+	task, err := h.Tasks.CreateTask(p.Context, input, graphQLActor(p.Context))
+	if err != nil {
+		log.Error("Error creating task: ", err)
+		return nil, graphQLStoreError(err, errTaskNotFound)
+	}
+
+	log.Info("Task created successfully")
+	return *task, nil
+}
+
+func (h *Handlers) resolveUpdateTask(p graphql.ResolveParams) (interface{}, error) {
+	objId, err := objectIDArg(p, "id")
+	if err != nil {
+		return nil, err
+	}
+	input, err := taskFromInput(p.Args["input"])
+	if err != nil {
+		return nil, err
+	}
+
+	task, err := h.Tasks.ReplaceTask(p.Context, objId, input, versionArg(p), graphQLActor(p.Context))
+	if err != nil {
+		log.Error("Error updating task: ", err)
+		return nil, graphQLStoreError(err, errTaskNotFound)
+	}
+
+	log.Info("Task updated successfully")
+	return *task, nil
+}
+
+func (h *Handlers) resolveDeleteTask(p graphql.ResolveParams) (interface{}, error) {
+	objId, err := objectIDArg(p, "id")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.Tasks.DeleteTask(p.Context, objId, versionArg(p), graphQLActor(p.Context)); err != nil {
+		log.Error("Error deleting task: ", err)
+		return nil, graphQLStoreError(err, errTaskNotFound)
+	}
+
+	log.Info("Task deleted successfully")
+	return true, nil
+}
+
+// objectIDArg parses an ID argument
+func objectIDArg(p graphql.ResolveParams, name string) (primitive.ObjectID, error) {
+	value, _ := p.Args[name].(string)
+	id, err := primitive.ObjectIDFromHex(value)
+	if err != nil {
+		return id, errors.New("invalid " + name + ": " + value)
+	}
+	return id, nil
+}
+
+// versionArg returns the versions a write accepts: the version argument
+// when given, any version otherwise
+func versionArg(p graphql.ResolveParams) []int64 {
+	if version, ok := p.Args["version"].(int); ok {
+		return []int64{int64(version)}
+	}
+	return nil
+}
+
+// graphQLStoreError maps the store errors to the messages of the GraphQL API
+func graphQLStoreError(err, notFound error) error {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		return notFound
+	case errors.Is(err, store.ErrStale):
+		return errStaleVersion
+	}
+	return err
+}
+
+func userFromInput(input interface{}) models.User {
+	fields, _ := input.(map[string]interface{})
+	name, _ := fields["name"].(string)
+	email, _ := fields["email"].(string)
+	password, _ := fields["password"].(string)
+	return models.User{Name: name, Email: email, Password: password}
+}
+
+func taskFromInput(input interface{}) (models.Task, error) {
+	fields, _ := input.(map[string]interface{})
+	task := models.Task{}
+	task.Title, _ = fields["title"].(string)
+	task.Description, _ = fields["description"].(string)
+	task.Completed, _ = fields["completed"].(bool)
+	if dueAt, ok := fields["dueAt"].(time.Time); ok {
+		task.DueAt = &dueAt
+	}
+
+	userId, _ := fields["userId"].(string)
+	id, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return task, errors.New("invalid userId: " + userId)
+	}
+	task.UserID = id
+	return task, nil
+}
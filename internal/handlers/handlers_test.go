@@ -0,0 +1,52 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// send makes a request to app as actor, with body as JSON unless it is nil,
+// and returns the status and decoded JSON object of the response
+func send(t *testing.T, app *fiber.App, method, path, actor string, body interface{}) (int, fiber.Map) {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	if actor != "" {
+		req.Header.Set("X-User-ID", actor)
+	}
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var decoded fiber.Map
+	if data, _ := io.ReadAll(resp.Body); len(data) > 0 {
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("%s %s: response is not a JSON object: %s", method, path, data)
+		}
+	}
+	return resp.StatusCode, decoded
+}
+
+// expectStatus fails the test when a response doesn't have the wanted status
+func expectStatus(t *testing.T, what string, got, want int, body fiber.Map) {
+	t.Helper()
+	if got != want {
+		t.Fatalf("%s: status %d, want %d: %v", what, got, want, body)
+	}
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GetTaskHistory handles the fetching of the history of a task
+// @Summary Get the history of a task
+// @Description Fetch every recorded change of a task, newest first
+// @Param taskId path string true "Task ID"
+// @Success 200 {object} fiber.Map
+// @Failure 500 Internal Server Error
+// @Router /tasks/{taskId}/history [get]
+func (h *Handlers) GetTaskHistory(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	taskId := c.Params("taskId")
+	defer cancel()
+
+	objId, _ := primitive.ObjectIDFromHex(taskId)
+
+	history, err := h.Tasks.TaskHistory(ctx, objId)
+	if err != nil {
+		log.Error("Error fetching task history: ", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch task history",
+		})
+	}
+
+	log.Info("Task history fetched successfully")
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"history": history,
+		"count":   len(history),
+	})
+}
+
+// RevertTask handles restoring a task to a prior version
+// @Summary Revert a task to a prior version
+// @Description Restore a task to the snapshot stored in one of its history entries
+// @Param taskId path string true "Task ID"
+// @Param historyId path string true "History entry ID"
+// @Success 200 {object} models.Task
+// @Failure 404 Not Found
+// @Failure 500 Internal Server Error
+// @Router /tasks/{taskId}/history/{historyId}/revert [post]
+func (h *Handlers) RevertTask(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	taskId := c.Params("taskId")
+	historyId := c.Params("historyId")
+	defer cancel()
+
+	objId, _ := primitive.ObjectIDFromHex(taskId)
+	entryId, _ := primitive.ObjectIDFromHex(historyId)
+
+	task, err := h.Tasks.RevertTask(ctx, objId, entryId, requestActor(c))
+	if err != nil {
+		log.Error("Error reverting task: ", err)
+		return storeError(c, err, "History entry not found")
+	}
+
+	log.Info("Task reverted successfully")
+	c.Set(fiber.HeaderETag, etag(task.Version))
+	return c.Status(http.StatusOK).JSON(task)
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cmerin0/tasky/internal/jobs"
+	"github.com/cmerin0/tasky/internal/models"
+	"github.com/cmerin0/tasky/internal/store"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Sortable job fields, keyed by the name clients use
+var jobSortFields = map[string]string{"id": "_id", "runAt": "runAt", "status": "status"}
+
+// GetJobs handles the listing of background jobs
+// @Summary List background jobs
+// @Description Get a page of background jobs, optionally of one type or status
+// @Param X-User-ID header string true "ID of an admin user, listed in ADMIN_USER_IDS"
+// @Param type query string false "Only list jobs of this type"
+// @Param status query string false "Only jobs with this status: pending, running, succeeded, dead or cancelled"
+// @Param limit query int false "Number of jobs per page"
+// @Param cursor query string false "Opaque cursor taken from a next or prev link"
+// @Param sort query string false "Sort field: id, runAt or status, prefix with - for descending"
+// @Param count query bool false "Set to false to skip counting the total"
+// @Success 200 {object} fiber.Map
+// @Failure 400 Bad Request
+// @Failure 401 Unauthorized
+// @Failure 403 Forbidden
+// @Failure 500 Internal Server Error
+// @Router /admin/jobs [get]
+func (h *Handlers) GetJobs(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	limit, _ := strconv.Atoi(c.Query("limit", strconv.Itoa(store.DefaultPageLimit)))
+	req, err := store.NewPageRequest(limit, c.Query("cursor"), defaultSort(c, "-id"), c.Query("count") != "false", jobSortFields)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	status := c.Query("status")
+	switch status {
+	case "", models.JobPending, models.JobRunning, models.JobSucceeded, models.JobDead, models.JobCancelled:
+	default:
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": "status must be pending, running, succeeded, dead or cancelled"})
+	}
+
+	page, err := h.Jobs.ListJobs(ctx, c.Query("type"), status, req)
+	if err != nil {
+		log.Error("Error fetching jobs: ", err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	log.Info("Jobs fetched successfully")
+	return c.Status(http.StatusOK).JSON(pageResponse(c, "jobs", page, req))
+}
+
+// GetJob handles the retrieval of a background job by ID
+// @Summary Get a background job
+// @Description Get a background job by ID with its payload and last error
+// @Param X-User-ID header string true "ID of an admin user, listed in ADMIN_USER_IDS"
+// @Param jobId path string true "Job ID"
+// @Success 200 {object} models.Job
+// @Failure 400 Bad Request
+// @Failure 401 Unauthorized
+// @Failure 403 Forbidden
+// @Failure 404 Not Found
+// @Failure 500 Internal Server Error
+// @Router /admin/jobs/{jobId} [get]
+func (h *Handlers) GetJob(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	id, err := pathID(c, "jobId")
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	job, err := h.Jobs.GetJob(ctx, id)
+	if err != nil {
+		log.Error("Error fetching job: ", err)
+		return storeError(c, err, "Job not found")
+	}
+
+	log.Info("Job fetched successfully")
+	return c.Status(http.StatusOK).JSON(job)
+}
+
+// RetryJob handles running a background job again
+// @Summary Retry a background job
+// @Description Run a dead, cancelled or pending job now, from its first attempt. Retrying the job of a failed webhook delivery sets the delivery back to pending.
+// @Param X-User-ID header string true "ID of an admin user, listed in ADMIN_USER_IDS"
+// @Param jobId path string true "Job ID"
+// @Success 200 {object} models.Job
+// @Failure 400 Bad Request
+// @Failure 401 Unauthorized
+// @Failure 403 Forbidden
+// @Failure 404 Not Found
+// @Failure 409 Conflict
+// @Failure 500 Internal Server Error
+// @Router /admin/jobs/{jobId}/retry [post]
+func (h *Handlers) RetryJob(c *fiber.Ctx) error {
+	return transitionJob(c, h.Jobs.RetryJob, "retried")
+}
+
+// CancelJob handles cancelling a background job
+// @Summary Cancel a background job
+// @Description Stop a pending job from running. A running job finishes but isn't retried.
+// @Param X-User-ID header string true "ID of an admin user, listed in ADMIN_USER_IDS"
+// @Param jobId path string true "Job ID"
+// @Success 200 {object} models.Job
+// @Failure 400 Bad Request
+// @Failure 401 Unauthorized
+// @Failure 403 Forbidden
+// @Failure 404 Not Found
+// @Failure 409 Conflict
+// @Failure 500 Internal Server Error
+// @Router /admin/jobs/{jobId}/cancel [post]
+func (h *Handlers) CancelJob(c *fiber.Ctx) error {
+	return transitionJob(c, h.Jobs.CancelJob, "cancelled")
+}
+
+// transitionJob applies a status change to the job in the path
+func transitionJob(c *fiber.Ctx, change func(context.Context, primitive.ObjectID) (*models.Job, error), done string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	id, err := pathID(c, "jobId")
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	job, err := change(ctx, id)
+	if errors.Is(err, jobs.ErrStatus) {
+		return c.Status(http.StatusConflict).JSON(fiber.Map{"message": "The job can't be " + done + " in its current status"})
+	}
+	if err != nil {
+		log.Error("Error updating job: ", err)
+		return storeError(c, err, "Job not found")
+	}
+
+	log.Info("Job " + done + " successfully")
+	return c.Status(http.StatusOK).JSON(job)
+}
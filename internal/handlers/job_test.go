@@ -0,0 +1,93 @@
+package handlers_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cmerin0/tasky/internal/handlers"
+	"github.com/cmerin0/tasky/internal/jobs"
+	"github.com/cmerin0/tasky/internal/middleware"
+	"github.com/cmerin0/tasky/internal/models"
+	"github.com/cmerin0/tasky/internal/store"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeJobs holds jobs in a map, retrying dead jobs and cancelling pending ones
+type fakeJobs struct {
+	store.JobRepository
+	jobs map[primitive.ObjectID]*models.Job
+}
+
+func (f *fakeJobs) GetJob(ctx context.Context, id primitive.ObjectID) (*models.Job, error) {
+	job, ok := f.jobs[id]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return job, nil
+}
+
+func (f *fakeJobs) RetryJob(ctx context.Context, id primitive.ObjectID) (*models.Job, error) {
+	return f.transition(id, models.JobDead, models.JobPending)
+}
+
+func (f *fakeJobs) CancelJob(ctx context.Context, id primitive.ObjectID) (*models.Job, error) {
+	return f.transition(id, models.JobPending, models.JobCancelled)
+}
+
+func (f *fakeJobs) transition(id primitive.ObjectID, from, to string) (*models.Job, error) {
+	job, ok := f.jobs[id]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	if job.Status != from {
+		return nil, jobs.ErrStatus
+	}
+	job.Status = to
+	return job, nil
+}
+
+func TestJobs(t *testing.T) {
+	dead := &models.Job{ID: primitive.NewObjectID(), Type: "test", Status: models.JobDead}
+	h := handlers.New(nil, nil)
+	h.Jobs = &fakeJobs{jobs: map[primitive.ObjectID]*models.Job{dead.ID: dead}}
+
+	admin, user := primitive.NewObjectID().Hex(), primitive.NewObjectID().Hex()
+	app := fiber.New()
+	adminJobs := app.Group("/admin/jobs", middleware.Admin([]string{admin}))
+	adminJobs.Get("/", h.GetJobs)
+	adminJobs.Get("/:jobId", h.GetJob)
+	adminJobs.Post("/:jobId/retry", h.RetryJob)
+	adminJobs.Post("/:jobId/cancel", h.CancelJob)
+	path := "/admin/jobs/" + dead.ID.Hex()
+	missing := "/admin/jobs/" + primitive.NewObjectID().Hex()
+
+	tests := []struct {
+		method, path, actor string
+		status              int
+		jobStatus           string
+	}{
+		// Only admins get past the middleware
+		{"GET", "/admin/jobs", "", fiber.StatusUnauthorized, ""},
+		{"GET", path, user, fiber.StatusForbidden, ""},
+		{"POST", path + "/retry", user, fiber.StatusForbidden, ""},
+		{"GET", "/admin/jobs?status=lost", admin, fiber.StatusBadRequest, ""},
+		{"GET", "/admin/jobs/nope", admin, fiber.StatusBadRequest, ""},
+		{"GET", missing, admin, fiber.StatusNotFound, ""},
+		{"GET", path, admin, fiber.StatusOK, models.JobDead},
+		{"POST", path + "/cancel", admin, fiber.StatusConflict, ""},
+		{"POST", path + "/retry", admin, fiber.StatusOK, models.JobPending},
+		{"POST", path + "/retry", admin, fiber.StatusConflict, ""},
+		{"POST", missing + "/retry", admin, fiber.StatusNotFound, ""},
+		{"POST", path + "/cancel", admin, fiber.StatusOK, models.JobCancelled},
+	}
+	for _, test := range tests {
+		what := test.method + " " + test.path
+		status, body := send(t, app, test.method, test.path, test.actor, nil)
+		expectStatus(t, what, status, test.status, body)
+		if test.jobStatus != "" && body["status"] != test.jobStatus {
+			t.Fatalf("%s: job status %v, want %s", what, body["status"], test.jobStatus)
+		}
+	}
+}
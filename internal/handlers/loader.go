@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"context"
+	"sync"
+
+	"github.com/cmerin0/tasky/internal/models"
+	"github.com/cmerin0/tasky/internal/store"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// loader batches lookups by key. Load only queues the key and returns a thunk,
+// the first thunk called fetches every queued key in a single query.
+// Results are cached for the lifetime of the loader, which is one request.
+type loader[K comparable, V any] struct {
+	fetch func(ctx context.Context, keys []K) (map[K]V, error)
+
+	mu      sync.Mutex
+	pending []K
+	results map[K]V
+	errs    map[K]error
+}
+
+func newLoader[K comparable, V any](fetch func(ctx context.Context, keys []K) (map[K]V, error)) *loader[K, V] {
+	return &loader[K, V]{fetch: fetch, results: map[K]V{}, errs: map[K]error{}}
+}
+
+// Load queues key and returns a thunk resolving to its value.
+// Keys without a value resolve to the zero value of V.
+func (l *loader[K, V]) Load(ctx context.Context, key K) func() (V, error) {
+	l.mu.Lock()
+	if _, done := l.results[key]; !done {
+		l.pending = append(l.pending, key)
+	}
+	l.mu.Unlock()
+
+	return func() (V, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		if len(l.pending) > 0 {
+			l.dispatch(ctx)
+		}
+		return l.results[key], l.errs[key]
+	}
+}
+
+// dispatch fetches the pending keys, l.mu must be held
+func (l *loader[K, V]) dispatch(ctx context.Context) {
+	keys := make([]K, 0, len(l.pending))
+	seen := map[K]bool{}
+	for _, key := range l.pending {
+		if _, done := l.results[key]; !done && !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	l.pending = nil
+	if len(keys) == 0 {
+		return
+	}
+
+	values, err := l.fetch(ctx, keys)
+	for _, key := range keys {
+		l.results[key] = values[key]
+		if err != nil {
+			l.errs[key] = err
+		}
+	}
+}
+
+// loaders are the batching loaders of a single GraphQL request
+type loaders struct {
+	users        *loader[primitive.ObjectID, *models.UserResponse]
+	tasksForUser *loader[primitive.ObjectID, []models.Task]
+}
+
+func newLoaders(h *Handlers) *loaders {
+	return &loaders{
+		users:        newLoader(h.fetchUsers),
+		tasksForUser: newLoader(h.fetchTasksForUsers),
+	}
+}
+
+// fetchUsers loads users by ID, never selecting the password
+func (h *Handlers) fetchUsers(ctx context.Context, ids []primitive.ObjectID) (map[primitive.ObjectID]*models.UserResponse, error) {
+	users, err := h.Users.FindUsers(ctx, store.IDIn(ids))
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[primitive.ObjectID]*models.UserResponse, len(users))
+	for i := range users {
+		byID[users[i].ID] = &users[i]
+	}
+	return byID, nil
+}
+
+// fetchTasksForUsers loads the tasks of several users, oldest first
+func (h *Handlers) fetchTasksForUsers(ctx context.Context, userIds []primitive.ObjectID) (map[primitive.ObjectID][]models.Task, error) {
+	tasks, err := h.Tasks.FindTasks(ctx, store.FieldIn(store.TaskFilterFields, "userId", userIds))
+	if err != nil {
+		return nil, err
+	}
+
+	byUser := make(map[primitive.ObjectID][]models.Task, len(userIds))
+	for _, userId := range userIds {
+		byUser[userId] = []models.Task{}
+	}
+	for _, task := range tasks {
+		byUser[task.UserID] = append(byUser[task.UserID], task)
+	}
+	return byUser, nil
+}
@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var metricsHandler = adaptor.HTTPHandler(promhttp.Handler())
+
+// Metrics exposes the metrics for Prometheus to scrape
+// @Summary Prometheus metrics
+// @Description Metrics in the Prometheus text format, including tasky_api_requests_total per API version.
+// @Produce plain
+// @Success 200 {string} string
+// @Router /metrics [get]
+func Metrics(c *fiber.Ctx) error {
+	return metricsHandler(c)
+}
@@ -0,0 +1,271 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cmerin0/tasky/internal/models"
+	"github.com/cmerin0/tasky/internal/store"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// preferencesOwner checks the caller is the user whose preferences are
+// requested. It writes the error response itself and returns false when not.
+func preferencesOwner(c *fiber.Ctx) (bool, error) {
+	userId, err := pathID(c, "userId")
+	if err != nil {
+		return false, c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+	actor, ok := requestUserID(c)
+	if !ok {
+		return false, actorRequired(c)
+	}
+	if actor != userId {
+		return false, c.Status(http.StatusForbidden).JSON(fiber.Map{
+			"message": "Users can only manage their own notification preferences",
+		})
+	}
+	return true, nil
+}
+
+// GetNotificationPreferences handles the fetching of a user's notification preferences
+// @Summary Get the notification preferences of a user
+// @Description Fetch the emails the caller receives. Users who never saved preferences get every task email but the daily digest.
+// @Param userId path string true "User ID, must be the caller"
+// @Success 200 {object} models.NotificationPreferences
+// @Failure 400 Bad Request
+// @Failure 401 Unauthorized
+// @Failure 403 Forbidden
+// @Failure 500 Internal Server Error
+// @Router /users/{userId}/notification-preferences [get]
+func (h *Handlers) GetNotificationPreferences(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if ok, err := preferencesOwner(c); !ok {
+		return err
+	}
+	userId, _ := pathID(c, "userId")
+
+	prefs, err := h.Notifications.GetNotificationPreferences(ctx, userId)
+	if err != nil {
+		log.Error("Error fetching notification preferences: ", err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	log.Info("Notification preferences fetched successfully")
+	return c.Status(http.StatusOK).JSON(prefs)
+}
+
+// UpdateNotificationPreferences handles the updating of a user's notification preferences
+// @Summary Update the notification preferences of a user
+// @Description Replace the emails the caller receives: task assignments, due soon and overdue reminders, and a daily digest of open tasks sent at digestHour (UTC). Setting email to false turns them all off.
+// @Param userId path string true "User ID, must be the caller"
+// @Param preferences body models.NotificationPreferences true "Notification preferences"
+// @Success 200 {object} models.NotificationPreferences
+// @Failure 400 Bad Request
+// @Failure 401 Unauthorized
+// @Failure 403 Forbidden
+// @Failure 500 Internal Server Error
+// @Router /users/{userId}/notification-preferences [put]
+func (h *Handlers) UpdateNotificationPreferences(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	var prefs models.NotificationPreferences
+	defer cancel()
+
+	if ok, err := preferencesOwner(c); !ok {
+		return err
+	}
+	userId, _ := pathID(c, "userId")
+
+	if err := c.BodyParser(&prefs); err != nil {
+		log.Error("Error parsing notification preferences: ", err)
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+	prefs.UserID = userId
+
+	if err := h.Notifications.SaveNotificationPreferences(ctx, prefs); err != nil {
+		log.Error("Error saving notification preferences: ", err)
+		return storeError(c, err, "User not found")
+	}
+
+	log.Info("Notification preferences updated successfully")
+	return c.Status(http.StatusOK).JSON(prefs)
+}
+
+// GetNotifications handles the listing of the caller's notifications
+// @Summary List the caller's notifications
+// @Description Get a page of the caller's in-app notifications, newest first, with their unread count. Each user keeps their latest 200 notifications, read ones are deleted after 30 days and unread ones after 90.
+// @Param unread query bool false "Only list unread notifications"
+// @Param limit query int false "Number of notifications per page"
+// @Param cursor query string false "Opaque cursor taken from a next or prev link"
+// @Param count query bool false "Set to false to skip counting the total"
+// @Success 200 {object} fiber.Map
+// @Failure 400 Bad Request
+// @Failure 401 Unauthorized
+// @Failure 500 Internal Server Error
+// @Router /notifications [get]
+func (h *Handlers) GetNotifications(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	userId, ok := requestUserID(c)
+	if !ok {
+		return actorRequired(c)
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit", strconv.Itoa(store.DefaultPageLimit)))
+	req, err := store.NewPageRequest(limit, c.Query("cursor"), "-id", c.Query("count") != "false", store.NotificationSortFields)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	page, err := h.Notifications.ListNotifications(ctx, userId, c.QueryBool("unread"), req)
+	if err != nil {
+		log.Error("Error fetching notifications: ", err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"message": err.Error()})
+	}
+	unread, err := h.Notifications.CountUnread(ctx, userId)
+	if err != nil {
+		log.Error("Error counting unread notifications: ", err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	log.Info("Notifications fetched successfully")
+	response := pageResponse(c, "notifications", page, req)
+	response["unread"] = unread
+	return c.Status(http.StatusOK).JSON(response)
+}
+
+// MarkNotificationRead handles marking one of the caller's notifications as read
+// @Summary Mark a notification as read
+// @Description Mark one of the caller's notifications as read, marking it again keeps its first read time
+// @Param notificationId path string true "Notification ID"
+// @Success 200 {object} models.Notification
+// @Failure 400 Bad Request
+// @Failure 401 Unauthorized
+// @Failure 404 Not Found
+// @Failure 500 Internal Server Error
+// @Router /notifications/{notificationId}/read [post]
+func (h *Handlers) MarkNotificationRead(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	userId, ok := requestUserID(c)
+	if !ok {
+		return actorRequired(c)
+	}
+	id, err := pathID(c, "notificationId")
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	notification, err := h.Notifications.MarkNotificationRead(ctx, userId, id)
+	if err != nil {
+		log.Error("Error marking notification as read: ", err)
+		return storeError(c, err, "Notification not found")
+	}
+
+	log.Info("Notification marked as read")
+	return c.Status(http.StatusOK).JSON(notification)
+}
+
+// MarkAllNotificationsRead handles marking every notification of the caller as read
+// @Summary Mark all notifications as read
+// @Description Mark every unread notification of the caller as read
+// @Success 200 {object} fiber.Map
+// @Failure 401 Unauthorized
+// @Failure 500 Internal Server Error
+// @Router /notifications/read-all [post]
+func (h *Handlers) MarkAllNotificationsRead(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	userId, ok := requestUserID(c)
+	if !ok {
+		return actorRequired(c)
+	}
+
+	updated, err := h.Notifications.MarkAllNotificationsRead(ctx, userId)
+	if err != nil {
+		log.Error("Error marking notifications as read: ", err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	log.Info("Notifications marked as read")
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"message": "Notifications marked as read",
+		"updated": updated,
+	})
+}
+
+// WatchTask handles subscribing the caller to a task
+// @Summary Watch a task
+// @Description Get an in-app notification when the task is completed. Watching a task again is a no-op.
+// @Param taskId path string true "Task ID"
+// @Success 200 {object} fiber.Map
+// @Failure 400 Bad Request
+// @Failure 401 Unauthorized
+// @Failure 404 Not Found
+// @Failure 500 Internal Server Error
+// @Router /tasks/{taskId}/watch [put]
+func (h *Handlers) WatchTask(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	userId, ok := requestUserID(c)
+	if !ok {
+		return actorRequired(c)
+	}
+	taskId, err := pathID(c, "taskId")
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	if _, err := h.Tasks.GetTask(ctx, taskId); err != nil {
+		log.Error("Error fetching task: ", err)
+		return storeError(c, err, "Task not found")
+	}
+	if err := h.Notifications.WatchTask(ctx, taskId, userId); err != nil {
+		log.Error("Error watching task: ", err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	log.Info("Task watched successfully")
+	return c.Status(http.StatusOK).JSON(fiber.Map{"message": "Task watched successfully"})
+}
+
+// UnwatchTask handles unsubscribing the caller from a task
+// @Summary Stop watching a task
+// @Description Stop the notifications about a task the caller watches, if any
+// @Param taskId path string true "Task ID"
+// @Success 200 {object} fiber.Map
+// @Failure 400 Bad Request
+// @Failure 401 Unauthorized
+// @Failure 500 Internal Server Error
+// @Router /tasks/{taskId}/watch [delete]
+func (h *Handlers) UnwatchTask(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	userId, ok := requestUserID(c)
+	if !ok {
+		return actorRequired(c)
+	}
+	taskId, err := pathID(c, "taskId")
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	if err := h.Notifications.UnwatchTask(ctx, taskId, userId); err != nil {
+		log.Error("Error unwatching task: ", err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	log.Info("Task unwatched successfully")
+	return c.Status(http.StatusOK).JSON(fiber.Map{"message": "Task unwatched successfully"})
+}
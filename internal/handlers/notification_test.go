@@ -0,0 +1,68 @@
+package handlers_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cmerin0/tasky/internal/handlers"
+	"github.com/cmerin0/tasky/internal/models"
+	"github.com/cmerin0/tasky/internal/store"
+	"github.com/cmerin0/tasky/internal/store/memory"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeNotifications records the tasks watched by each user
+type fakeNotifications struct {
+	store.NotificationRepository
+	watches map[primitive.ObjectID][]primitive.ObjectID
+}
+
+func (f *fakeNotifications) WatchTask(ctx context.Context, taskId, userId primitive.ObjectID) error {
+	f.watches[userId] = append(f.watches[userId], taskId)
+	return nil
+}
+
+func (f *fakeNotifications) UnwatchTask(ctx context.Context, taskId, userId primitive.ObjectID) error {
+	delete(f.watches, userId)
+	return nil
+}
+
+func TestWatchTask(t *testing.T) {
+	repo := memory.New()
+	notifications := &fakeNotifications{watches: map[primitive.ObjectID][]primitive.ObjectID{}}
+	h := handlers.New(repo, repo)
+	h.Notifications = notifications
+
+	app := fiber.New()
+	app.Put("/tasks/:taskId/watch", h.WatchTask)
+	app.Delete("/tasks/:taskId/watch", h.UnwatchTask)
+
+	task, err := repo.CreateTask(context.Background(), models.Task{Title: "Ship", UserID: primitive.NewObjectID()}, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := primitive.NewObjectID()
+	path := "/tasks/" + task.ID.Hex() + "/watch"
+
+	status, body := send(t, app, "PUT", path, "", nil)
+	expectStatus(t, "watch without actor", status, fiber.StatusUnauthorized, body)
+	status, body = send(t, app, "PUT", "/tasks/"+primitive.NewObjectID().Hex()+"/watch", user.Hex(), nil)
+	expectStatus(t, "watch a missing task", status, fiber.StatusNotFound, body)
+	if len(notifications.watches) != 0 {
+		t.Fatalf("a missing task was watched: %v", notifications.watches)
+	}
+
+	status, body = send(t, app, "PUT", path, user.Hex(), nil)
+	expectStatus(t, "watch", status, fiber.StatusOK, body)
+	if watched := notifications.watches[user]; len(watched) != 1 || watched[0] != task.ID {
+		t.Fatalf("watched tasks %v, want %v", watched, task.ID)
+	}
+
+	status, body = send(t, app, "DELETE", path, user.Hex(), nil)
+	expectStatus(t, "unwatch", status, fiber.StatusOK, body)
+	if len(notifications.watches) != 0 {
+		t.Fatalf("the task is still watched: %v", notifications.watches)
+	}
+}
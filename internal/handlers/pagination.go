@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+
+	"github.com/cmerin0/tasky/internal/filter"
+	"github.com/cmerin0/tasky/internal/store"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// parseFilter parses the filter query parameter, nil when there is none
+func parseFilter(c *fiber.Ctx, fields filter.Fields) (filter.Expr, error) {
+	return filter.Parse(c.Query("filter"), fields)
+}
+
+// filterError renders an invalid filter as a 400 pointing at the error position
+func filterError(c *fiber.Ctx, err error) error {
+	response := fiber.Map{"message": "Invalid filter: " + err.Error()}
+	var syntaxErr *filter.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		response["position"] = syntaxErr.Pos
+	}
+	return c.Status(fiber.StatusBadRequest).JSON(response)
+}
+
+// parsePageRequest reads limit, sort, cursor and count from the query string.
+// sortFields whitelists the fields a client may sort by.
+func parsePageRequest(c *fiber.Ctx, sortFields map[string]string) (store.PageRequest, error) {
+	limit, _ := strconv.Atoi(c.Query("limit", strconv.Itoa(store.DefaultPageLimit)))
+	return store.NewPageRequest(limit, c.Query("cursor"), c.Query("sort"), c.Query("count") != "false", sortFields)
+}
+
+// defaultSort returns the sort of the request, or def when neither a sort
+// nor a cursor, which carries its own, is given
+func defaultSort(c *fiber.Ctx, def string) string {
+	if c.Query("cursor") != "" {
+		return c.Query("sort")
+	}
+	return c.Query("sort", def)
+}
+
+// pageLink returns the URL of the current request with its cursor replaced.
+// The sort is kept, cursors only follow the sort they were made for.
+func pageLink(c *fiber.Ctx, cur *store.Cursor) interface{} {
+	if cur == nil {
+		return nil
+	}
+	query, _ := url.ParseQuery(string(c.Request().URI().QueryString()))
+	query.Set("cursor", store.EncodeCursor(cur))
+	return c.BaseURL() + c.Path() + "?" + query.Encode()
+}
+
+// pageResponse renders a page under the given key together with its links
+func pageResponse[T any](c *fiber.Ctx, key string, p *store.Page[T], req store.PageRequest) fiber.Map {
+	response := fiber.Map{
+		key:     p.Items,
+		"count": len(p.Items),
+		"limit": req.Limit,
+		"next":  pageLink(c, p.Next),
+		"prev":  pageLink(c, p.Prev),
+	}
+	if p.Total != nil {
+		response["total"] = *p.Total
+	}
+	return response
+}
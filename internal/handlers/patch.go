@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cmerin0/tasky/internal/models"
+	"github.com/cmerin0/tasky/internal/patch"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var errUnsupportedPatch = errors.New("unsupported patch format, use " +
+	patch.MergePatchType + " or " + patch.JSONPatchType)
+
+var errIDChanged = errors.New("id cannot be changed")
+
+// applyPatch applies the request body to a JSON document according to the
+// request content type. Plain JSON bodies are treated as merge patches.
+func applyPatch(c *fiber.Ctx, doc []byte) ([]byte, error) {
+	mediaType := strings.TrimSpace(strings.Split(c.Get(fiber.HeaderContentType), ";")[0])
+
+	switch strings.ToLower(mediaType) {
+	case patch.JSONPatchType:
+		return patch.JSONPatch(doc, c.Body())
+	case patch.MergePatchType, fiber.MIMEApplicationJSON, "":
+		return patch.MergePatch(doc, c.Body())
+	default:
+		return nil, errUnsupportedPatch
+	}
+}
+
+// patchError maps a patch that couldn't be applied to its response
+func patchError(c *fiber.Ctx, err error) error {
+	status := http.StatusBadRequest
+	switch {
+	case errors.Is(err, errUnsupportedPatch):
+		status = http.StatusUnsupportedMediaType
+	case errors.Is(err, patch.ErrTestFailed):
+		status = http.StatusConflict
+	}
+	return c.Status(status).JSON(fiber.Map{"message": err.Error()})
+}
+
+// PatchTask handles partial updates of a task
+// @Summary Partially update a task by ID
+// @Description Update only the given fields of a task, using a JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902)
+// @Accept application/merge-patch+json,application/json-patch+json
+// @Param taskId path string true "Task ID"
+// @Param If-Match header string false "ETag the task must still have"
+// @Param patch body any true "Merge patch or JSON Patch document"
+// @Success 200 {object} models.Task
+// @Failure 400 Bad Request
+// @Failure 404 Not Found
+// @Failure 409 Conflict
+// @Failure 412 Precondition Failed
+// @Failure 415 Unsupported Media Type
+// @Failure 500 Internal Server Error
+// @Router /tasks/{taskId} [patch]
+func (h *Handlers) PatchTask(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	taskId := c.Params("taskId")
+	defer cancel()
+
+	objId, _ := primitive.ObjectIDFromHex(taskId)
+
+	versions, err := ifMatchVersions(c)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	// The patch is applied to the version the store reads, only the fields
+	// that actually changed are written
+	var patchErr error
+	task, err := h.Tasks.UpdateTask(ctx, objId, versions, func(task *models.Task) error {
+		patchErr = patchTask(c, task)
+		return patchErr
+	}, requestActor(c))
+	if patchErr != nil {
+		log.Error("Error patching task: ", patchErr)
+		return patchError(c, patchErr)
+	}
+	if err != nil {
+		log.Error("Error updating task: ", err)
+		return storeError(c, err, "Task not found")
+	}
+
+	log.Info("Task patched successfully")
+	c.Set(fiber.HeaderETag, etag(task.Version))
+	return c.Status(http.StatusOK).JSON(task)
+}
+
+// patchTask applies the request body to a task
+func patchTask(c *fiber.Ctx, task *models.Task) error {
+	doc, _ := json.Marshal(task)
+	patched, err := applyPatch(c, doc)
+	if err != nil {
+		return err
+	}
+
+	var after models.Task
+	if err := json.Unmarshal(patched, &after); err != nil {
+		return err
+	}
+	if !after.ID.IsZero() && after.ID != task.ID {
+		return errIDChanged
+	}
+	*task = after
+	return nil
+}
+
+// PatchUser handles partial updates of a user
+// @Summary Partially update a user by ID
+// @Description Update only the given fields of a user, using a JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902). The password is write-only: it can be set but never read or tested.
+// @Accept application/merge-patch+json,application/json-patch+json
+// @Param userId path string true "User ID"
+// @Param If-Match header string false "ETag the user must still have"
+// @Param patch body any true "Merge patch or JSON Patch document"
+// @Success 200 {object} models.UserResponse
+// @Failure 400 Bad Request
+// @Failure 404 Not Found
+// @Failure 409 Conflict
+// @Failure 412 Precondition Failed
+// @Failure 415 Unsupported Media Type
+// @Failure 500 Internal Server Error
+// @Router /users/{userId} [patch]
+func (h *Handlers) PatchUser(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	userId := c.Params("userId")
+	defer cancel()
+
+	objId, _ := primitive.ObjectIDFromHex(userId)
+
+	versions, err := ifMatchVersions(c)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	var patchErr error
+	user, err := h.Users.UpdateUser(ctx, objId, versions, func(user *models.User) error {
+		patchErr = patchUser(c, user)
+		return patchErr
+	})
+	if patchErr != nil {
+		log.Error("Error patching user: ", patchErr)
+		return patchError(c, patchErr)
+	}
+	if err != nil {
+		log.Error("Error updating user: ", err)
+		return storeError(c, err, "User not found")
+	}
+
+	log.Info("User patched successfully")
+	c.Set(fiber.HeaderETag, etag(user.Version))
+	return c.Status(http.StatusOK).JSON(user)
+}
+
+// patchUser applies the request body to a user
+func patchUser(c *fiber.Ctx, user *models.User) error {
+	// Patch the public representation so the password can't be probed
+	doc, _ := json.Marshal(models.UserResponse{ID: user.ID, Name: user.Name, Email: user.Email, Version: user.Version})
+	patched, err := applyPatch(c, doc)
+	if err != nil {
+		return err
+	}
+
+	var after models.User
+	if err := json.Unmarshal(patched, &after); err != nil {
+		return err
+	}
+	if !after.ID.IsZero() && after.ID != user.ID {
+		return errIDChanged
+	}
+	if after.Password == "" {
+		after.Password = user.Password
+	}
+	*user = after
+	return nil
+}
@@ -4,7 +4,7 @@ import (
 	"context"
 	"time"
 
-	"github.com/cmerin0/tasky/internal/db"
+	"github.com/cmerin0/tasky/internal/store"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/log"
 )
@@ -29,21 +29,22 @@ func Healthcheck(c *fiber.Ctx) error {
 // @Success 200 {object} fiber.Map
 // @Failure 503 {object} fiber.Map
 // @Router /readyz [get]
-func ReadinessProbe(c *fiber.Ctx) error {
-	// Check MongoDB connection
+func (h *Handlers) ReadinessProbe(c *fiber.Ctx) error {
+	// Check the storage connection, in-process storage is always ready
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
 
-	// Check if the MongoDB client is connected
-	if err := db.Client.Ping(ctx, nil); err != nil {
-		log.Error("MongoDB not connected: ", err)
-		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
-			"status": "DOWN",
-			"error":  "MongoDB not connected",
-		})
+	if pinger, ok := h.Tasks.(store.Pinger); ok {
+		if err := pinger.Ping(ctx); err != nil {
+			log.Error("Storage not connected: ", err)
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"status": "DOWN",
+				"error":  "Storage not connected",
+			})
+		}
 	}
 
-	log.Info("MongoDB connected")
+	log.Info("Storage connected")
 	return c.JSON(fiber.Map{
 		"status": "READY",
 	})
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cmerin0/tasky/internal/models"
+	"github.com/cmerin0/tasky/internal/store"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Selectable fields, keyed by their JSON name
+var (
+	taskColumns = map[string]string{
+		"id":          "_id",
+		"title":       "title",
+		"description": "description",
+		"completed":   "completed",
+		"userId":      "userId",
+		"dueAt":       "dueAt",
+		"version":     "version",
+	}
+	userColumns = map[string]string{
+		"id":      "_id",
+		"name":    "name",
+		"email":   "email",
+		"version": "version",
+	}
+)
+
+// allTaskColumns is the column selection used when related documents are
+// embedded without a field selection
+var allTaskColumns = []string{"title", "description", "completed", "userId", "dueAt", "version"}
+
+// splitList splits a comma separated query parameter, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseIDs parses the ids query parameter used to fetch documents in batch
+func parseIDs(c *fiber.Ctx) ([]primitive.ObjectID, error) {
+	items := splitList(c.Query("ids"))
+	if len(items) > store.MaxPageLimit {
+		return nil, fmt.Errorf("at most %d ids can be fetched at once", store.MaxPageLimit)
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(items))
+	for _, item := range items {
+		id, err := primitive.ObjectIDFromHex(item)
+		if err != nil {
+			return nil, errors.New("invalid id: " + item)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// embedUsers adds the owner of each task to its row under "user".
+// docs and rows must line up, docs need their userId.
+func (h *Handlers) embedUsers(ctx context.Context, docs []bson.M, rows []fiber.Map) error {
+	var ids []primitive.ObjectID
+	for _, doc := range docs {
+		if id, ok := doc["userId"].(primitive.ObjectID); ok {
+			ids = append(ids, id)
+		}
+	}
+
+	users, err := h.Users.FindUsers(ctx, store.IDIn(ids))
+	if err != nil {
+		return err
+	}
+
+	byId := make(map[primitive.ObjectID]models.UserResponse, len(users))
+	for _, user := range users {
+		byId[user.ID] = user
+	}
+	for i, doc := range docs {
+		rows[i]["user"] = nil
+		if id, ok := doc["userId"].(primitive.ObjectID); ok {
+			if user, found := byId[id]; found {
+				rows[i]["user"] = user
+			}
+		}
+	}
+	return nil
+}
+
+// validateColumns checks that every column is a known field
+func validateColumns(columns []string, known map[string]string) error {
+	for _, column := range columns {
+		if _, ok := known[column]; !ok {
+			return errors.New("unsupported column: " + column)
+		}
+	}
+	return nil
+}
+
+// storedColumns returns the stored field names of columns
+func storedColumns(columns []string, known map[string]string) []string {
+	stored := make([]string, len(columns))
+	for i, column := range columns {
+		stored[i] = known[column]
+	}
+	return stored
+}
+
+// columnDocs turns users or tasks into documents keyed by their stored
+// field names, the names the selectable columns map to
+func columnDocs[T any](items []T) ([]bson.M, error) {
+	docs := make([]bson.M, len(items))
+	for i, item := range items {
+		raw, err := bson.Marshal(item)
+		if err != nil {
+			return nil, err
+		}
+		if err := bson.Unmarshal(raw, &docs[i]); err != nil {
+			return nil, err
+		}
+	}
+	return docs, nil
+}
+
+// selectColumns renders documents with only the selected columns,
+// named as in the regular JSON responses
+func selectColumns(docs []bson.M, columns []string, known map[string]string) []fiber.Map {
+	rows := make([]fiber.Map, 0, len(docs))
+	for _, doc := range docs {
+		row := fiber.Map{"id": doc["_id"]}
+		for _, column := range columns {
+			if value, ok := doc[known[column]]; ok {
+				row[column] = value
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
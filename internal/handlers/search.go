@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"context"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cmerin0/tasky/internal/filter"
+	"github.com/cmerin0/tasky/internal/models"
+	"github.com/cmerin0/tasky/internal/store"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const snippetLength = 160 // Maximum length of a description snippet, in bytes
+
+// SearchResult is a task matching a search, with its relevance and highlights
+type SearchResult struct {
+	models.Task
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights"`
+}
+
+// SearchTasks handles full text search over tasks
+// @Summary Search tasks
+// @Description Search the titles and descriptions of the caller's tasks, most relevant first. Matches are wrapped in <mark> in the highlights. Tasks have no comments yet, so none are searched.
+// @Param X-User-ID header string true "ID of the user searching"
+// @Param q query string true "Search terms"
+// @Param userId query string false "Owner of the tasks, which must be the caller"
+// @Param completed query bool false "Only completed (true) or open (false) tasks"
+// @Param limit query int false "Maximum number of results"
+// @Success 200 {object} fiber.Map
+// @Failure 400 Bad Request
+// @Failure 401 Unauthorized
+// @Failure 403 Forbidden
+// @Failure 500 Internal Server Error
+// @Router /search [get]
+func (h *Handlers) SearchTasks(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Users only ever see their own tasks
+	actorId, ok := requestUserID(c)
+	if !ok {
+		return actorRequired(c)
+	}
+	where := store.FieldIs(store.TaskFilterFields, "userId", actorId)
+
+	q := strings.TrimSpace(c.Query("q"))
+	if q == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Query parameter q is required"})
+	}
+
+	if owner := c.Query("userId"); owner != "" {
+		ownerId, err := primitive.ObjectIDFromHex(owner)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Invalid userId"})
+		}
+		if ownerId != actorId {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"message": "Only your own tasks can be searched"})
+		}
+	}
+
+	if completed := c.Query("completed"); completed != "" {
+		done, err := strconv.ParseBool(completed)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Invalid completed flag"})
+		}
+		where = filter.AllOf(where, store.FieldIs(store.TaskFilterFields, "completed", done))
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit", strconv.Itoa(store.DefaultPageLimit)))
+	if limit < 1 {
+		limit = store.DefaultPageLimit
+	}
+	if limit > store.MaxPageLimit {
+		limit = store.MaxPageLimit
+	}
+
+	matches, err := h.Tasks.SearchTasks(ctx, q, where, limit)
+	if err != nil {
+		log.Error("Error searching tasks: ", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to search tasks",
+		})
+	}
+
+	results := make([]SearchResult, len(matches))
+	for i, match := range matches {
+		results[i] = SearchResult{Task: match.Task, Score: match.Score}
+	}
+
+	terms := searchTerms(q)
+	for i := range results {
+		results[i].Highlights = map[string]string{
+			"title":       highlight(results[i].Title, terms, 0),
+			"description": highlight(results[i].Description, terms, snippetLength),
+		}
+	}
+
+	log.Info("Tasks searched successfully")
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"results": results,
+		"count":   len(results),
+	})
+}
+
+// searchTerms extracts the words to highlight from a text search query,
+// leaving out negated terms
+func searchTerms(q string) []string {
+	var terms []string
+	for _, word := range strings.Fields(strings.ReplaceAll(q, `"`, " ")) {
+		if !strings.HasPrefix(word, "-") {
+			terms = append(terms, strings.ToLower(word))
+		}
+	}
+	return terms
+}
+
+// highlight HTML escapes text and wraps the search terms in <mark> tags.
+// When maxLen is positive the text is cut to a snippet around the first match.
+func highlight(text string, terms []string, maxLen int) string {
+	// Collect the non overlapping matches in order
+	type span struct{ start, end int }
+	var spans []span
+	for i := 0; i < len(text); {
+		matched := 0
+		for _, term := range terms {
+			n := len(term)
+			if n > matched && i+n <= len(text) && strings.EqualFold(text[i:i+n], term) {
+				matched = n
+			}
+		}
+		if matched > 0 {
+			spans = append(spans, span{i, i + matched})
+			i += matched
+		} else {
+			i++
+		}
+	}
+
+	start, end := 0, len(text)
+	if maxLen > 0 && len(text) > maxLen {
+		if len(spans) > 0 {
+			start = max(spans[0].start-maxLen/4, 0)
+		}
+		end = min(start+maxLen, len(text))
+		// Don't cut multi byte characters in half
+		for start > 0 && !utf8.RuneStart(text[start]) {
+			start--
+		}
+		for end < len(text) && !utf8.RuneStart(text[end]) {
+			end++
+		}
+	}
+
+	var sb strings.Builder
+	if start > 0 {
+		sb.WriteString("…")
+	}
+	pos := start
+	for _, s := range spans {
+		if s.start < start || s.end > end {
+			continue
+		}
+		sb.WriteString(html.EscapeString(text[pos:s.start]))
+		sb.WriteString("<mark>" + html.EscapeString(text[s.start:s.end]) + "</mark>")
+		pos = s.end
+	}
+	sb.WriteString(html.EscapeString(text[pos:end]))
+	if end < len(text) {
+		sb.WriteString("…")
+	}
+	return sb.String()
+}
@@ -3,28 +3,27 @@ package handlers
 import (
 	"context"
 	"net/http"
-	"strconv"
 	"time"
 
+	"github.com/cmerin0/tasky/internal/filter"
 	"github.com/cmerin0/tasky/internal/models"
+	"github.com/cmerin0/tasky/internal/store"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/log"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-const maxPaginationLimit = 30 // Maximum number of items to return in a single request
-
 // CreateTask handles the creation of a new task
 // @Summary Create a new task
 // @Description Create a new task in the database
 // @Param task body models.Task true "Task object"
-// @Success 201 {object} models.Task
+// @Success 201 {object} fiber.Map
 // @Failure 400 Bad Request
 // @Failure 500 Internal Server Error
-func CreateTask(c *fiber.Ctx) error {
+// @Deprecated
+// @Router /tasks [post]
+func (h *Handlers) CreateTask(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	var task models.Task
 	defer cancel()
@@ -34,24 +33,16 @@ func CreateTask(c *fiber.Ctx) error {
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
 	}
 
-	newTask := models.Task{
-		Title:       task.Title,
-		Description: task.Description,
-		Completed:   task.Completed,
-		UserID:      task.UserID,
-	}
-
-	taskCollection := getTaskCollection()
-	result, err := taskCollection.InsertOne(ctx, newTask)
+	newTask, err := h.Tasks.CreateTask(ctx, task, requestActor(c))
 	if err != nil {
-		log.Error("Error inserting task: ", err)
-		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"message": err.Error()})
+		log.Error("Error creating task: ", err)
+		return storeError(c, err, "Task not found")
 	}
 
 	log.Info("Task created successfully")
 	return c.Status(http.StatusCreated).JSON(fiber.Map{
 		"message": "Task created successfully",
-		"taskId":  result.InsertedID,
+		"taskId":  newTask.ID,
 	})
 }
 
@@ -59,21 +50,27 @@ func CreateTask(c *fiber.Ctx) error {
 // @Summary Get a task by ID
 // @Description Fetch a task from the database by ID
 // @Param taskId path string true "Task ID"
+// @Param If-None-Match header string false "ETag of a cached copy"
 // @Success 200 {object} models.Task
+// @Success 304 Not Modified
 // @Failure 404 {object} fiber.Map
-func GetTask(c *fiber.Ctx) error {
+// @Deprecated
+// @Router /tasks/{taskId} [get]
+func (h *Handlers) GetTask(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	taskId := c.Params("taskId")
-	var task models.Task
 	defer cancel()
 
 	objId, _ := primitive.ObjectIDFromHex(taskId)
 
-	taskCollection := getTaskCollection()
-	err := taskCollection.FindOne(ctx, bson.M{"_id": objId}).Decode(&task)
+	task, err := h.Tasks.GetTask(ctx, objId)
 	if err != nil {
 		log.Error("Error fetching task: ", err)
-		return c.Status(http.StatusNotFound).JSON(fiber.Map{"message": err.Error()})
+		return storeError(c, err, "Task not found")
+	}
+
+	if notModified(c, task.Version) {
+		return c.SendStatus(http.StatusNotModified)
 	}
 
 	log.Info("Task fetched successfully")
@@ -85,32 +82,17 @@ func GetTask(c *fiber.Ctx) error {
 // @Description Fetch all tasks from the database no pagination
 // @Success 200 {object} []models.Task
 // @Failure 500 Internal Server Error
-func GetAllTasks(c *fiber.Ctx) error {
+func (h *Handlers) GetAllTasks(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	var tasks []models.Task
 	defer cancel()
 
-	cursor, err := getTaskCollection().Find(ctx, bson.M{})
+	tasks, err := h.Tasks.FindTasks(ctx, nil)
 	if err != nil {
 		log.Error("Error fetching all tasks: ", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to fetch tasks",
 		})
 	}
-	defer cursor.Close(ctx)
-
-	for cursor.Next(ctx) {
-		var task models.Task
-		cursor.Decode(&task)
-		tasks = append(tasks, task)
-	}
-
-	if err := cursor.Err(); err != nil {
-		log.Error("Cursor error: ", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Cursor error",
-		})
-	}
 
 	log.Info("All tasks fetched successfully")
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
@@ -119,107 +101,170 @@ func GetAllTasks(c *fiber.Ctx) error {
 	})
 }
 
-// ListTasks handles the listing of tasks with pagination
+// ListTasks handles the listing of tasks with cursor based pagination
 // @Summary List tasks with pagination
-// @Description Get a list of tasks with pagination
-// @Param page query int false "Page number"
+// @Description Get a page of tasks. Follow the next and prev links to move between pages.
 // @Param limit query int false "Number of tasks per page"
+// @Param cursor query string false "Opaque cursor taken from a next or prev link"
+// @Param sort query string false "Sort field (id, title, completed), prefix with - for descending"
+// @Param count query bool false "Set to false to skip counting the total"
+// @Param filter query string false "Filter expression, e.g. completed:false AND title~release"
+// @Param ids query string false "Comma separated task IDs to fetch in one request"
+// @Param fields query string false "Comma separated fields to return, e.g. title,completed"
+// @Param include query string false "Related documents to embed, only user is supported"
 // @Success 200 {object} fiber.Map
+// @Failure 400 Bad Request
 // @Failure 500 Internal Server Error
-func ListTasks(c *fiber.Ctx) error {
+// @Deprecated
+// @Router /tasks [get]
+func (h *Handlers) ListTasks(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	// Get pagination parameters
-	page, _ := strconv.Atoi(c.Query("page", "1"))
-	limit, _ := strconv.Atoi(c.Query("limit", "10"))
-	skip := (page - 1) * limit
+	req, err := parsePageRequest(c, store.TaskSortFields)
+	if err != nil {
+		log.Error("Invalid pagination parameters: ", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
 
-	// Validate limit
-	if limit > maxPaginationLimit {
-		limit = maxPaginationLimit
+	where, err := parseFilter(c, store.TaskFilterFields)
+	if err != nil {
+		log.Error("Invalid filter: ", err)
+		return filterError(c, err)
 	}
 
-	// Get total count
-	total, err := getTaskCollection().CountDocuments(ctx, bson.M{})
+	// Batch fetch: every requested task fits in a single page
+	ids, err := parseIDs(c)
 	if err != nil {
-		log.Error("Error counting tasks: ", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to count tasks",
-		})
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+	if len(ids) > 0 {
+		where = filter.AllOf(where, store.IDIn(ids))
+		req.Limit = max(req.Limit, len(ids))
+	}
+
+	fields := splitList(c.Query("fields"))
+	if err := validateColumns(fields, taskColumns); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+	includeUser := false
+	for _, include := range splitList(c.Query("include")) {
+		if include != "user" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "unsupported include: " + include})
+		}
+		includeUser = true
+	}
+
+	// Only the selected columns are read, plus the owner of embedded users
+	var stored []string
+	if len(fields) > 0 {
+		stored = storedColumns(fields, taskColumns)
+		if includeUser {
+			stored = append(stored, "userId")
+		}
 	}
 
-	// Find with pagination
-	cursor, err := getTaskCollection().Find(ctx, bson.M{}, options.Find().
-		SetSkip(int64(skip)).
-		SetLimit(int64(limit)))
+	result, err := h.Tasks.ListTasks(ctx, where, req, stored)
 	if err != nil {
 		log.Error("Error fetching tasks: ", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to fetch tasks",
 		})
 	}
-	defer cursor.Close(ctx)
 
-	var tasks []models.Task
-	if err = cursor.All(ctx, &tasks); err != nil {
-		log.Error("Error decoding tasks: ", err)
+	if len(fields) == 0 && !includeUser {
+		log.Info("Tasks fetched successfully with pagination")
+		return c.Status(fiber.StatusOK).JSON(pageResponse(c, "tasks", result, req))
+	}
+
+	if len(fields) == 0 {
+		fields = allTaskColumns
+	}
+	docs, err := columnDocs(result.Items)
+	if err != nil {
+		log.Error("Error selecting task fields: ", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to decode tasks",
+			"error": "Failed to fetch tasks",
 		})
 	}
 
+	rows := selectColumns(docs, fields, taskColumns)
+	if includeUser {
+		if err := h.embedUsers(ctx, docs, rows); err != nil {
+			log.Error("Error fetching task users: ", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to fetch task users",
+			})
+		}
+	}
+
 	log.Info("Tasks fetched successfully with pagination")
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"tasks": tasks,
-		"page":  page,
-		"limit": limit,
-		"total": total,
-	})
+	return c.Status(fiber.StatusOK).JSON(pageResponse(c, "tasks", &store.Page[fiber.Map]{
+		Items: rows,
+		Next:  result.Next,
+		Prev:  result.Prev,
+		Total: result.Total,
+	}, req))
 }
 
 // GetUserTasks handles the fetching of tasks for a specific user
 // @Summary Get tasks for a specific user
-// @Description Fetch tasks from the database for a specific user
+// @Description Fetch a page of tasks for a specific user
 // @Param userId path string true "User ID"
-// @Success 200 {object} []models.Task
-// @Failure 404 {object} fiber.Map
-func GetUserTasks(c *fiber.Ctx) error {
+// @Param limit query int false "Number of tasks per page"
+// @Param cursor query string false "Opaque cursor taken from a next or prev link"
+// @Param sort query string false "Sort field (id, title, completed), prefix with - for descending"
+// @Param count query bool false "Set to false to skip counting the total"
+// @Param filter query string false "Filter expression, e.g. completed:false AND title~release"
+// @Success 200 {object} fiber.Map
+// @Failure 400 Bad Request
+// @Failure 500 Internal Server Error
+// @Router /tasks/user/{userId} [get]
+func (h *Handlers) GetUserTasks(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	userId := c.Params("userId")
-	var tasks []models.Task
 	defer cancel()
 
 	objId, _ := primitive.ObjectIDFromHex(userId)
 
-	taskCollection := getTaskCollection()
-	cursor, err := taskCollection.Find(ctx, bson.M{"userId": objId})
+	req, err := parsePageRequest(c, store.TaskSortFields)
+	if err != nil {
+		log.Error("Invalid pagination parameters: ", err)
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	where, err := parseFilter(c, store.TaskFilterFields)
 	if err != nil {
-		log.Error("Error fetching user: ", err)
-		return c.Status(http.StatusNotFound).JSON(fiber.Map{"message": err.Error()})
+		log.Error("Invalid filter: ", err)
+		return filterError(c, err)
 	}
-	defer cursor.Close(ctx)
 
-	for cursor.Next(ctx) {
-		var task models.Task
-		cursor.Decode(&task)
-		tasks = append(tasks, task)
+	where = filter.AllOf(store.FieldIs(store.TaskFilterFields, "userId", objId), where)
+	result, err := h.Tasks.ListTasks(ctx, where, req, nil)
+	if err != nil {
+		log.Error("Error fetching user tasks: ", err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"message": err.Error()})
 	}
 
 	log.Info("User tasks fetched successfully")
-	return c.Status(http.StatusOK).JSON(tasks)
+	return c.Status(http.StatusOK).JSON(pageResponse(c, "tasks", result, req))
 }
 
-// UpdateTask handles the updating of a task
-// @Summary Update a task by ID
-// @Description Update a task in the database by ID
+// UpdateTask handles the full replacement of a task
+// @Summary Replace a task by ID
+// @Description Replace every field of a task. Use PATCH to change only some fields.
 // @Param taskId path string true "Task ID"
+// @Param If-Match header string false "ETag the task must still have"
 // @Param task body models.Task true "Task object"
 // @Success 200 {object} fiber.Map
 // @Failure 400 Bad Request
 // @Failure 404 Not Found
+// @Failure 412 Precondition Failed
 // @Failure 500 Internal Server Error
-func UpdateTask(c *fiber.Ctx) error {
+// @Deprecated
+// @Router /tasks/{taskId} [put]
+func (h *Handlers) UpdateTask(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	taskId := c.Params("taskId")
 	var task models.Task
@@ -232,25 +277,19 @@ func UpdateTask(c *fiber.Ctx) error {
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
 	}
 
-	update := bson.M{
-		"title":       task.Title,
-		"description": task.Description,
-		"completed":   task.Completed,
+	versions, err := ifMatchVersions(c)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
 	}
 
-	taskCollection := getTaskCollection()
-	result, err := taskCollection.UpdateOne(ctx, bson.M{"_id": objId}, bson.M{"$set": update})
+	updated, err := h.Tasks.ReplaceTask(ctx, objId, task, versions, requestActor(c))
 	if err != nil {
 		log.Error("Error updating task: ", err)
-		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"message": err.Error()})
-	}
-
-	if result.MatchedCount == 0 {
-		log.Error("No task found with the given ID")
-		return c.Status(http.StatusNotFound).JSON(fiber.Map{"message": "Task not found"})
+		return storeError(c, err, "Task not found")
 	}
 
 	log.Info("Task updated successfully")
+	c.Set(fiber.HeaderETag, etag(updated.Version))
 	return c.Status(http.StatusOK).JSON(fiber.Map{"message": "Task updated successfully"})
 }
 
@@ -258,26 +297,28 @@ func UpdateTask(c *fiber.Ctx) error {
 // @Summary Delete a task by ID
 // @Description Delete a task from the database by ID
 // @Param taskId path string true "Task ID"
+// @Param If-Match header string false "ETag the task must still have"
 // @Success 200 {object} fiber.Map
 // @Failure 404 Not Found
+// @Failure 412 Precondition Failed
 // @Failure 500 Internal Server Error
-func DeleteTask(c *fiber.Ctx) error {
+// @Deprecated
+// @Router /tasks/{taskId} [delete]
+func (h *Handlers) DeleteTask(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	taskId := c.Params("taskId")
 	defer cancel()
 
 	objId, _ := primitive.ObjectIDFromHex(taskId)
 
-	taskCollection := getTaskCollection()
-	result, err := taskCollection.DeleteOne(ctx, bson.M{"_id": objId})
+	versions, err := ifMatchVersions(c)
 	if err != nil {
-		log.Error("Error deleting task: ", err)
-		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"message": err.Error()})
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
 	}
 
-	if result.DeletedCount == 0 {
-		log.Error("No task found with the given ID")
-		return c.Status(http.StatusNotFound).JSON(fiber.Map{"message": "Task not found"})
+	if err := h.Tasks.DeleteTask(ctx, objId, versions, requestActor(c)); err != nil {
+		log.Error("Error deleting task: ", err)
+		return storeError(c, err, "Task not found")
 	}
 
 	log.Info("Task deleted successfully")
@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/cmerin0/tasky/internal/filter"
+	"github.com/cmerin0/tasky/internal/models"
+	"github.com/cmerin0/tasky/internal/store"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// ListTasksV2 handles the listing of tasks in the v2 shape
+// @Summary List tasks
+// @Description Fetch a page of tasks. Follow links.next and links.prev to move between pages.
+// @Param limit query int false "Number of tasks per page"
+// @Param cursor query string false "Opaque cursor taken from a next or prev link"
+// @Param sort query string false "Sort field (id, title, status), prefix with - for descending"
+// @Param count query bool false "Set to false to skip counting the total"
+// @Param status query string false "Only list open or done tasks"
+// @Param filter query string false "Filter expression over id, title, description and ownerId"
+// @Success 200 {object} TaskPageV2
+// @Failure 400 Bad Request
+// @Failure 500 Internal Server Error
+// @Router /api/v2/tasks [get]
+func (h *Handlers) ListTasksV2(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	req, err := parsePageRequest(c, taskSortFieldsV2)
+	if err != nil {
+		log.Error("Invalid pagination parameters: ", err)
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	where, err := parseFilter(c, taskFilterFieldsV2)
+	if err != nil {
+		log.Error("Invalid filter: ", err)
+		return filterError(c, err)
+	}
+	switch status := c.Query("status"); status {
+	case "":
+	case models.TaskStatusOpen, models.TaskStatusDone:
+		where = filter.AllOf(where, store.FieldIs(store.TaskFilterFields, "completed", status == models.TaskStatusDone))
+	default:
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": "status must be one of open, done"})
+	}
+
+	result, err := h.Tasks.ListTasks(ctx, where, req, nil)
+	if err != nil {
+		log.Error("Error fetching tasks: ", err)
+		return storeError(c, err, "Task not found")
+	}
+
+	tasks := make([]models.TaskV2, len(result.Items))
+	for i, task := range result.Items {
+		tasks[i] = models.NewTaskV2(task)
+	}
+
+	log.Info("Tasks fetched successfully with pagination")
+	return c.Status(http.StatusOK).JSON(TaskPageV2{Data: tasks, Links: pageLinksV2(c, result), Total: result.Total})
+}
+
+// CreateTaskV2 handles the creation of a task in the v2 shape
+// @Summary Create a task
+// @Description Create a task, the status defaults to open. The response carries its ETag and Location.
+// @Param Idempotency-Key header string false "Key making the request safe to retry, ignored unless the storage is MongoDB"
+// @Param task body models.TaskInputV2 true "Task"
+// @Success 201 {object} models.TaskV2
+// @Failure 400 Bad Request
+// @Failure 409 Conflict
+// @Failure 422 Unprocessable Entity
+// @Failure 500 Internal Server Error
+// @Router /api/v2/tasks [post]
+func (h *Handlers) CreateTaskV2(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	var input models.TaskInputV2
+	defer cancel()
+
+	if err := c.BodyParser(&input); err != nil {
+		log.Error("Error parsing task: ", err)
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+	if err := models.Validate(input); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	task, err := h.Tasks.CreateTask(ctx, input.Task(), requestActor(c))
+	if err != nil {
+		log.Error("Error creating task: ", err)
+		return storeError(c, err, "Task not found")
+	}
+
+	log.Info("Task created successfully")
+	c.Location("/api/v2/tasks/" + task.ID.Hex())
+	c.Set(fiber.HeaderETag, etag(task.Version))
+	return c.Status(http.StatusCreated).JSON(models.NewTaskV2(*task))
+}
+
+// GetTaskV2 handles the retrieval of a task in the v2 shape
+// @Summary Get a task
+// @Description Fetch a task. Send its ETag in If-None-Match to get a 304 while it's unchanged.
+// @Param taskId path string true "Task ID"
+// @Param If-None-Match header string false "ETag of a cached copy"
+// @Success 200 {object} models.TaskV2
+// @Success 304 Not Modified
+// @Failure 400 Bad Request
+// @Failure 404 Not Found
+// @Failure 500 Internal Server Error
+// @Router /api/v2/tasks/{taskId} [get]
+func (h *Handlers) GetTaskV2(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objId, err := pathID(c, "taskId")
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	task, err := h.Tasks.GetTask(ctx, objId)
+	if err != nil {
+		log.Error("Error fetching task: ", err)
+		return storeError(c, err, "Task not found")
+	}
+
+	if notModified(c, task.Version) {
+		return c.SendStatus(http.StatusNotModified)
+	}
+
+	log.Info("Task fetched successfully")
+	return c.Status(http.StatusOK).JSON(models.NewTaskV2(*task))
+}
+
+// UpdateTaskV2 handles the full replacement of a task in the v2 shape
+// @Summary Replace a task
+// @Description Replace every writable field of a task and return it with its new ETag.
+// @Param taskId path string true "Task ID"
+// @Param If-Match header string false "ETag the task must still have"
+// @Param task body models.TaskInputV2 true "Task"
+// @Success 200 {object} models.TaskV2
+// @Failure 400 Bad Request
+// @Failure 404 Not Found
+// @Failure 412 Precondition Failed
+// @Failure 500 Internal Server Error
+// @Router /api/v2/tasks/{taskId} [put]
+func (h *Handlers) UpdateTaskV2(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	var input models.TaskInputV2
+	defer cancel()
+
+	objId, err := pathID(c, "taskId")
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	if err := c.BodyParser(&input); err != nil {
+		log.Error("Error parsing task: ", err)
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+	if err := models.Validate(input); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	versions, err := ifMatchVersions(c)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	task, err := h.Tasks.ReplaceTask(ctx, objId, input.Task(), versions, requestActor(c))
+	if err != nil {
+		log.Error("Error updating task: ", err)
+		return storeError(c, err, "Task not found")
+	}
+
+	log.Info("Task updated successfully")
+	c.Set(fiber.HeaderETag, etag(task.Version))
+	return c.Status(http.StatusOK).JSON(models.NewTaskV2(*task))
+}
+
+// DeleteTaskV2 handles the deletion of a task
+// @Summary Delete a task
+// @Param taskId path string true "Task ID"
+// @Param If-Match header string false "ETag the task must still have"
+// @Success 204 No Content
+// @Failure 400 Bad Request
+// @Failure 404 Not Found
+// @Failure 412 Precondition Failed
+// @Failure 500 Internal Server Error
+// @Router /api/v2/tasks/{taskId} [delete]
+func (h *Handlers) DeleteTaskV2(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objId, err := pathID(c, "taskId")
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	versions, err := ifMatchVersions(c)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	if err := h.Tasks.DeleteTask(ctx, objId, versions, requestActor(c)); err != nil {
+		log.Error("Error deleting task: ", err)
+		return storeError(c, err, "Task not found")
+	}
+
+	log.Info("Task deleted successfully")
+	return c.SendStatus(http.StatusNoContent)
+}
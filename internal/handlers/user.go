@@ -5,51 +5,83 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/cmerin0/tasky/internal/filter"
 	"github.com/cmerin0/tasky/internal/models"
+	"github.com/cmerin0/tasky/internal/store"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/log"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-// GetUsers handles the fetching of all users
+// GetUsers handles the listing of users with cursor based pagination
 // @Summary Get all users
-// @Description Fetch all users from the database
-// @Success 200 {array} models.UserResponse
+// @Description Fetch a page of users. Follow the next and prev links to move between pages.
+// @Param limit query int false "Number of users per page"
+// @Param cursor query string false "Opaque cursor taken from a next or prev link"
+// @Param sort query string false "Sort field (id, name, email), prefix with - for descending"
+// @Param count query bool false "Set to false to skip counting the total"
+// @Param ids query string false "Comma separated user IDs to fetch in one request"
+// @Param fields query string false "Comma separated fields to return, e.g. name,email"
+// @Success 200 {object} fiber.Map
+// @Failure 400 Bad Request
 // @Failure 500 Internal Server Error
+// @Deprecated
 // @Router /users [get]
-func GetUsers(c *fiber.Ctx) error {
+func (h *Handlers) GetUsers(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	var users []models.UserResponse
 	defer cancel()
 
-	cursor, err := getUserCollection().Find(ctx, bson.M{})
+	req, err := parsePageRequest(c, store.UserSortFields)
+	if err != nil {
+		log.Error("Invalid pagination parameters: ", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	// Batch fetch: every requested user fits in a single page
+	var where filter.Expr
+	ids, err := parseIDs(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+	if len(ids) > 0 {
+		where = store.IDIn(ids)
+		req.Limit = max(req.Limit, len(ids))
+	}
+
+	fields := splitList(c.Query("fields"))
+	if err := validateColumns(fields, userColumns); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	result, err := h.Users.ListUsers(ctx, where, req)
 	if err != nil {
 		log.Error("Error fetching users: ", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to fetch users",
 		})
 	}
-	defer cursor.Close(ctx)
 
-	for cursor.Next(ctx) {
-		var user models.UserResponse
-		cursor.Decode(&user)
-		users = append(users, user)
+	if len(fields) == 0 {
+		log.Info("Users fetched successfully with pagination")
+		return c.Status(fiber.StatusOK).JSON(pageResponse(c, "users", result, req))
 	}
 
-	if err := cursor.Err(); err != nil {
-		log.Error("Cursor error: ", err)
+	// Only whitelisted columns are selected, the password is never read
+	docs, err := columnDocs(result.Items)
+	if err != nil {
+		log.Error("Error selecting user fields: ", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Cursor error",
+			"error": "Failed to fetch users",
 		})
 	}
 
-	log.Info("All users fetched successfully")
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"users": users,
-		"count": len(users),
-	})
+	log.Info("Users fetched successfully with pagination")
+	return c.Status(fiber.StatusOK).JSON(pageResponse(c, "users", &store.Page[fiber.Map]{
+		Items: selectColumns(docs, fields, userColumns),
+		Next:  result.Next,
+		Prev:  result.Prev,
+		Total: result.Total,
+	}, req))
 }
 
 // CreateUser handles the creation of a new user
@@ -58,11 +90,12 @@ func GetUsers(c *fiber.Ctx) error {
 // @Accept json
 // @Produce json
 // @Param user body models.User true "User data"
-// @Success 201 {object} models.UserResponse
+// @Success 201 {object} fiber.Map
 // @Failure 400 Bad Request
 // @Failure 500 Internal server error
+// @Deprecated
 // @Router /users [post]
-func CreateUser(c *fiber.Ctx) error {
+func (h *Handlers) CreateUser(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	var user models.User
 	defer cancel()
@@ -72,23 +105,16 @@ func CreateUser(c *fiber.Ctx) error {
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
 	}
 
-	newUser := models.User{
-		Name:     user.Name,
-		Email:    user.Email,
-		Password: user.Password,
-	}
-
-	collection := getUserCollection()
-	result, err := collection.InsertOne(ctx, newUser)
+	newUser, err := h.Users.CreateUser(ctx, user)
 	if err != nil {
-		log.Error("Error inserting user: ", err)
-		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"message": err.Error()})
+		log.Error("Error creating user: ", err)
+		return storeError(c, err, "User not found")
 	}
 
 	log.Info("User created successfully")
 	return c.Status(http.StatusCreated).JSON(fiber.Map{
 		"message": "User created successfully",
-		"userId":  result.InsertedID,
+		"userId":  newUser.ID,
 	})
 }
 
@@ -96,39 +122,47 @@ func CreateUser(c *fiber.Ctx) error {
 // @Summary Get a user by ID
 // @Description Fetch a user from the database by ID
 // @Param userId path string true "User ID"
+// @Param If-None-Match header string false "ETag of a cached copy"
 // @Success 200 {object} models.UserResponse
+// @Success 304 Not Modified
 // @Failure 404 Status Not Found
+// @Deprecated
 // @Router /users/{userId} [get]
-func GetUser(c *fiber.Ctx) error {
+func (h *Handlers) GetUser(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	userId := c.Params("userId")
-	var user models.UserResponse
 	defer cancel()
 
 	objId, _ := primitive.ObjectIDFromHex(userId)
 
-	userCollection := getUserCollection()
-	err := userCollection.FindOne(ctx, bson.M{"_id": objId}).Decode(&user)
+	user, err := h.Users.GetUser(ctx, objId)
 	if err != nil {
 		log.Error("Error fetching user: ", err)
-		return c.Status(http.StatusNotFound).JSON(fiber.Map{"message": err.Error()})
+		return storeError(c, err, "User not found")
+	}
+
+	if notModified(c, user.Version) {
+		return c.SendStatus(http.StatusNotModified)
 	}
 
 	log.Info("User fetched successfully")
 	return c.Status(http.StatusOK).JSON(user)
 }
 
-// UpdateUser handles the updating of a user
-// @Summary Update a user by ID
-// @Description Update a user in the database by ID
+// UpdateUser handles the full replacement of a user
+// @Summary Replace a user by ID
+// @Description Replace every field of a user, including the password. Use PATCH to change only some fields.
 // @Param userId path string true "User ID"
+// @Param If-Match header string false "ETag the user must still have"
 // @Param user body models.User true "User data"
-// @Success 200 {object} models.UserResponse
+// @Success 200 {object} fiber.Map
 // @Failure 400 Bad Request
 // @Failure 404 Not Found
+// @Failure 412 Precondition Failed
 // @Failure 500 Internal Server Error
+// @Deprecated
 // @Router /users/{userId} [put]
-func UpdateUser(c *fiber.Ctx) error {
+func (h *Handlers) UpdateUser(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	userId := c.Params("userId")
 	var user models.User
@@ -141,25 +175,19 @@ func UpdateUser(c *fiber.Ctx) error {
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
 	}
 
-	update := bson.M{
-		"name":     user.Name,
-		"email":    user.Email,
-		"password": user.Password,
+	versions, err := ifMatchVersions(c)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
 	}
 
-	userCollection := getUserCollection()
-	result, err := userCollection.UpdateOne(ctx, bson.M{"_id": objId}, bson.M{"$set": update})
+	updated, err := h.Users.ReplaceUser(ctx, objId, user, versions)
 	if err != nil {
 		log.Error("Error updating user: ", err)
-		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"message": err.Error()})
-	}
-
-	if result.MatchedCount == 0 {
-		log.Error("User not found")
-		return c.Status(http.StatusNotFound).JSON(fiber.Map{"message": "User not found"})
+		return storeError(c, err, "User not found")
 	}
 
 	log.Info("User updated successfully")
+	c.Set(fiber.HeaderETag, etag(updated.Version))
 	return c.Status(http.StatusOK).JSON(fiber.Map{"message": "User updated successfully"})
 }
 
@@ -167,27 +195,28 @@ func UpdateUser(c *fiber.Ctx) error {
 // @Summary Delete a user by ID
 // @Description Delete a user from the database by ID
 // @Param userId path string true "User ID"
+// @Param If-Match header string false "ETag the user must still have"
 // @Success 200 {object} fiber.Map
 // @Failure 404 Not Found
+// @Failure 412 Precondition Failed
 // @Failure 500 Internal Server Error
+// @Deprecated
 // @Router /users/{userId} [delete]
-func DeleteUser(c *fiber.Ctx) error {
+func (h *Handlers) DeleteUser(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	userId := c.Params("userId")
 	defer cancel()
 
 	objId, _ := primitive.ObjectIDFromHex(userId)
 
-	userCollection := getUserCollection()
-	result, err := userCollection.DeleteOne(ctx, bson.M{"_id": objId})
+	versions, err := ifMatchVersions(c)
 	if err != nil {
-		log.Error("Error deleting user: ", err)
-		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"message": err.Error()})
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
 	}
 
-	if result.DeletedCount == 0 {
-		log.Error("User not found")
-		return c.Status(http.StatusNotFound).JSON(fiber.Map{"message": "User not found"})
+	if err := h.Users.DeleteUser(ctx, objId, versions); err != nil {
+		log.Error("Error deleting user: ", err)
+		return storeError(c, err, "User not found")
 	}
 
 	log.Info("User deleted successfully")
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/cmerin0/tasky/internal/models"
+	"github.com/cmerin0/tasky/internal/store"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// ListUsersV2 handles the listing of users in the v2 shape
+// @Summary List users
+// @Description Fetch a page of users. Follow links.next and links.prev to move between pages.
+// @Param limit query int false "Number of users per page"
+// @Param cursor query string false "Opaque cursor taken from a next or prev link"
+// @Param sort query string false "Sort field (id, name, email), prefix with - for descending"
+// @Param count query bool false "Set to false to skip counting the total"
+// @Success 200 {object} UserPageV2
+// @Failure 400 Bad Request
+// @Failure 500 Internal Server Error
+// @Router /api/v2/users [get]
+func (h *Handlers) ListUsersV2(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	req, err := parsePageRequest(c, store.UserSortFields)
+	if err != nil {
+		log.Error("Invalid pagination parameters: ", err)
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	result, err := h.Users.ListUsers(ctx, nil, req)
+	if err != nil {
+		log.Error("Error fetching users: ", err)
+		return storeError(c, err, "User not found")
+	}
+
+	users := make([]models.UserV2, len(result.Items))
+	for i, user := range result.Items {
+		users[i] = models.NewUserV2(user)
+	}
+
+	log.Info("Users fetched successfully with pagination")
+	return c.Status(http.StatusOK).JSON(UserPageV2{Data: users, Links: pageLinksV2(c, result), Total: result.Total})
+}
+
+// CreateUserV2 handles the creation of a user in the v2 shape
+// @Summary Create a user
+// @Description Create a user. The response carries its ETag and Location.
+// @Param Idempotency-Key header string false "Key making the request safe to retry, ignored unless the storage is MongoDB"
+// @Param user body models.UserInputV2 true "User"
+// @Success 201 {object} models.UserV2
+// @Failure 400 Bad Request
+// @Failure 409 Conflict
+// @Failure 422 Unprocessable Entity
+// @Failure 500 Internal Server Error
+// @Router /api/v2/users [post]
+func (h *Handlers) CreateUserV2(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	var input models.UserInputV2
+	defer cancel()
+
+	if err := c.BodyParser(&input); err != nil {
+		log.Error("Error parsing user data: ", err)
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+	if err := models.Validate(input); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	user, err := h.Users.CreateUser(ctx, input.User())
+	if err != nil {
+		log.Error("Error creating user: ", err)
+		return storeError(c, err, "User not found")
+	}
+
+	log.Info("User created successfully")
+	c.Location("/api/v2/users/" + user.ID.Hex())
+	c.Set(fiber.HeaderETag, etag(user.Version))
+	return c.Status(http.StatusCreated).JSON(models.NewUserV2(*user))
+}
+
+// GetUserV2 handles the retrieval of a user in the v2 shape
+// @Summary Get a user
+// @Description Fetch a user. Send its ETag in If-None-Match to get a 304 while it's unchanged.
+// @Param userId path string true "User ID"
+// @Param If-None-Match header string false "ETag of a cached copy"
+// @Success 200 {object} models.UserV2
+// @Success 304 Not Modified
+// @Failure 400 Bad Request
+// @Failure 404 Not Found
+// @Failure 500 Internal Server Error
+// @Router /api/v2/users/{userId} [get]
+func (h *Handlers) GetUserV2(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objId, err := pathID(c, "userId")
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	user, err := h.Users.GetUser(ctx, objId)
+	if err != nil {
+		log.Error("Error fetching user: ", err)
+		return storeError(c, err, "User not found")
+	}
+
+	if notModified(c, user.Version) {
+		return c.SendStatus(http.StatusNotModified)
+	}
+
+	log.Info("User fetched successfully")
+	return c.Status(http.StatusOK).JSON(models.NewUserV2(*user))
+}
+
+// UpdateUserV2 handles the full replacement of a user in the v2 shape
+// @Summary Replace a user
+// @Description Replace every writable field of a user and return it with its new ETag.
+// @Param userId path string true "User ID"
+// @Param If-Match header string false "ETag the user must still have"
+// @Param user body models.UserInputV2 true "User"
+// @Success 200 {object} models.UserV2
+// @Failure 400 Bad Request
+// @Failure 404 Not Found
+// @Failure 412 Precondition Failed
+// @Failure 500 Internal Server Error
+// @Router /api/v2/users/{userId} [put]
+func (h *Handlers) UpdateUserV2(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	var input models.UserInputV2
+	defer cancel()
+
+	objId, err := pathID(c, "userId")
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	if err := c.BodyParser(&input); err != nil {
+		log.Error("Error parsing user data: ", err)
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+	if err := models.Validate(input); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	versions, err := ifMatchVersions(c)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	user, err := h.Users.ReplaceUser(ctx, objId, input.User(), versions)
+	if err != nil {
+		log.Error("Error updating user: ", err)
+		return storeError(c, err, "User not found")
+	}
+
+	log.Info("User updated successfully")
+	c.Set(fiber.HeaderETag, etag(user.Version))
+	return c.Status(http.StatusOK).JSON(models.NewUserV2(*user))
+}
+
+// DeleteUserV2 handles the deletion of a user
+// @Summary Delete a user
+// @Param userId path string true "User ID"
+// @Param If-Match header string false "ETag the user must still have"
+// @Success 204 No Content
+// @Failure 400 Bad Request
+// @Failure 404 Not Found
+// @Failure 412 Precondition Failed
+// @Failure 500 Internal Server Error
+// @Router /api/v2/users/{userId} [delete]
+func (h *Handlers) DeleteUserV2(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objId, err := pathID(c, "userId")
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	versions, err := ifMatchVersions(c)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	if err := h.Users.DeleteUser(ctx, objId, versions); err != nil {
+		log.Error("Error deleting user: ", err)
+		return storeError(c, err, "User not found")
+	}
+
+	log.Info("User deleted successfully")
+	return c.SendStatus(http.StatusNoContent)
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/cmerin0/tasky/internal/filter"
+	"github.com/cmerin0/tasky/internal/models"
+	"github.com/cmerin0/tasky/internal/store"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Sortable and filterable task fields of the v2 API, keyed by their v2 names
+var (
+	taskSortFieldsV2   = map[string]string{"id": "_id", "title": "title", "status": "completed"}
+	taskFilterFieldsV2 = filter.Fields{
+		"id":          store.TaskFilterFields["id"],
+		"title":       store.TaskFilterFields["title"],
+		"description": store.TaskFilterFields["description"],
+		"ownerId":     store.TaskFilterFields["userId"],
+		"dueAt":       store.TaskFilterFields["dueAt"],
+	}
+)
+
+// PageLinksV2 are the links to the pages around a v2 page, null at either end
+type PageLinksV2 struct {
+	Next *string `json:"next"`
+	Prev *string `json:"prev"`
+}
+
+// TaskPageV2 is a page of v2 tasks
+type TaskPageV2 struct {
+	Data  []models.TaskV2 `json:"data"`
+	Links PageLinksV2     `json:"links"`
+	Total *int64          `json:"total,omitempty"`
+}
+
+// UserPageV2 is a page of v2 users
+type UserPageV2 struct {
+	Data  []models.UserV2 `json:"data"`
+	Links PageLinksV2     `json:"links"`
+	Total *int64          `json:"total,omitempty"`
+}
+
+// pageLinksV2 returns the links to the pages around p
+func pageLinksV2[T any](c *fiber.Ctx, p *store.Page[T]) PageLinksV2 {
+	var links PageLinksV2
+	if next, ok := pageLink(c, p.Next).(string); ok {
+		links.Next = &next
+	}
+	if prev, ok := pageLink(c, p.Prev).(string); ok {
+		links.Prev = &prev
+	}
+	return links
+}
+
+// pathID parses an ID path parameter, v2 rejects malformed IDs with a 400
+func pathID(c *fiber.Ctx, name string) (primitive.ObjectID, error) {
+	id, err := primitive.ObjectIDFromHex(c.Params(name))
+	if err != nil {
+		return id, errors.New("invalid " + name + ": " + c.Params(name))
+	}
+	return id, nil
+}
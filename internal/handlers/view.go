@@ -0,0 +1,346 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/cmerin0/tasky/internal/filter"
+	"github.com/cmerin0/tasky/internal/models"
+	"github.com/cmerin0/tasky/internal/store"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// validateView checks a view before it is stored
+func validateView(view *models.View) error {
+	if view.Name == "" {
+		return errors.New("name is required")
+	}
+
+	switch view.Visibility {
+	case "":
+		view.Visibility = models.ViewPrivate
+	case models.ViewPrivate:
+	case models.ViewProject, models.ViewWorkspace:
+		if view.SharedWith == "" {
+			return errors.New("sharedWith is required for shared views")
+		}
+	default:
+		return errors.New("visibility must be private, project or workspace")
+	}
+	if view.Visibility == models.ViewPrivate {
+		view.SharedWith = ""
+	}
+
+	if _, err := filter.Parse(view.Filter, store.TaskFilterFields); err != nil {
+		return errors.New("invalid filter: " + err.Error())
+	}
+	if view.Sort != "" {
+		var req store.PageRequest
+		if err := req.ApplySort(view.Sort, store.TaskSortFields); err != nil {
+			return err
+		}
+	}
+	return validateColumns(view.Columns, taskColumns)
+}
+
+// canReadView reports whether a user may see and run a view.
+// Shared views are readable by anyone who names their project or workspace.
+func canReadView(view *models.View, userId primitive.ObjectID, project, workspace string) bool {
+	switch {
+	case view.OwnerID == userId:
+		return true
+	case view.Visibility == models.ViewProject:
+		return project != "" && view.SharedWith == project
+	case view.Visibility == models.ViewWorkspace:
+		return workspace != "" && view.SharedWith == workspace
+	}
+	return false
+}
+
+// actorRequired responds to requests that need an identified user
+func actorRequired(c *fiber.Ctx) error {
+	return c.Status(http.StatusUnauthorized).JSON(fiber.Map{
+		"message": actorHeader + " header with a valid user ID is required",
+	})
+}
+
+// findView loads a view and checks the caller can read it.
+// It writes the error response itself and returns nil when it fails.
+func (h *Handlers) findView(ctx context.Context, c *fiber.Ctx, userId primitive.ObjectID) (*models.View, error) {
+	objId, _ := primitive.ObjectIDFromHex(c.Params("viewId"))
+
+	view, err := h.Views.GetView(ctx, objId)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		log.Error("Error fetching view: ", err)
+		return nil, c.Status(http.StatusInternalServerError).JSON(fiber.Map{"message": err.Error()})
+	}
+	// A view the caller can't read is reported as missing so its id doesn't leak
+	if err != nil || !canReadView(view, userId, c.Query("project"), c.Query("workspace")) {
+		return nil, c.Status(http.StatusNotFound).JSON(fiber.Map{"message": "View not found"})
+	}
+	return view, nil
+}
+
+// GetViews handles the listing of saved views
+// @Summary List saved views
+// @Description List the caller's views plus the views shared with a project or workspace
+// @Param project query string false "Include views shared with this project"
+// @Param workspace query string false "Include views shared with this workspace"
+// @Success 200 {object} fiber.Map
+// @Failure 401 Unauthorized
+// @Failure 500 Internal Server Error
+// @Router /views [get]
+func (h *Handlers) GetViews(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	userId, ok := requestUserID(c)
+	if !ok {
+		return actorRequired(c)
+	}
+
+	views, err := h.Views.ListViews(ctx, userId, c.Query("project"), c.Query("workspace"))
+	if err != nil {
+		log.Error("Error fetching views: ", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch views",
+		})
+	}
+
+	log.Info("Views fetched successfully")
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"views": views,
+		"count": len(views),
+	})
+}
+
+// CreateView handles the creation of a saved view
+// @Summary Create a saved view
+// @Description Save a named task query owned by the caller
+// @Param view body models.View true "View object"
+// @Success 201 {object} fiber.Map
+// @Failure 400 Bad Request
+// @Failure 401 Unauthorized
+// @Failure 500 Internal Server Error
+// @Router /views [post]
+func (h *Handlers) CreateView(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	var view models.View
+	defer cancel()
+
+	userId, ok := requestUserID(c)
+	if !ok {
+		return actorRequired(c)
+	}
+
+	if err := c.BodyParser(&view); err != nil {
+		log.Error("Error parsing view: ", err)
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	view.OwnerID = userId
+	if err := validateView(&view); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	created, err := h.Views.CreateView(ctx, view)
+	if err != nil {
+		log.Error("Error inserting view: ", err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	log.Info("View created successfully")
+	return c.Status(http.StatusCreated).JSON(fiber.Map{
+		"message": "View created successfully",
+		"viewId":  created.ID,
+	})
+}
+
+// GetView handles the fetching of a saved view
+// @Summary Get a saved view by ID
+// @Description Fetch a view owned by the caller, or shared with the project or workspace they name
+// @Param viewId path string true "View ID"
+// @Param project query string false "Project the view is shared with"
+// @Param workspace query string false "Workspace the view is shared with"
+// @Success 200 {object} models.View
+// @Failure 401 Unauthorized
+// @Failure 404 Not Found
+// @Failure 500 Internal Server Error
+// @Router /views/{viewId} [get]
+func (h *Handlers) GetView(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	userId, ok := requestUserID(c)
+	if !ok {
+		return actorRequired(c)
+	}
+
+	view, err := h.findView(ctx, c, userId)
+	if view == nil {
+		return err
+	}
+
+	log.Info("View fetched successfully")
+	return c.Status(http.StatusOK).JSON(view)
+}
+
+// UpdateView handles the updating of a saved view
+// @Summary Update a saved view by ID
+// @Description Replace a view owned by the caller
+// @Param viewId path string true "View ID"
+// @Param view body models.View true "View object"
+// @Success 200 {object} fiber.Map
+// @Failure 400 Bad Request
+// @Failure 401 Unauthorized
+// @Failure 404 Not Found
+// @Failure 500 Internal Server Error
+// @Router /views/{viewId} [put]
+func (h *Handlers) UpdateView(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	viewId := c.Params("viewId")
+	var view models.View
+	defer cancel()
+
+	userId, ok := requestUserID(c)
+	if !ok {
+		return actorRequired(c)
+	}
+
+	objId, _ := primitive.ObjectIDFromHex(viewId)
+
+	if err := c.BodyParser(&view); err != nil {
+		log.Error("Error parsing view: ", err)
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+	if err := validateView(&view); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	// Only the owner may change a view
+	view.ID, view.OwnerID = objId, userId
+	if err := h.Views.UpdateView(ctx, view); err != nil {
+		log.Error("Error updating view: ", err)
+		return storeError(c, err, "View not found")
+	}
+
+	log.Info("View updated successfully")
+	return c.Status(http.StatusOK).JSON(fiber.Map{"message": "View updated successfully"})
+}
+
+// DeleteView handles the deletion of a saved view
+// @Summary Delete a saved view by ID
+// @Description Delete a view owned by the caller
+// @Param viewId path string true "View ID"
+// @Success 200 {object} fiber.Map
+// @Failure 401 Unauthorized
+// @Failure 404 Not Found
+// @Failure 500 Internal Server Error
+// @Router /views/{viewId} [delete]
+func (h *Handlers) DeleteView(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	viewId := c.Params("viewId")
+	defer cancel()
+
+	userId, ok := requestUserID(c)
+	if !ok {
+		return actorRequired(c)
+	}
+
+	objId, _ := primitive.ObjectIDFromHex(viewId)
+
+	if err := h.Views.DeleteView(ctx, objId, userId); err != nil {
+		log.Error("Error deleting view: ", err)
+		return storeError(c, err, "View not found")
+	}
+
+	log.Info("View deleted successfully")
+	return c.Status(http.StatusOK).JSON(fiber.Map{"message": "View deleted successfully"})
+}
+
+// GetViewTasks handles running a saved view
+// @Summary Run a saved view
+// @Description Get a page of the tasks matching a view, paginated like the task listing
+// @Param viewId path string true "View ID"
+// @Param project query string false "Project the view is shared with"
+// @Param workspace query string false "Workspace the view is shared with"
+// @Param limit query int false "Number of tasks per page"
+// @Param cursor query string false "Opaque cursor taken from a next or prev link"
+// @Param sort query string false "Overrides the sort of the view"
+// @Param count query bool false "Set to false to skip counting the total"
+// @Param filter query string false "Narrows the filter of the view"
+// @Success 200 {object} fiber.Map
+// @Failure 400 Bad Request
+// @Failure 401 Unauthorized
+// @Failure 404 Not Found
+// @Failure 500 Internal Server Error
+// @Router /views/{viewId}/tasks [get]
+func (h *Handlers) GetViewTasks(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	userId, ok := requestUserID(c)
+	if !ok {
+		return actorRequired(c)
+	}
+
+	view, err := h.findView(ctx, c, userId)
+	if view == nil {
+		return err
+	}
+
+	req, err := parsePageRequest(c, store.TaskSortFields)
+	if err != nil {
+		log.Error("Invalid pagination parameters: ", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+	if req.Cursor == nil && c.Query("sort") == "" && view.Sort != "" {
+		_ = req.ApplySort(view.Sort, store.TaskSortFields)
+	}
+
+	viewExpr, err := filter.Parse(view.Filter, store.TaskFilterFields)
+	if err != nil {
+		log.Error("Invalid view filter: ", err)
+		return filterError(c, err)
+	}
+	where, err := parseFilter(c, store.TaskFilterFields)
+	if err != nil {
+		log.Error("Invalid filter: ", err)
+		return filterError(c, err)
+	}
+
+	result, err := h.Tasks.ListTasks(ctx, filter.AllOf(viewExpr, where), req, nil)
+	if err != nil {
+		log.Error("Error running view: ", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch tasks",
+		})
+	}
+
+	if len(view.Columns) == 0 {
+		log.Info("View tasks fetched successfully")
+		return c.Status(fiber.StatusOK).JSON(pageResponse(c, "tasks", result, req))
+	}
+
+	docs, err := columnDocs(result.Items)
+	if err != nil {
+		log.Error("Error selecting view columns: ", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch tasks",
+		})
+	}
+	rows := &store.Page[fiber.Map]{
+		Items: selectColumns(docs, view.Columns, taskColumns),
+		Next:  result.Next,
+		Prev:  result.Prev,
+		Total: result.Total,
+	}
+
+	log.Info("View tasks fetched successfully")
+	return c.Status(fiber.StatusOK).JSON(pageResponse(c, "tasks", rows, req))
+}
@@ -0,0 +1,82 @@
+package handlers_test
+
+import (
+	"testing"
+
+	"github.com/cmerin0/tasky/internal/handlers"
+	"github.com/cmerin0/tasky/internal/store/memory"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func newViewApp() *fiber.App {
+	repo := memory.New()
+	h := handlers.New(repo, repo)
+	h.Views = repo
+
+	app := fiber.New()
+	app.Get("/views", h.GetViews)
+	app.Post("/views", h.CreateView)
+	app.Get("/views/:viewId", h.GetView)
+	app.Put("/views/:viewId", h.UpdateView)
+	app.Delete("/views/:viewId", h.DeleteView)
+	return app
+}
+
+func TestViews(t *testing.T) {
+	app := newViewApp()
+	owner, other := primitive.NewObjectID().Hex(), primitive.NewObjectID().Hex()
+
+	status, body := send(t, app, "POST", "/views", "", fiber.Map{"name": "Open"})
+	expectStatus(t, "create without actor", status, fiber.StatusUnauthorized, body)
+	status, body = send(t, app, "POST", "/views", owner, fiber.Map{"name": "Open", "filter": "completed:"})
+	expectStatus(t, "create with a bad filter", status, fiber.StatusBadRequest, body)
+
+	status, body = send(t, app, "POST", "/views", owner, fiber.Map{
+		"name": "Open", "filter": "completed:false", "visibility": "project", "sharedWith": "apollo",
+	})
+	expectStatus(t, "create", status, fiber.StatusCreated, body)
+	path := "/views/" + body["viewId"].(string)
+
+	status, body = send(t, app, "GET", path, owner, nil)
+	expectStatus(t, "get as owner", status, fiber.StatusOK, body)
+	if body["name"] != "Open" || body["ownerId"] != owner {
+		t.Fatalf("get as owner: %v", body)
+	}
+
+	// Others only see the view through the project it is shared with
+	status, body = send(t, app, "GET", path, other, nil)
+	expectStatus(t, "get as someone else", status, fiber.StatusNotFound, body)
+	status, body = send(t, app, "GET", path+"?project=apollo", other, nil)
+	expectStatus(t, "get through the project", status, fiber.StatusOK, body)
+	status, body = send(t, app, "GET", "/views?project=apollo", other, nil)
+	expectStatus(t, "list through the project", status, fiber.StatusOK, body)
+	if body["count"] != 1.0 {
+		t.Fatalf("list through the project: %v", body)
+	}
+	status, body = send(t, app, "GET", "/views", other, nil)
+	expectStatus(t, "list as someone else", status, fiber.StatusOK, body)
+	if body["count"] != 0.0 {
+		t.Fatalf("list as someone else: %v", body)
+	}
+
+	// Only the owner changes it
+	status, body = send(t, app, "PUT", path, other, fiber.Map{"name": "Mine"})
+	expectStatus(t, "update as someone else", status, fiber.StatusNotFound, body)
+	status, body = send(t, app, "DELETE", path, other, nil)
+	expectStatus(t, "delete as someone else", status, fiber.StatusNotFound, body)
+
+	status, body = send(t, app, "PUT", path, owner, fiber.Map{"name": "Renamed"})
+	expectStatus(t, "update", status, fiber.StatusOK, body)
+	status, body = send(t, app, "GET", path, owner, nil)
+	expectStatus(t, "get after update", status, fiber.StatusOK, body)
+	if body["name"] != "Renamed" || body["visibility"] != "private" {
+		t.Fatalf("get after update: %v", body)
+	}
+
+	status, body = send(t, app, "DELETE", path, owner, nil)
+	expectStatus(t, "delete", status, fiber.StatusOK, body)
+	status, body = send(t, app, "GET", path, owner, nil)
+	expectStatus(t, "get after delete", status, fiber.StatusNotFound, body)
+}
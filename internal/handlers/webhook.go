@@ -0,0 +1,373 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cmerin0/tasky/internal/models"
+	"github.com/cmerin0/tasky/internal/store"
+	"github.com/cmerin0/tasky/internal/webhooks"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// Sortable delivery fields, keyed by the name clients use
+var deliverySortFields = map[string]string{"id": "_id", "createdAt": "createdAt", "status": "status"}
+
+// parseWebhook reads and validates the webhook in the request body,
+// refusing URLs that don't point to a public address
+func parseWebhook(ctx context.Context, c *fiber.Ctx) (*models.WebhookInput, error) {
+	var input models.WebhookInput
+	if err := c.BodyParser(&input); err != nil {
+		return nil, err
+	}
+	if err := models.Validate(input); err != nil {
+		return nil, err
+	}
+	if err := webhooks.CheckDestination(ctx, input.URL); err != nil {
+		return nil, err
+	}
+	return &input, nil
+}
+
+// GetWebhooks handles the listing of webhooks
+// @Summary List webhooks
+// @Description List the webhook subscriptions of the caller. Secrets are never returned.
+// @Success 200 {object} fiber.Map
+// @Failure 401 Unauthorized
+// @Failure 500 Internal Server Error
+// @Router /webhooks [get]
+func (h *Handlers) GetWebhooks(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	userId, ok := requestUserID(c)
+	if !ok {
+		return actorRequired(c)
+	}
+
+	hooks, err := h.Webhooks.ListWebhooks(ctx, userId)
+	if err != nil {
+		log.Error("Error fetching webhooks: ", err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	log.Info("Webhooks fetched successfully")
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"webhooks": hooks,
+		"count":    len(hooks),
+	})
+}
+
+// CreateWebhook handles the creation of a webhook
+// @Summary Create a webhook
+// @Description Subscribe a URL to the events of the caller's tasks and account. Deliveries are signed with the secret, which is generated when not given and only returned in this response. The URL must point to a public address: loopback, private and link-local ones are refused.
+// @Param webhook body models.WebhookInput true "Webhook object"
+// @Success 201 {object} models.Webhook
+// @Failure 400 Bad Request
+// @Failure 401 Unauthorized
+// @Failure 500 Internal Server Error
+// @Router /webhooks [post]
+func (h *Handlers) CreateWebhook(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	userId, ok := requestUserID(c)
+	if !ok {
+		return actorRequired(c)
+	}
+
+	input, err := parseWebhook(ctx, c)
+	if err != nil {
+		log.Error("Invalid webhook: ", err)
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	webhook := models.Webhook{
+		OwnerID:   userId,
+		URL:       input.URL,
+		Events:    input.Events,
+		Secret:    input.Secret,
+		Active:    input.Active == nil || *input.Active,
+		CreatedAt: time.Now().UTC(),
+	}
+	if webhook.Secret == "" {
+		webhook.Secret = webhooks.NewSecret()
+	}
+
+	created, err := h.Webhooks.CreateWebhook(ctx, webhook)
+	if err != nil {
+		log.Error("Error inserting webhook: ", err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	log.Info("Webhook created successfully")
+	c.Location(c.BaseURL() + c.Path() + "/" + created.ID.Hex())
+	return c.Status(http.StatusCreated).JSON(created)
+}
+
+// GetWebhook handles the fetching of a webhook
+// @Summary Get a webhook by ID
+// @Description Fetch a webhook subscription of the caller, without its secret
+// @Param webhookId path string true "Webhook ID"
+// @Success 200 {object} models.Webhook
+// @Failure 400 Bad Request
+// @Failure 401 Unauthorized
+// @Failure 404 Not Found
+// @Failure 500 Internal Server Error
+// @Router /webhooks/{webhookId} [get]
+func (h *Handlers) GetWebhook(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	userId, ok := requestUserID(c)
+	if !ok {
+		return actorRequired(c)
+	}
+
+	id, err := pathID(c, "webhookId")
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	webhook, err := h.Webhooks.GetWebhook(ctx, id, userId)
+	if err != nil {
+		log.Error("Error fetching webhook: ", err)
+		return storeError(c, err, "Webhook not found")
+	}
+
+	log.Info("Webhook fetched successfully")
+	return c.Status(http.StatusOK).JSON(webhook)
+}
+
+// UpdateWebhook handles the updating of a webhook
+// @Summary Update a webhook by ID
+// @Description Replace the URL, events and active flag of a webhook of the caller. The secret is rotated only when a new one is given. The URL must point to a public address.
+// @Param webhookId path string true "Webhook ID"
+// @Param webhook body models.WebhookInput true "Webhook object"
+// @Success 200 {object} models.Webhook
+// @Failure 400 Bad Request
+// @Failure 401 Unauthorized
+// @Failure 404 Not Found
+// @Failure 500 Internal Server Error
+// @Router /webhooks/{webhookId} [put]
+func (h *Handlers) UpdateWebhook(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	userId, ok := requestUserID(c)
+	if !ok {
+		return actorRequired(c)
+	}
+
+	id, err := pathID(c, "webhookId")
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	input, err := parseWebhook(ctx, c)
+	if err != nil {
+		log.Error("Invalid webhook: ", err)
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	webhook, err := h.Webhooks.UpdateWebhook(ctx, id, models.Webhook{
+		OwnerID: userId,
+		URL:     input.URL,
+		Events:  input.Events,
+		Secret:  input.Secret,
+		Active:  input.Active == nil || *input.Active,
+	})
+	if err != nil {
+		log.Error("Error updating webhook: ", err)
+		return storeError(c, err, "Webhook not found")
+	}
+
+	log.Info("Webhook updated successfully")
+	return c.Status(http.StatusOK).JSON(webhook)
+}
+
+// DeleteWebhook handles the deletion of a webhook
+// @Summary Delete a webhook by ID
+// @Description Delete a webhook subscription of the caller together with its delivery log
+// @Param webhookId path string true "Webhook ID"
+// @Success 200 {object} fiber.Map
+// @Failure 400 Bad Request
+// @Failure 401 Unauthorized
+// @Failure 404 Not Found
+// @Failure 500 Internal Server Error
+// @Router /webhooks/{webhookId} [delete]
+func (h *Handlers) DeleteWebhook(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	userId, ok := requestUserID(c)
+	if !ok {
+		return actorRequired(c)
+	}
+
+	id, err := pathID(c, "webhookId")
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	if err := h.Webhooks.DeleteWebhook(ctx, id, userId); err != nil {
+		log.Error("Error deleting webhook: ", err)
+		return storeError(c, err, "Webhook not found")
+	}
+
+	log.Info("Webhook deleted successfully")
+	return c.Status(http.StatusOK).JSON(fiber.Map{"message": "Webhook deleted successfully"})
+}
+
+// GetWebhookDeliveries handles the listing of a webhook's deliveries
+// @Summary List the deliveries of a webhook
+// @Description Get a page of the delivery log of a webhook of the caller, newest first by default. Each delivery lists its attempts.
+// @Param webhookId path string true "Webhook ID"
+// @Param status query string false "Only deliveries with this status: pending, succeeded or failed"
+// @Param limit query int false "Number of deliveries per page"
+// @Param cursor query string false "Opaque cursor taken from a next or prev link"
+// @Param sort query string false "Sort field, prefixed with - for descending: id, createdAt or status"
+// @Param count query bool false "Set to false to skip counting the total"
+// @Success 200 {object} fiber.Map
+// @Failure 400 Bad Request
+// @Failure 401 Unauthorized
+// @Failure 404 Not Found
+// @Failure 500 Internal Server Error
+// @Router /webhooks/{webhookId}/deliveries [get]
+func (h *Handlers) GetWebhookDeliveries(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	webhook, err := h.findWebhook(ctx, c)
+	if webhook == nil {
+		return err
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit", strconv.Itoa(store.DefaultPageLimit)))
+	req, err := store.NewPageRequest(limit, c.Query("cursor"), defaultSort(c, "-id"), c.Query("count") != "false", deliverySortFields)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	status := c.Query("status")
+	switch status {
+	case "", models.DeliveryPending, models.DeliverySucceeded, models.DeliveryFailed:
+	default:
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": "status must be pending, succeeded or failed"})
+	}
+
+	page, err := h.Webhooks.ListDeliveries(ctx, webhook.ID, status, req)
+	if err != nil {
+		log.Error("Error fetching webhook deliveries: ", err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	log.Info("Webhook deliveries fetched successfully")
+	return c.Status(http.StatusOK).JSON(pageResponse(c, "deliveries", page, req))
+}
+
+// findWebhook loads the webhook named in the path if the caller owns it.
+// It writes the error response itself and returns nil when it fails.
+func (h *Handlers) findWebhook(ctx context.Context, c *fiber.Ctx) (*models.Webhook, error) {
+	userId, ok := requestUserID(c)
+	if !ok {
+		return nil, actorRequired(c)
+	}
+	id, err := pathID(c, "webhookId")
+	if err != nil {
+		return nil, c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	webhook, err := h.Webhooks.GetWebhook(ctx, id, userId)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, c.Status(http.StatusNotFound).JSON(fiber.Map{"message": "Webhook not found"})
+	}
+	if err != nil {
+		log.Error("Error fetching webhook: ", err)
+		return nil, c.Status(http.StatusInternalServerError).JSON(fiber.Map{"message": err.Error()})
+	}
+	return webhook, nil
+}
+
+// findDelivery loads a delivery of the webhook named in the path, which
+// the caller must own. It writes the error response itself and returns
+// nil when it fails.
+func (h *Handlers) findDelivery(ctx context.Context, c *fiber.Ctx) (*models.WebhookDelivery, error) {
+	webhook, err := h.findWebhook(ctx, c)
+	if webhook == nil {
+		return nil, err
+	}
+	deliveryId, err := pathID(c, "deliveryId")
+	if err != nil {
+		return nil, c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	delivery, err := h.Webhooks.GetDelivery(ctx, webhook.ID, deliveryId)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, c.Status(http.StatusNotFound).JSON(fiber.Map{"message": "Delivery not found"})
+	}
+	if err != nil {
+		log.Error("Error fetching webhook delivery: ", err)
+		return nil, c.Status(http.StatusInternalServerError).JSON(fiber.Map{"message": err.Error()})
+	}
+	return delivery, nil
+}
+
+// GetWebhookDelivery handles the fetching of a webhook delivery
+// @Summary Get a webhook delivery by ID
+// @Description Fetch a delivery of a webhook of the caller with its payload and every attempt made
+// @Param webhookId path string true "Webhook ID"
+// @Param deliveryId path string true "Delivery ID"
+// @Success 200 {object} models.WebhookDelivery
+// @Failure 400 Bad Request
+// @Failure 401 Unauthorized
+// @Failure 404 Not Found
+// @Failure 500 Internal Server Error
+// @Router /webhooks/{webhookId}/deliveries/{deliveryId} [get]
+func (h *Handlers) GetWebhookDelivery(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	delivery, err := h.findDelivery(ctx, c)
+	if delivery == nil {
+		return err
+	}
+
+	log.Info("Webhook delivery fetched successfully")
+	return c.Status(http.StatusOK).JSON(delivery)
+}
+
+// RedeliverWebhook handles the redelivery of a webhook delivery
+// @Summary Redeliver a webhook delivery
+// @Description Queue a new delivery of the same event and payload to a webhook of the caller, whatever the outcome of the original. The new delivery gets a fresh set of attempts.
+// @Param webhookId path string true "Webhook ID"
+// @Param deliveryId path string true "Delivery ID"
+// @Success 202 {object} models.WebhookDelivery
+// @Failure 400 Bad Request
+// @Failure 401 Unauthorized
+// @Failure 404 Not Found
+// @Failure 500 Internal Server Error
+// @Router /webhooks/{webhookId}/deliveries/{deliveryId}/redeliver [post]
+func (h *Handlers) RedeliverWebhook(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	original, err := h.findDelivery(ctx, c)
+	if original == nil {
+		return err
+	}
+
+	delivery, err := h.Webhooks.Redeliver(ctx, original)
+	if err != nil {
+		log.Error("Error queuing redelivery: ", err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	log.Info("Webhook redelivery queued")
+	return c.Status(http.StatusAccepted).JSON(delivery)
+}
@@ -0,0 +1,139 @@
+package handlers_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cmerin0/tasky/internal/handlers"
+	"github.com/cmerin0/tasky/internal/models"
+	"github.com/cmerin0/tasky/internal/store"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeWebhooks holds webhooks and their deliveries in memory
+type fakeWebhooks struct {
+	store.WebhookRepository
+	hooks      []models.Webhook
+	deliveries []models.WebhookDelivery
+}
+
+func (f *fakeWebhooks) ListWebhooks(ctx context.Context, owner primitive.ObjectID) ([]models.Webhook, error) {
+	hooks := []models.Webhook{}
+	for _, hook := range f.hooks {
+		if hook.OwnerID == owner {
+			hooks = append(hooks, hook)
+		}
+	}
+	return hooks, nil
+}
+
+func (f *fakeWebhooks) GetWebhook(ctx context.Context, id, owner primitive.ObjectID) (*models.Webhook, error) {
+	for _, hook := range f.hooks {
+		if hook.ID == id && hook.OwnerID == owner {
+			return &hook, nil
+		}
+	}
+	return nil, store.ErrNotFound
+}
+
+func (f *fakeWebhooks) DeleteWebhook(ctx context.Context, id, owner primitive.ObjectID) error {
+	for i, hook := range f.hooks {
+		if hook.ID == id && hook.OwnerID == owner {
+			f.hooks = append(f.hooks[:i], f.hooks[i+1:]...)
+			return nil
+		}
+	}
+	return store.ErrNotFound
+}
+
+func (f *fakeWebhooks) GetDelivery(ctx context.Context, webhookId, id primitive.ObjectID) (*models.WebhookDelivery, error) {
+	for _, delivery := range f.deliveries {
+		if delivery.ID == id && delivery.WebhookID == webhookId {
+			return &delivery, nil
+		}
+	}
+	return nil, store.ErrNotFound
+}
+
+func (f *fakeWebhooks) Redeliver(ctx context.Context, original *models.WebhookDelivery) (*models.WebhookDelivery, error) {
+	delivery := *original
+	delivery.ID = primitive.NewObjectID()
+	delivery.Status = models.DeliveryPending
+	delivery.RedeliveryOf = &original.ID
+	f.deliveries = append(f.deliveries, delivery)
+	return &delivery, nil
+}
+
+func TestWebhooks(t *testing.T) {
+	owner, other := primitive.NewObjectID().Hex(), primitive.NewObjectID().Hex()
+	ownerId, _ := primitive.ObjectIDFromHex(owner)
+	hook := models.Webhook{ID: primitive.NewObjectID(), OwnerID: ownerId, URL: "https://example.com/hook", Events: []string{models.EventTaskCreated}, Active: true}
+	failed := models.WebhookDelivery{ID: primitive.NewObjectID(), WebhookID: hook.ID, EventType: models.EventTaskCreated, Status: models.DeliveryFailed}
+	repo := &fakeWebhooks{hooks: []models.Webhook{hook}, deliveries: []models.WebhookDelivery{failed}}
+	h := handlers.New(nil, nil)
+	h.Webhooks = repo
+
+	app := fiber.New()
+	app.Get("/webhooks", h.GetWebhooks)
+	app.Get("/webhooks/:webhookId", h.GetWebhook)
+	app.Delete("/webhooks/:webhookId", h.DeleteWebhook)
+	app.Get("/webhooks/:webhookId/deliveries", h.GetWebhookDeliveries)
+	app.Get("/webhooks/:webhookId/deliveries/:deliveryId", h.GetWebhookDelivery)
+	app.Post("/webhooks/:webhookId/deliveries/:deliveryId/redeliver", h.RedeliverWebhook)
+	path := "/webhooks/" + hook.ID.Hex()
+	missing := "/webhooks/" + primitive.NewObjectID().Hex()
+
+	status, body := send(t, app, "GET", "/webhooks", "", nil)
+	expectStatus(t, "list without actor", status, fiber.StatusUnauthorized, body)
+	status, body = send(t, app, "GET", "/webhooks", owner, nil)
+	expectStatus(t, "list", status, fiber.StatusOK, body)
+	if body["count"] != 1.0 {
+		t.Fatalf("list: %v", body)
+	}
+	status, body = send(t, app, "GET", path, owner, nil)
+	expectStatus(t, "get", status, fiber.StatusOK, body)
+	status, body = send(t, app, "GET", missing, owner, nil)
+	expectStatus(t, "get a missing webhook", status, fiber.StatusNotFound, body)
+
+	// The webhooks of someone else are missing, and so are their deliveries
+	status, body = send(t, app, "GET", "/webhooks", other, nil)
+	expectStatus(t, "list as someone else", status, fiber.StatusOK, body)
+	if body["count"] != 0.0 {
+		t.Fatalf("list as someone else: %v", body)
+	}
+	for _, request := range []struct{ method, path string }{
+		{"GET", path},
+		{"GET", path + "/deliveries"},
+		{"GET", path + "/deliveries/" + failed.ID.Hex()},
+		{"POST", path + "/deliveries/" + failed.ID.Hex() + "/redeliver"},
+		{"DELETE", path},
+	} {
+		status, body = send(t, app, request.method, request.path, other, nil)
+		expectStatus(t, request.method+" "+request.path+" as someone else", status, fiber.StatusNotFound, body)
+	}
+	if len(repo.hooks) != 1 || len(repo.deliveries) != 1 {
+		t.Fatalf("someone else changed the webhook: %v, %v", repo.hooks, repo.deliveries)
+	}
+
+	// A delivery is only found under its own webhook
+	status, body = send(t, app, "GET", missing+"/deliveries/"+failed.ID.Hex(), owner, nil)
+	expectStatus(t, "get under another webhook", status, fiber.StatusNotFound, body)
+	status, body = send(t, app, "GET", path+"/deliveries/nope", owner, nil)
+	expectStatus(t, "get with a bad ID", status, fiber.StatusBadRequest, body)
+	status, body = send(t, app, "GET", path+"/deliveries/"+failed.ID.Hex(), owner, nil)
+	expectStatus(t, "get delivery", status, fiber.StatusOK, body)
+
+	status, body = send(t, app, "POST", path+"/deliveries/"+failed.ID.Hex()+"/redeliver", owner, nil)
+	expectStatus(t, "redeliver", status, fiber.StatusAccepted, body)
+	if body["status"] != models.DeliveryPending || body["redeliveryOf"] != failed.ID.Hex() {
+		t.Fatalf("redeliver: %v", body)
+	}
+	if len(repo.deliveries) != 2 {
+		t.Fatalf("redeliver queued %d deliveries, want 1", len(repo.deliveries)-1)
+	}
+
+	status, body = send(t, app, "DELETE", path, owner, nil)
+	expectStatus(t, "delete", status, fiber.StatusOK, body)
+}
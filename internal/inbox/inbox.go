@@ -0,0 +1,57 @@
+// Package inbox fills the in-app notification inbox of users from outbox
+// events: a task assigned to them, a task they watch completed.
+package inbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/cmerin0/tasky/internal/models"
+	"github.com/cmerin0/tasky/internal/store"
+)
+
+// HandleEvent is the outbox handler adding the notifications of an event.
+// Notifications are unique per user and event, so relaying an event
+// again doesn't notify twice.
+func HandleEvent(ctx context.Context, event *models.OutboxEvent) error {
+	if event.Type != models.EventTaskAssigned && event.Type != models.EventTaskCompleted {
+		return nil
+	}
+
+	var body struct {
+		Data models.Task `json:"data"`
+	}
+	if err := json.Unmarshal(event.Payload, &body); err != nil {
+		return err
+	}
+	task := body.Data
+
+	if event.Type == models.EventTaskAssigned {
+		return store.AddNotification(ctx, models.Notification{
+			UserID:  task.UserID,
+			Kind:    models.NotificationAssigned,
+			TaskID:  task.ID,
+			Message: fmt.Sprintf("You were assigned %q", task.Title),
+			EventID: event.ID,
+		})
+	}
+
+	watchers, err := store.TaskWatchers(ctx, task.ID)
+	if err != nil {
+		return err
+	}
+	for _, userId := range watchers {
+		err := store.AddNotification(ctx, models.Notification{
+			UserID:  userId,
+			Kind:    models.NotificationWatchedCompleted,
+			TaskID:  task.ID,
+			Message: fmt.Sprintf("%q was completed", task.Title),
+			EventID: event.ID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+// Package jobs is a durable background job queue stored in MongoDB.
+//
+// A job is claimed atomically and stays hidden from other workers until its
+// visibility timeout ends. A failed job is retried with exponential backoff
+// and dead-lettered once it failed every attempt. A job whose worker died
+// is handed out again when its visibility timeout ends, so handlers must be
+// idempotent. Any number of app replicas can run the queue side by side.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/cmerin0/tasky/internal/models"
+	"github.com/cmerin0/tasky/internal/store"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrStatus is returned when a job can't be retried or cancelled in its status
+var ErrStatus = errors.New("the job can't be changed in its current status")
+
+// Handler runs a job, returning an error to retry it
+type Handler func(ctx context.Context, job *models.Job) error
+
+// RetryAt is an error of a handler retrying its job at a given time
+// instead of after the backoff
+type RetryAt struct {
+	At  time.Time
+	Err error
+}
+
+func (e *RetryAt) Error() string { return e.Err.Error() }
+
+func (e *RetryAt) Unwrap() error { return e.Err }
+
+// Type configures how the jobs of a type run
+type Type struct {
+	Handler Handler
+	// Concurrency is how many jobs of the type each replica runs at once, 1 by default
+	Concurrency int
+	// Timeout is the visibility timeout: how long a run may take before
+	// the job is handed out again, a minute by default
+	Timeout time.Duration
+	// MaxAttempts is how many runs fail before the job is dead, 5 by default
+	MaxAttempts int
+	// OnRetry, when set, runs in the transaction retrying a job by hand so
+	// it can reset what its handler checks before running again. An error
+	// leaves the job as it was.
+	OnRetry Handler
+}
+
+// Queue runs the jobs of the registered types
+type Queue struct {
+	types map[string]Type
+}
+
+func New() *Queue {
+	return &Queue{types: map[string]Type{}}
+}
+
+// Register sets the handler and limits of a job type
+func (q *Queue) Register(jobType string, t Type) {
+	t.Concurrency = max(t.Concurrency, 1)
+	if t.Timeout <= 0 {
+		t.Timeout = time.Minute
+	}
+	if t.MaxAttempts <= 0 {
+		t.MaxAttempts = 5
+	}
+	q.types[jobType] = t
+}
+
+// Enqueue adds a job running now. payload is marshalled to JSON.
+func Enqueue(ctx context.Context, jobType string, payload interface{}) (*models.Job, error) {
+	return enqueue(ctx, jobType, "", payload)
+}
+
+// EnqueueOnce adds a job unless one with the same key was already added,
+// then it returns nil
+func EnqueueOnce(ctx context.Context, key, jobType string, payload interface{}) (*models.Job, error) {
+	job, err := enqueue(ctx, jobType, key, payload)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, nil
+	}
+	return job, err
+}
+
+func enqueue(ctx context.Context, jobType, key string, payload interface{}) (*models.Job, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	job := models.Job{
+		Type:      jobType,
+		Payload:   string(body),
+		Status:    models.JobPending,
+		RunAt:     now,
+		CreatedAt: now,
+		Key:       key,
+	}
+	result, err := store.Jobs().InsertOne(ctx, job)
+	if err != nil {
+		return nil, err
+	}
+	job.ID = result.InsertedID.(primitive.ObjectID)
+	return &job, nil
+}
+
+var _ store.JobRepository = (*Queue)(nil)
+
+// ListJobs returns a page of jobs, of one type and status when given
+func (q *Queue) ListJobs(ctx context.Context, jobType, status string, req store.PageRequest) (*store.Page[models.Job], error) {
+	query := bson.M{}
+	if jobType != "" {
+		query["type"] = jobType
+	}
+	if status != "" {
+		query["status"] = status
+	}
+	return store.Paginate[models.Job](ctx, store.Jobs(), query, nil, req)
+}
+
+// GetJob returns a job by ID
+func (q *Queue) GetJob(ctx context.Context, id primitive.ObjectID) (*models.Job, error) {
+	var job models.Job
+	err := store.Jobs().FindOne(ctx, bson.M{"_id": id}).Decode(&job)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, store.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// RetryJob makes a dead, cancelled or pending job run now, from its first
+// attempt, after the OnRetry hook of its type
+func (q *Queue) RetryJob(ctx context.Context, id primitive.ObjectID) (*models.Job, error) {
+	var job *models.Job
+	err := store.WithTransaction(ctx, func(sc mongo.SessionContext) error {
+		var err error
+		job, err = transition(sc, id, []string{models.JobDead, models.JobCancelled, models.JobPending}, bson.M{
+			"$set":   bson.M{"status": models.JobPending, "attempts": 0, "runAt": time.Now().UTC()},
+			"$unset": bson.M{"finishedAt": ""},
+		})
+		if err != nil {
+			return err
+		}
+		if retry := q.types[job.Type].OnRetry; retry != nil {
+			return retry(sc, job)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// CancelJob stops a pending job from running. A running job is left to
+// finish but its outcome is ignored and it isn't retried.
+func (q *Queue) CancelJob(ctx context.Context, id primitive.ObjectID) (*models.Job, error) {
+	return transition(ctx, id, []string{models.JobPending, models.JobRunning}, bson.M{
+		"$set": bson.M{"status": models.JobCancelled, "finishedAt": time.Now().UTC()},
+	})
+}
+
+// transition applies update to a job in one of the given statuses
+func transition(ctx context.Context, id primitive.ObjectID, from []string, update bson.M) (*models.Job, error) {
+	var job models.Job
+	err := store.Jobs().FindOneAndUpdate(ctx,
+		bson.M{"_id": id, "status": bson.M{"$in": from}},
+		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&job)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		count, err := store.Jobs().CountDocuments(ctx, bson.M{"_id": id})
+		if err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			return nil, ErrStatus
+		}
+		return nil, store.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
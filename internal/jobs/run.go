@@ -0,0 +1,129 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/cmerin0/tasky/internal/models"
+	"github.com/cmerin0/tasky/internal/store"
+	"github.com/cmerin0/tasky/internal/worker"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	pollInterval = time.Second // How often an idle worker looks for jobs
+
+	baseBackoff = 10 * time.Second // Wait after the first failed attempt, doubled after each one
+	maxBackoff  = time.Hour
+)
+
+// Backoff returns how long to wait after the given number of failed attempts:
+// 10s doubling up to an hour, plus up to 10% jitter
+func Backoff(attempts int) time.Duration {
+	wait := maxBackoff
+	if attempts < 10 {
+		wait = min(baseBackoff<<(attempts-1), maxBackoff)
+	}
+	return wait + rand.N(wait/10+1)
+}
+
+// Run runs the jobs of the registered types until ctx is done,
+// with as many workers per type as its concurrency
+func (q *Queue) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for jobType, t := range q.types {
+		for range t.Concurrency {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				worker.Poll(ctx, pollInterval, "running "+jobType+" jobs", func(ctx context.Context) (bool, error) {
+					return runNext(ctx, jobType, t)
+				})
+			}()
+		}
+	}
+	wg.Wait()
+}
+
+// runNext claims the job of the type that is due first and runs it.
+// Pending jobs are due at their run time, running ones when their
+// visibility timeout ends. It reports whether a job was run.
+func runNext(ctx context.Context, jobType string, t Type) (bool, error) {
+	now := time.Now().UTC()
+
+	var job models.Job
+	err := store.Jobs().FindOneAndUpdate(ctx,
+		bson.M{
+			"type":   jobType,
+			"status": bson.M{"$in": bson.A{models.JobPending, models.JobRunning}},
+			"runAt":  bson.M{"$lte": now},
+		},
+		bson.M{
+			"$set": bson.M{"status": models.JobRunning, "runAt": now.Add(t.Timeout)},
+			"$inc": bson.M{"attempts": 1},
+		},
+		options.FindOneAndUpdate().
+			SetSort(bson.D{{Key: "runAt", Value: 1}}).
+			SetReturnDocument(options.After),
+	).Decode(&job)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	// A job handed out again after its last attempt timed out is dead
+	var runErr error
+	if job.Attempts > t.MaxAttempts {
+		runErr = fmt.Errorf("timed out after %s", t.Timeout)
+	} else {
+		runErr = run(ctx, t, &job)
+	}
+
+	finished := time.Now().UTC()
+	set := bson.M{"status": models.JobSucceeded, "finishedAt": finished}
+	update := bson.M{"$set": set, "$unset": bson.M{"lastError": ""}}
+	if runErr != nil {
+		update = bson.M{"$set": set}
+		set["lastError"] = runErr.Error()
+		if job.Attempts >= t.MaxAttempts {
+			set["status"] = models.JobDead
+		} else {
+			set["status"] = models.JobPending
+			set["runAt"] = finished.Add(Backoff(job.Attempts))
+			var retry *RetryAt
+			if errors.As(runErr, &retry) {
+				set["runAt"] = retry.At
+			}
+			delete(set, "finishedAt")
+		}
+	}
+
+	// The attempt fences the update: a job cancelled or handed out again
+	// in the meantime is left alone
+	_, err = store.Jobs().UpdateOne(ctx,
+		bson.M{"_id": job.ID, "status": models.JobRunning, "attempts": job.Attempts},
+		update,
+	)
+	return err == nil, err
+}
+
+// run calls the handler within the visibility timeout, turning panics into errors
+func run(ctx context.Context, t Type, job *models.Job) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, t.Timeout)
+	defer cancel()
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("panic: %v", recovered)
+		}
+	}()
+	return t.Handler(ctx, job)
+}
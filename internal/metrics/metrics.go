@@ -0,0 +1,14 @@
+// Package metrics holds the Prometheus metrics of the API
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// APIRequests counts REST API requests per API version and route,
+// it shows which clients still call a version before it's retired
+var APIRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "tasky_api_requests_total",
+	Help: "REST API requests by API version, method, route and status.",
+}, []string{"version", "method", "route", "status"})
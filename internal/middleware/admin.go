@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Admin only lets the users in admins through, identified by the actor
+// header. Callers who don't say who they are get 401, other users 403.
+// With no admins every request is refused.
+func Admin(admins []string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		actor := c.Get(ActorHeader)
+		if actor == "" {
+			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"message": ActorHeader + " header is required"})
+		}
+		if !slices.Contains(admins, actor) {
+			return c.Status(http.StatusForbidden).JSON(fiber.Map{"message": "Admin access is required"})
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,175 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/cmerin0/tasky/internal/db"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotentReplayedHeader  = "Idempotent-Replayed"
+	idempotencyCollectionName = "idempotency_keys"
+	maxIdempotencyKeyLength   = 255
+	// idempotencyLease is how long a request holds its key before a retry
+	// may take it over, in case the process handling it died
+	idempotencyLease = time.Minute
+)
+
+// ActorHeader identifies the user performing a request
+const ActorHeader = "X-User-ID"
+
+// replayedHeaders are the response headers stored with a response and
+// sent again when it is replayed
+var replayedHeaders = []string{fiber.HeaderContentType, fiber.HeaderETag, fiber.HeaderLocation}
+
+// idempotencyRecord is the stored outcome of a request made with an Idempotency-Key.
+// Records expire through a TTL index on createdAt.
+type idempotencyRecord struct {
+	ID          string            `bson:"_id"`
+	RequestHash string            `bson:"requestHash"`
+	Completed   bool              `bson:"completed"`
+	LockedUntil time.Time         `bson:"lockedUntil"`
+	Status      int               `bson:"status,omitempty"`
+	Headers     map[string]string `bson:"headers,omitempty"`
+	Body        []byte            `bson:"body,omitempty"`
+	CreatedAt   time.Time         `bson:"createdAt"`
+}
+
+var idempotencyCollection *mongo.Collection
+
+// getIdempotencyCollection returns the idempotency key collection
+// from the database. It initializes it if not already done.
+func getIdempotencyCollection() *mongo.Collection {
+	if idempotencyCollection == nil {
+		idempotencyCollection = db.GetCollection(idempotencyCollectionName)
+	}
+	return idempotencyCollection
+}
+
+// Idempotency makes POST requests carrying an Idempotency-Key header safe to retry.
+// The first response for a key is stored and replayed for later requests with
+// the same key, a key reused with a different request is rejected with 422 and
+// a key whose first request is still running is rejected with 409, until
+// its lease runs out.
+func Idempotency() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := c.Get(IdempotencyKeyHeader)
+		if c.Method() != fiber.MethodPost || key == "" {
+			return c.Next()
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": "Idempotency-Key is too long"})
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		// Keys are scoped to the caller so clients can't collide with each other
+		hash := sha256.New()
+		hash.Write([]byte(c.Method() + " " + c.Path() + "?" + string(c.Request().URI().QueryString()) + "\n"))
+		hash.Write(c.Body())
+		now := time.Now().UTC()
+		record := idempotencyRecord{
+			ID:          c.Get(ActorHeader) + ":" + key,
+			RequestHash: hex.EncodeToString(hash.Sum(nil)),
+			LockedUntil: now.Add(idempotencyLease),
+			CreatedAt:   now,
+		}
+
+		collection := getIdempotencyCollection()
+		claimed, err := claim(ctx, record)
+		if err != nil {
+			log.Error("Error storing idempotency key: ", err)
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"message": err.Error()})
+		}
+		if !claimed {
+			return replay(ctx, c, record)
+		}
+
+		// Failed requests release the key so they can be retried
+		if err := c.Next(); err != nil || c.Response().StatusCode() >= http.StatusInternalServerError {
+			if _, delErr := collection.DeleteOne(ctx, bson.M{"_id": record.ID}); delErr != nil {
+				log.Error("Error releasing idempotency key: ", delErr)
+			}
+			return err
+		}
+
+		headers := map[string]string{}
+		for _, name := range replayedHeaders {
+			if value := c.Response().Header.Peek(name); len(value) > 0 {
+				headers[name] = string(value)
+			}
+		}
+		_, err = collection.UpdateOne(ctx, bson.M{"_id": record.ID}, bson.M{"$set": bson.M{
+			"completed": true,
+			"status":    c.Response().StatusCode(),
+			"headers":   headers,
+			"body":      c.Response().Body(),
+		}})
+		if err != nil {
+			log.Error("Error storing idempotent response: ", err)
+		}
+		return nil
+	}
+}
+
+// claim claims the key of a request, only one request can insert it. A
+// retry of the same request takes over a claim whose lease ran out.
+func claim(ctx context.Context, record idempotencyRecord) (bool, error) {
+	collection := getIdempotencyCollection()
+	_, err := collection.InsertOne(ctx, record)
+	if err == nil {
+		return true, nil
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return false, err
+	}
+
+	result, err := collection.UpdateOne(ctx, bson.M{
+		"_id":         record.ID,
+		"requestHash": record.RequestHash,
+		"completed":   false,
+		"lockedUntil": bson.M{"$lt": record.CreatedAt},
+	}, bson.M{"$set": bson.M{"lockedUntil": record.LockedUntil}})
+	if err != nil {
+		return false, err
+	}
+	if result.ModifiedCount == 1 {
+		log.Warn("Taking over an expired Idempotency-Key claim")
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// replay answers a request whose key was already used
+func replay(ctx context.Context, c *fiber.Ctx, request idempotencyRecord) error {
+	var stored idempotencyRecord
+	if err := getIdempotencyCollection().FindOne(ctx, bson.M{"_id": request.ID}).Decode(&stored); err != nil {
+		// The first request failed and released the key in the meantime
+		log.Error("Error fetching idempotency key: ", err)
+		return c.Status(http.StatusConflict).JSON(fiber.Map{"message": "Request with this Idempotency-Key is being processed, retry later"})
+	}
+
+	if stored.RequestHash != request.RequestHash {
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{"message": "Idempotency-Key was already used for a different request"})
+	}
+	if !stored.Completed {
+		return c.Status(http.StatusConflict).JSON(fiber.Map{"message": "Request with this Idempotency-Key is being processed, retry later"})
+	}
+
+	log.Info("Replaying idempotent response")
+	c.Set(IdempotentReplayedHeader, "true")
+	for name, value := range stored.Headers {
+		c.Set(name, value)
+	}
+	return c.Status(stored.Status).Send(stored.Body)
+}
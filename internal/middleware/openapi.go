@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/cmerin0/tasky/internal/openapi"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// OpenAPIConfig configures the OpenAPI validation middleware
+type OpenAPIConfig struct {
+	// Responses also checks responses against the document. A response that
+	// doesn't conform is replaced by a 500, so only enable it in tests.
+	Responses bool
+}
+
+// OpenAPIValidation checks requests against the OpenAPI document and
+// rejects those that don't conform with a 400 listing the violations.
+// Routes the document doesn't describe are passed through untouched.
+func OpenAPIValidation(config OpenAPIConfig) fiber.Handler {
+	doc, err := openapi.Load()
+	if err != nil {
+		log.Fatal("Failed to load the OpenAPI document: ", err)
+	}
+
+	return func(c *fiber.Ctx) error {
+		query, _ := url.ParseQuery(string(c.Request().URI().QueryString()))
+		violations := doc.ValidateRequest(openapi.Request{
+			Method: c.Method(),
+			Path:   c.Path(),
+			Query:  query,
+			Header: http.Header(c.GetReqHeaders()),
+			Body:   c.Body(),
+		})
+		if len(violations) > 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"message":    "Request does not match the API specification",
+				"violations": violations,
+			})
+		}
+
+		if err := c.Next(); err != nil || !config.Responses {
+			return err
+		}
+
+		// Streamed responses, such as Server-Sent Events, never end
+		response := c.Response()
+		if response.IsBodyStream() || response.StatusCode() == fiber.StatusSwitchingProtocols {
+			return nil
+		}
+
+		violations = doc.ValidateResponse(c.Method(), c.Path(), response.StatusCode(),
+			string(response.Header.ContentType()), response.Body())
+		if len(violations) > 0 {
+			log.Errorf("Response of %s %s does not match the API specification: %v", c.Method(), c.Path(), violations)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"message":    "Response does not match the API specification",
+				"violations": violations,
+			})
+		}
+		return nil
+	}
+}
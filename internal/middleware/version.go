@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cmerin0/tasky/internal/metrics"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	DeprecationHeader = "Deprecation"
+	SunsetHeader      = "Sunset"
+)
+
+// APIVersion counts the requests served by a version of the API in
+// metrics.APIRequests, labelled with the route pattern rather than the
+// path so IDs don't blow up the number of series
+func APIVersion(version string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if fiberErr, ok := err.(*fiber.Error); ok {
+			status = fiberErr.Code
+		}
+		// Fiber reuses the request buffers, the route strings are safe to keep
+		route := c.Route()
+		metrics.APIRequests.WithLabelValues(version, route.Method, route.Path, strconv.Itoa(status)).Inc()
+		return err
+	}
+}
+
+// DeprecationConfig describes when a deprecated endpoint goes away
+type DeprecationConfig struct {
+	// Since is when the endpoint was deprecated
+	Since time.Time
+	// Sunset is when the endpoint stops being served
+	Sunset time.Time
+	// Successor returns the path of the endpoint replacing the requested one
+	Successor func(path string) string
+}
+
+// Deprecation marks an endpoint as deprecated with the Deprecation
+// (RFC 9745) and Sunset (RFC 8594) headers, linking its successor
+func Deprecation(config DeprecationConfig) fiber.Handler {
+	deprecation := "@" + strconv.FormatInt(config.Since.Unix(), 10)
+	sunset := config.Sunset.UTC().Format(http.TimeFormat)
+
+	return func(c *fiber.Ctx) error {
+		c.Set(DeprecationHeader, deprecation)
+		c.Set(SunsetHeader, sunset)
+		if config.Successor != nil {
+			c.Append(fiber.HeaderLink, "<"+config.Successor(c.Path())+`>; rel="successor-version"`)
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Actions recorded in the task history
+const (
+	HistoryCreated  = "created"
+	HistoryUpdated  = "updated"
+	HistoryDeleted  = "deleted"
+	HistoryReverted = "reverted"
+)
+
+// TaskHistory is a single mutation of a task. Snapshot holds the task
+// as it was after the mutation (or right before it, for deletions) so
+// that any entry can be used as a revert target.
+type TaskHistory struct {
+	ID        primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	TaskID    primitive.ObjectID `json:"taskId" bson:"taskId"`
+	Action    string             `json:"action" bson:"action"`
+	Actor     string             `json:"actor" bson:"actor"`
+	Timestamp time.Time          `json:"timestamp" bson:"timestamp"`
+	Changes   []FieldChange      `json:"changes" bson:"changes"`
+	Snapshot  Task               `json:"snapshot" bson:"snapshot"`
+}
+
+// FieldChange describes how a single task field changed
+type FieldChange struct {
+	Field string      `json:"field" bson:"field"`
+	From  interface{} `json:"from" bson:"from"`
+	To    interface{} `json:"to" bson:"to"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Job statuses
+const (
+	JobPending   = "pending"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	// JobDead is a job that failed every attempt, kept until retried
+	JobDead      = "dead"
+	JobCancelled = "cancelled"
+)
+
+// Job is a unit of background work run by the handler of its type.
+// Payload is the JSON the handler reads.
+type Job struct {
+	ID       primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	Type     string             `json:"type" bson:"type"`
+	Payload  string             `json:"payload" bson:"payload"`
+	Status   string             `json:"status" bson:"status"`
+	Attempts int                `json:"attempts" bson:"attempts"`
+	// RunAt is when a pending job is due, and when a running
+	// job is handed out again if it hasn't finished by then
+	RunAt      time.Time  `json:"runAt" bson:"runAt"`
+	LastError  string     `json:"lastError,omitempty" bson:"lastError,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt" bson:"createdAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty" bson:"finishedAt,omitempty"`
+	// Key makes enqueuing a job idempotent, most jobs don't have one
+	Key string `json:"-" bson:"key,omitempty"`
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Kinds of in-app notifications. Tasks have no comments, so there is no
+// kind for mentions yet.
+const (
+	NotificationAssigned         = "assigned"          // A task was assigned to the user
+	NotificationWatchedCompleted = "watched_completed" // A task the user watches was completed
+)
+
+// Notification is an entry of a user's in-app inbox
+type Notification struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID    primitive.ObjectID `json:"userId" bson:"userId"`
+	Kind      string             `json:"kind" bson:"kind"`
+	TaskID    primitive.ObjectID `json:"taskId" bson:"taskId"`
+	Message   string             `json:"message" bson:"message"`
+	Read      bool               `json:"read" bson:"read"`
+	ReadAt    *time.Time         `json:"readAt,omitempty" bson:"readAt,omitempty"`
+	CreatedAt time.Time          `json:"createdAt" bson:"createdAt"`
+	// EventID is the outbox event the notification was created for
+	EventID primitive.ObjectID `json:"-" bson:"eventId"`
+}
+
+// TaskWatch subscribes a user to the completion of a task
+type TaskWatch struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TaskID    primitive.ObjectID `json:"taskId" bson:"taskId"`
+	UserID    primitive.ObjectID `json:"userId" bson:"userId"`
+	CreatedAt time.Time          `json:"createdAt" bson:"createdAt"`
+}
+
+// NotificationPreferences are the emails a user wants to receive.
+// Users who never saved theirs get DefaultNotificationPreferences.
+type NotificationPreferences struct {
+	UserID     primitive.ObjectID `json:"userId" bson:"_id"`
+	Email      bool               `json:"email" bson:"email"` // Turns every email off when false
+	Assigned   bool               `json:"assigned" bson:"assigned"`
+	DueSoon    bool               `json:"dueSoon" bson:"dueSoon"`
+	Overdue    bool               `json:"overdue" bson:"overdue"`
+	Digest     bool               `json:"digest" bson:"digest"`
+	DigestHour int                `json:"digestHour" bson:"digestHour" validate:"min=0,max=23"` // UTC hour the digest is sent at
+}
+
+// DefaultNotificationPreferences sends every task email but the digest,
+// which users opt in to
+func DefaultNotificationPreferences(userId primitive.ObjectID) NotificationPreferences {
+	return NotificationPreferences{
+		UserID:     userId,
+		Email:      true,
+		Assigned:   true,
+		DueSoon:    true,
+		Overdue:    true,
+		DigestHour: 8,
+	}
+}
@@ -1,6 +1,10 @@
 package models
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
 type Task struct {
 	ID          primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
@@ -8,4 +12,6 @@ type Task struct {
 	Description string             `json:"description" bson:"description"`
 	Completed   bool               `json:"completed" bson:"completed" default:"false"`
 	UserID      primitive.ObjectID `json:"userId" bson:"userId" validate:"required"`
+	DueAt       *time.Time         `json:"dueAt,omitempty" bson:"dueAt,omitempty"`
+	Version     int64              `json:"version" bson:"version"`
 }
@@ -7,10 +7,12 @@ type User struct {
 	Name     string             `json:"name" bson:"name" validate:"required"`
 	Email    string             `json:"email" bson:"email" validate:"required,email"`
 	Password string             `json:"password" bson:"password" validate:"required"`
+	Version  int64              `json:"version" bson:"version"`
 }
 
 type UserResponse struct {
-	ID    primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
-	Name  string             `json:"name" bson:"name"`
-	Email string             `json:"email" bson:"email"`
+	ID      primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	Name    string             `json:"name" bson:"name"`
+	Email   string             `json:"email" bson:"email"`
+	Version int64              `json:"version" bson:"version"`
 }
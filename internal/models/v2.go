@@ -0,0 +1,102 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The v2 API exposes tasks and users in new shapes. Documents are still
+// stored as Task and User, which v1 serves as is, and the adapters below
+// convert between the stored models and their v2 representation.
+
+// Task statuses of the v2 API, stored as the completed flag
+const (
+	TaskStatusOpen = "open"
+	TaskStatusDone = "done"
+)
+
+type TaskV2 struct {
+	ID          primitive.ObjectID `json:"id"`
+	Title       string             `json:"title"`
+	Description string             `json:"description"`
+	Status      string             `json:"status" validate:"oneof=open done"`
+	Owner       TaskOwnerV2        `json:"owner"`
+	DueAt       *time.Time         `json:"dueAt"`
+	Version     int64              `json:"version"`
+}
+
+// TaskOwnerV2 references the user owning a task
+type TaskOwnerV2 struct {
+	ID primitive.ObjectID `json:"id"`
+}
+
+// TaskInputV2 holds the writable fields of a v2 task, the status defaults to open
+type TaskInputV2 struct {
+	Title       string             `json:"title" validate:"required"`
+	Description string             `json:"description"`
+	Status      string             `json:"status" validate:"omitempty,oneof=open done"`
+	OwnerID     primitive.ObjectID `json:"ownerId" validate:"required"`
+	DueAt       *time.Time         `json:"dueAt"`
+}
+
+type UserV2 struct {
+	ID          primitive.ObjectID `json:"id"`
+	DisplayName string             `json:"displayName"`
+	Email       string             `json:"email"`
+	Version     int64              `json:"version"`
+}
+
+// UserInputV2 holds the writable fields of a v2 user
+type UserInputV2 struct {
+	DisplayName string `json:"displayName" validate:"required"`
+	Email       string `json:"email" validate:"required,email"`
+	Password    string `json:"password" validate:"required"`
+}
+
+// NewTaskV2 adapts a stored task to its v2 representation
+func NewTaskV2(task Task) TaskV2 {
+	status := TaskStatusOpen
+	if task.Completed {
+		status = TaskStatusDone
+	}
+	return TaskV2{
+		ID:          task.ID,
+		Title:       task.Title,
+		Description: task.Description,
+		Status:      status,
+		Owner:       TaskOwnerV2{ID: task.UserID},
+		DueAt:       task.DueAt,
+		Version:     task.Version,
+	}
+}
+
+// Task adapts the input to the stored task model
+func (in TaskInputV2) Task() Task {
+	return Task{
+		Title:       in.Title,
+		Description: in.Description,
+		Completed:   in.Status == TaskStatusDone,
+		UserID:      in.OwnerID,
+		DueAt:       in.DueAt,
+	}
+}
+
+// NewUserV2 adapts a stored user to its v2 representation
+func NewUserV2(user UserResponse) UserV2 {
+	return UserV2{
+		ID:          user.ID,
+		DisplayName: user.Name,
+		Email:       user.Email,
+		Version:     user.Version,
+	}
+}
+
+// User adapts the input to the stored user model
+func (in UserInputV2) User() User {
+	return User{
+		Name:     in.DisplayName,
+		Email:    in.Email,
+		Password: in.Password,
+	}
+}
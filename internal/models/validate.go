@@ -0,0 +1,65 @@
+package models
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+var validate = newValidator()
+
+// newValidator returns a validator that reports fields by their JSON name
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	return v
+}
+
+// Validate checks a model against its validate tags and
+// returns a readable error listing every invalid field
+func Validate(model interface{}) error {
+	err := validate.Struct(model)
+
+	var invalid validator.ValidationErrors
+	if !errors.As(err, &invalid) {
+		return err
+	}
+
+	messages := make([]string, 0, len(invalid))
+	for _, fieldErr := range invalid {
+		switch fieldErr.Tag() {
+		case "required":
+			messages = append(messages, fieldErr.Field()+" is required")
+		case "email":
+			messages = append(messages, fieldErr.Field()+" must be a valid email address")
+		case "min", "max":
+			bound := "at least "
+			if fieldErr.Tag() == "max" {
+				bound = "at most "
+			}
+			switch fieldErr.Kind() {
+			case reflect.Slice:
+				messages = append(messages, fieldErr.Field()+" must have "+bound+fieldErr.Param()+" item(s)")
+			case reflect.String:
+				messages = append(messages, fieldErr.Field()+" must be "+bound+fieldErr.Param()+" characters long")
+			default:
+				messages = append(messages, fieldErr.Field()+" must be "+bound+fieldErr.Param())
+			}
+		case "url", "http_url":
+			messages = append(messages, fieldErr.Field()+" must be a valid URL")
+		case "oneof":
+			messages = append(messages, fieldErr.Field()+" must be one of "+strings.ReplaceAll(fieldErr.Param(), " ", ", "))
+		default:
+			messages = append(messages, fieldErr.Field()+" failed the "+fieldErr.Tag()+" check")
+		}
+	}
+	return errors.New(strings.Join(messages, ", "))
+}
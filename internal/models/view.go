@@ -0,0 +1,23 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// Visibility of a saved view
+const (
+	ViewPrivate   = "private"
+	ViewProject   = "project"
+	ViewWorkspace = "workspace"
+)
+
+// View is a saved task query. Filter and Sort use the same syntax as the
+// filter and sort query parameters of the task listing.
+type View struct {
+	ID         primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	Name       string             `json:"name" bson:"name" validate:"required"`
+	OwnerID    primitive.ObjectID `json:"ownerId" bson:"ownerId"`
+	Filter     string             `json:"filter" bson:"filter"`
+	Sort       string             `json:"sort" bson:"sort"`
+	Columns    []string           `json:"columns" bson:"columns"`
+	Visibility string             `json:"visibility" bson:"visibility" default:"private"`
+	SharedWith string             `json:"sharedWith,omitempty" bson:"sharedWith,omitempty"` // Project or workspace ID
+}
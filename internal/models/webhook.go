@@ -0,0 +1,87 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Event types written to the outbox and delivered to webhooks
+const (
+	EventTaskCreated = "task.created"
+	EventTaskUpdated = "task.updated"
+	EventTaskDeleted = "task.deleted"
+	// EventTaskAssigned follows the created or updated event of a task
+	// that got a new owner
+	EventTaskAssigned = "task.assigned"
+	// EventTaskCompleted follows the updated event of a task that got completed
+	EventTaskCompleted = "task.completed"
+	EventUserCreated   = "user.created"
+	EventUserUpdated   = "user.updated"
+	EventUserDeleted   = "user.deleted"
+)
+
+// Delivery statuses
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// OutboxEvent is an event written in the same transaction as the mutation
+// it describes. Payload is the JSON body webhooks receive.
+type OutboxEvent struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty"`
+	Type         string             `bson:"type"`
+	Payload      []byte             `bson:"payload"`
+	CreatedAt    time.Time          `bson:"createdAt"`
+	LockedUntil  time.Time          `bson:"lockedUntil"`
+	DispatchedAt *time.Time         `bson:"dispatchedAt"`
+}
+
+// Webhook is a subscription to the events of its owner's tasks and
+// account. The secret signs deliveries and is only returned when the
+// webhook is created.
+type Webhook struct {
+	ID        primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	OwnerID   primitive.ObjectID `json:"ownerId" bson:"ownerId"`
+	URL       string             `json:"url" bson:"url"`
+	Events    []string           `json:"events" bson:"events"`
+	Secret    string             `json:"secret,omitempty" bson:"secret"`
+	Active    bool               `json:"active" bson:"active"`
+	CreatedAt time.Time          `json:"createdAt" bson:"createdAt"`
+}
+
+// WebhookInput creates or replaces a webhook
+type WebhookInput struct {
+	URL    string   `json:"url" validate:"required,http_url"`
+	Events []string `json:"events" validate:"required,min=1,dive,oneof=task.created task.updated task.deleted task.assigned task.completed user.created user.updated user.deleted"`
+	Secret string   `json:"secret,omitempty"` // Generated on creation when empty, kept on replacement
+	Active *bool    `json:"active,omitempty"` // Defaults to true
+}
+
+// WebhookDelivery is the delivery of an event to a webhook, with a log of
+// every attempt. Redeliveries are new deliveries pointing at the original.
+type WebhookDelivery struct {
+	ID            primitive.ObjectID  `json:"id,omitempty" bson:"_id,omitempty"`
+	WebhookID     primitive.ObjectID  `json:"webhookId" bson:"webhookId"`
+	EventID       primitive.ObjectID  `json:"eventId" bson:"eventId"`
+	EventType     string              `json:"eventType" bson:"eventType"`
+	Payload       string              `json:"payload" bson:"payload"`
+	Status        string              `json:"status" bson:"status"`
+	Attempts      []DeliveryAttempt   `json:"attempts" bson:"attempts"`
+	NextAttemptAt *time.Time          `json:"nextAttemptAt" bson:"nextAttemptAt"`
+	RedeliveryOf  *primitive.ObjectID `json:"redeliveryOf,omitempty" bson:"redeliveryOf,omitempty"`
+	// FanoutKey makes fanning an event out to a webhook idempotent,
+	// redeliveries don't have one
+	FanoutKey string    `json:"-" bson:"fanoutKey,omitempty"`
+	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
+}
+
+// DeliveryAttempt is a single request made to deliver an event
+type DeliveryAttempt struct {
+	At         time.Time `json:"at" bson:"at"`
+	StatusCode int       `json:"statusCode,omitempty" bson:"statusCode,omitempty"`
+	Error      string    `json:"error,omitempty" bson:"error,omitempty"`
+	DurationMs int64     `json:"durationMs" bson:"durationMs"`
+}
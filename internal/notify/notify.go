@@ -0,0 +1,238 @@
+// Package notify emails users about their tasks: when a task is assigned to
+// them, when it is due soon or overdue, and an opt-in daily digest of their
+// open tasks. Users choose which emails they get in their preferences.
+//
+// Assignment emails go through the job queue, so failed sends are retried
+// and can be inspected. Reminders and digests are scheduler jobs, run by
+// one replica at a time. Every email also has a key, recorded when it is
+// sent, so an email is sent once even when a job runs again or an outbox
+// event is relayed again.
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/cmerin0/tasky/internal/jobs"
+	"github.com/cmerin0/tasky/internal/models"
+	"github.com/cmerin0/tasky/internal/store"
+
+	"github.com/gofiber/fiber/v2/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	sendTimeout = 30 * time.Second // Time allowed to render and send one email
+	digestLimit = 100              // Maximum number of tasks listed in a digest
+
+	// SweepInterval is how often due dates and digests should be checked
+	SweepInterval = time.Minute
+	// DefaultRemindBefore is how long before its due date a task is due soon,
+	// unless the notifier is given another lead time
+	DefaultRemindBefore = 24 * time.Hour
+	// OverdueWindow bounds how long after its due date an overdue task is
+	// reported, so old overdue tasks don't all email at once
+	OverdueWindow = 24 * time.Hour
+)
+
+// Notifier sends task emails through a Sender
+type Notifier struct {
+	sender       Sender
+	users        store.UserRepository
+	remindBefore time.Duration
+}
+
+// New returns a notifier reminding owners remindBefore their tasks are due,
+// DefaultRemindBefore when it is not positive. Recipients are looked up in users.
+func New(sender Sender, users store.UserRepository, remindBefore time.Duration) *Notifier {
+	if remindBefore <= 0 {
+		remindBefore = DefaultRemindBefore
+	}
+	return &Notifier{sender: sender, users: users, remindBefore: remindBefore}
+}
+
+// JobAssignedEmail is the job emailing the new owner of an assigned task
+const JobAssignedEmail = "email.assigned"
+
+// assignedEmail is the payload of an assigned email job
+type assignedEmail struct {
+	EventID primitive.ObjectID `json:"eventId"`
+	Task    models.Task        `json:"task"`
+}
+
+// HandleEvent is the outbox handler queuing the email
+// to the new owner of an assigned task
+func (n *Notifier) HandleEvent(ctx context.Context, event *models.OutboxEvent) error {
+	if event.Type != models.EventTaskAssigned {
+		return nil
+	}
+
+	var body struct {
+		Data models.Task `json:"data"`
+	}
+	if err := json.Unmarshal(event.Payload, &body); err != nil {
+		return err
+	}
+
+	_, err := jobs.EnqueueOnce(ctx, "assigned:"+event.ID.Hex(), JobAssignedEmail,
+		assignedEmail{EventID: event.ID, Task: body.Data})
+	return err
+}
+
+// SendAssigned is the handler of assigned email jobs
+func (n *Notifier) SendAssigned(ctx context.Context, job *models.Job) error {
+	var payload assignedEmail
+	if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+		return err
+	}
+	task := payload.Task
+
+	return n.send(ctx, "assigned:"+payload.EventID.Hex(), KindAssigned, task.UserID,
+		func(prefs *models.NotificationPreferences) bool { return prefs.Assigned },
+		emailData{Task: &task})
+}
+
+// RemindDue emails the owners of open tasks that are due soon or that fell due recently
+func (n *Notifier) RemindDue(ctx context.Context) error {
+	now := time.Now().UTC()
+	cursor, err := store.Tasks().Find(ctx, bson.M{
+		"completed": false,
+		"dueAt":     bson.M{"$gt": now.Add(-OverdueWindow), "$lte": now.Add(n.remindBefore)},
+	})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var errs []error
+	for cursor.Next(ctx) {
+		var task models.Task
+		if err := cursor.Decode(&task); err != nil {
+			return err
+		}
+
+		// Keyed on the due date too, so moving it sends a new reminder
+		kind, wanted := KindDueSoon, func(prefs *models.NotificationPreferences) bool { return prefs.DueSoon }
+		if !task.DueAt.After(now) {
+			kind, wanted = KindOverdue, func(prefs *models.NotificationPreferences) bool { return prefs.Overdue }
+		}
+		key := kind + ":" + task.ID.Hex() + ":" + task.DueAt.UTC().Format(time.RFC3339)
+		if err := n.send(ctx, key, kind, task.UserID, wanted, emailData{Task: &task}); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(append(errs, cursor.Err())...)
+}
+
+// SendDigests emails their open tasks to the users who want
+// their digest at the current hour
+func (n *Notifier) SendDigests(ctx context.Context) error {
+	now := time.Now().UTC()
+	cursor, err := store.NotificationPreferences().Find(ctx, bson.M{
+		"email":      true,
+		"digest":     true,
+		"digestHour": now.Hour(),
+	})
+	if err != nil {
+		return err
+	}
+	var subscribers []models.NotificationPreferences
+	if err := cursor.All(ctx, &subscribers); err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, prefs := range subscribers {
+		tasks, err := openTasks(ctx, prefs.UserID)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if len(tasks) == 0 {
+			continue
+		}
+
+		key := "digest:" + prefs.UserID.Hex() + ":" + now.Format(time.DateOnly)
+		if err := n.send(ctx, key, KindDigest, prefs.UserID,
+			func(prefs *models.NotificationPreferences) bool { return prefs.Digest },
+			emailData{Tasks: tasks}); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// openTasks returns the open tasks of a user, soonest due first
+// and tasks without a due date last
+func openTasks(ctx context.Context, userId primitive.ObjectID) ([]models.Task, error) {
+	// MongoDB sorts missing values first, so flag them to sort them last.
+	// The order is settled before the limit so no due task is left out.
+	cursor, err := store.Tasks().Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"userId": userId, "completed": false}}},
+		{{Key: "$addFields", Value: bson.M{
+			"undated": bson.M{"$eq": bson.A{bson.M{"$ifNull": bson.A{"$dueAt", nil}}, nil}},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "undated", Value: 1}, {Key: "dueAt", Value: 1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$limit", Value: digestLimit}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var tasks []models.Task
+	if err := cursor.All(ctx, &tasks); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// send emails a user the message of the given kind, unless wanted says
+// their preferences turn it off or an email with the same key was sent
+func (n *Notifier) send(ctx context.Context, key, kind string, userId primitive.ObjectID, wanted func(*models.NotificationPreferences) bool, data emailData) error {
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	prefs, err := store.GetNotificationPreferences(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if !prefs.Email || !wanted(prefs) {
+		return nil
+	}
+
+	user, err := n.users.GetUser(ctx, userId)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	data.User = user
+
+	msg, err := render(kind, data)
+	if err != nil {
+		return err
+	}
+
+	// Record the key first, only the replica that inserts it sends the email
+	sent := store.SentNotifications()
+	_, err = sent.InsertOne(ctx, bson.M{"_id": key, "userId": userId, "sentAt": time.Now().UTC()})
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := n.sender.Send(ctx, msg); err != nil {
+		// Forget the key so the email is tried again
+		if _, delErr := sent.DeleteOne(context.Background(), bson.M{"_id": key}); delErr != nil {
+			log.Error("Error releasing notification key: ", delErr)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package notify
+
+import (
+	"context"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cmerin0/tasky/internal/db"
+	"github.com/cmerin0/tasky/internal/models"
+	"github.com/cmerin0/tasky/internal/notify/smtptest"
+	"github.com/cmerin0/tasky/internal/store"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestEmails(t *testing.T) {
+	server := smtptest.NewServer()
+	defer server.Close()
+	sender := NewSMTPSender(SMTPConfig{Addr: server.Addr, Username: "tasky", Password: "secret", From: "tasky@example.com"})
+
+	user := &models.UserResponse{ID: primitive.NewObjectID(), Name: "Ada", Email: "ada@example.com"}
+	due := time.Date(2030, time.March, 4, 9, 30, 0, 0, time.UTC)
+	past := time.Date(2020, time.January, 2, 8, 0, 0, 0, time.UTC)
+	task := &models.Task{ID: primitive.NewObjectID(), Title: "Ship the release", Description: "Tag and publish", DueAt: &due}
+	late := &models.Task{ID: primitive.NewObjectID(), Title: "File taxes", DueAt: &past}
+
+	tests := []struct {
+		kind    string
+		data    emailData
+		subject string
+		body    []string
+	}{
+		{
+			kind:    KindAssigned,
+			data:    emailData{User: user, Task: task},
+			subject: `You were assigned "Ship the release"`,
+			body:    []string{"Hi Ada,", `The task "Ship the release" is now yours.`, "Tag and publish", "It is due Mon Mar 4 09:30 UTC.", "Task ID: " + task.ID.Hex()},
+		},
+		{
+			kind:    KindDueSoon,
+			data:    emailData{User: user, Task: task},
+			subject: `"Ship the release" is due Mon Mar 4 09:30 UTC`,
+			body:    []string{"Hi Ada,", `Your task "Ship the release" is due Mon Mar 4 09:30 UTC.`, "Task ID: " + task.ID.Hex()},
+		},
+		{
+			kind:    KindOverdue,
+			data:    emailData{User: user, Task: late},
+			subject: `"File taxes" is overdue`,
+			body:    []string{`Your task "File taxes" was due Thu Jan 2 08:00 UTC and is still open.`, "Task ID: " + late.ID.Hex()},
+		},
+		{
+			kind:    KindDigest,
+			data:    emailData{User: user, Tasks: []models.Task{*task, *late, {Title: "Read the paper"}}},
+			subject: "Your open tasks: 3",
+			body: []string{
+				"You have 3 open task(s):",
+				"- Ship the release (due Mon Mar 4 09:30 UTC)\n",
+				"- File taxes (due Thu Jan 2 08:00 UTC, overdue)\n",
+				"- Read the paper\n",
+			},
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			msg, err := render(tt.kind, tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := sender.Send(ctx, msg); err != nil {
+				t.Fatal(err)
+			}
+
+			received := server.Messages()
+			if len(received) != i+1 {
+				t.Fatalf("server received %d message(s), want %d", len(received), i+1)
+			}
+			sent := received[i]
+			if sent.From != "tasky@example.com" || len(sent.To) != 1 || sent.To[0] != "ada@example.com" {
+				t.Errorf("envelope from %q to %q, want from tasky@example.com to ada@example.com", sent.From, sent.To)
+			}
+
+			parsed, err := sent.Parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+			subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if subject != tt.subject {
+				t.Errorf("subject %q, want %q", subject, tt.subject)
+			}
+			raw, err := io.ReadAll(quotedprintable.NewReader(parsed.Body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			body := strings.ReplaceAll(string(raw), "\r\n", "\n")
+			for _, want := range tt.body {
+				if !strings.Contains(body, want) {
+					t.Errorf("body does not contain %q:\n%s", want, body)
+				}
+			}
+		})
+	}
+}
+
+// TestOpenTasks runs against the MongoDB at TEST_MONGO_URI, in the
+// database named by MONGO_DBNAME or tasky_test
+func TestOpenTasks(t *testing.T) {
+	uri := os.Getenv("TEST_MONGO_URI")
+	if uri == "" {
+		t.Skip("TEST_MONGO_URI is not set")
+	}
+	if os.Getenv("MONGO_DBNAME") == "" {
+		t.Setenv("MONGO_DBNAME", "tasky_test")
+	}
+	db.ConnectDB(uri)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Undated tasks come first by ID and fill more than a digest on
+	// their own, the dated ones must still be listed, soonest first
+	userId := primitive.NewObjectID()
+	base := time.Date(2030, time.March, 4, 9, 0, 0, 0, time.UTC)
+	var docs []interface{}
+	for i := 0; i < digestLimit; i++ {
+		docs = append(docs, models.Task{ID: primitive.NewObjectID(), Title: "Undated", UserID: userId})
+	}
+	dated := []time.Duration{3 * time.Hour, time.Hour, 2 * time.Hour}
+	for _, offset := range dated {
+		due := base.Add(offset)
+		docs = append(docs, models.Task{ID: primitive.NewObjectID(), Title: "Dated", UserID: userId, DueAt: &due})
+	}
+	done := base
+	docs = append(docs, models.Task{ID: primitive.NewObjectID(), Title: "Done", UserID: userId, DueAt: &done, Completed: true})
+	if _, err := store.Tasks().InsertMany(ctx, docs); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_, _ = store.Tasks().DeleteMany(context.Background(), bson.M{"userId": userId})
+	})
+
+	tasks, err := openTasks(ctx, userId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != digestLimit {
+		t.Fatalf("got %d tasks, want %d", len(tasks), digestLimit)
+	}
+	for i, offset := range []time.Duration{time.Hour, 2 * time.Hour, 3 * time.Hour} {
+		if tasks[i].DueAt == nil || !tasks[i].DueAt.Equal(base.Add(offset)) {
+			t.Errorf("task %d is due %v, want %v", i, tasks[i].DueAt, base.Add(offset))
+		}
+	}
+	for i, task := range tasks[len(dated):] {
+		if task.DueAt != nil || task.Completed {
+			t.Fatalf("task %d is %+v, want an open task without a due date", len(dated)+i, task)
+		}
+	}
+}
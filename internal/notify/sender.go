@@ -0,0 +1,29 @@
+package notify
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// Message is an email ready to be sent
+type Message struct {
+	To      string
+	Subject string
+	Body    string // Plain text
+}
+
+// Sender sends emails. Implementations must be safe for concurrent use.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogSender logs emails instead of sending them,
+// it is used when no SMTP server is configured
+type LogSender struct{}
+
+func (LogSender) Send(ctx context.Context, msg Message) error {
+	log.Info("Email to ", msg.To, ": ", msg.Subject, "\n", strings.TrimSpace(msg.Body))
+	return nil
+}
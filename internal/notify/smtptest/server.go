@@ -0,0 +1,182 @@
+// Package smtptest provides a fake SMTP server that accepts every message
+// and keeps it in memory, for testing senders and for local development.
+package smtptest
+
+import (
+	"bytes"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+)
+
+// Message is a message received by the server
+type Message struct {
+	From string
+	To   []string
+	Data []byte // Headers and body, as sent
+}
+
+// Parse parses the headers and body of the message
+func (m Message) Parse() (*mail.Message, error) {
+	return mail.ReadMessage(bytes.NewReader(m.Data))
+}
+
+// Server is a fake SMTP server. It accepts any credentials and never
+// offers STARTTLS.
+type Server struct {
+	Addr string
+
+	listener  net.Listener
+	onMessage func(Message)
+	wg        sync.WaitGroup
+
+	mu       sync.Mutex
+	conns    map[net.Conn]bool
+	messages []Message
+}
+
+// NewServer starts a server on a random local port, it panics if it can't listen
+func NewServer() *Server {
+	s, err := Listen("127.0.0.1:0", nil)
+	if err != nil {
+		panic("smtptest: " + err.Error())
+	}
+	return s
+}
+
+// Listen starts a server on addr. onMessage, if not nil, is called with
+// every message received.
+func Listen(addr string, onMessage func(Message)) (*Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		Addr:      listener.Addr().String(),
+		listener:  listener,
+		onMessage: onMessage,
+		conns:     map[net.Conn]bool{},
+	}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Messages returns the messages received so far
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.messages...)
+}
+
+// Close stops the server, closing the open connections
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	s.mu.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns[conn] = true
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+
+			s.mu.Lock()
+			delete(s.conns, conn)
+			s.mu.Unlock()
+		}()
+	}
+}
+
+// handle runs the SMTP conversation of one connection
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	reply := func(format string, args ...interface{}) bool {
+		return tp.PrintfLine(format, args...) == nil
+	}
+
+	var msg Message
+	if !reply("220 smtptest ready") {
+		return
+	}
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+
+		ok := true
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			ok = reply("250-smtptest\r\n250-8BITMIME\r\n250 AUTH PLAIN LOGIN")
+		case "HELO":
+			ok = reply("250 smtptest")
+		case "AUTH":
+			ok = reply("235 2.7.0 Authentication successful")
+		case "MAIL":
+			msg = Message{From: address(arg)}
+			ok = reply("250 2.1.0 OK")
+		case "RCPT":
+			msg.To = append(msg.To, address(arg))
+			ok = reply("250 2.1.5 OK")
+		case "DATA":
+			if !reply("354 End data with <CR><LF>.<CR><LF>") {
+				return
+			}
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			msg.Data = data
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			if s.onMessage != nil {
+				s.onMessage(msg)
+			}
+			msg = Message{}
+			ok = reply("250 2.0.0 OK")
+		case "RSET":
+			msg = Message{}
+			ok = reply("250 2.0.0 OK")
+		case "NOOP":
+			ok = reply("250 2.0.0 OK")
+		case "QUIT":
+			reply("221 2.0.0 Bye")
+			return
+		default:
+			ok = reply("502 5.5.2 Command not recognized")
+		}
+		if !ok {
+			return
+		}
+	}
+}
+
+// address extracts the address from a MAIL FROM:<a> or RCPT TO:<a> argument
+func address(arg string) string {
+	_, addr, _ := strings.Cut(arg, ":")
+	addr, _, _ = strings.Cut(strings.TrimSpace(addr), " ")
+	return strings.Trim(addr, "<>")
+}
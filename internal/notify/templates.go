@@ -0,0 +1,68 @@
+package notify
+
+import (
+	"embed"
+	"errors"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/cmerin0/tasky/internal/models"
+)
+
+// Email kinds, each rendered from templates/<kind>.tmpl.
+// A template defines a "subject" and a "body".
+const (
+	KindAssigned = "assigned"
+	KindDueSoon  = "due_soon"
+	KindOverdue  = "overdue"
+	KindDigest   = "digest"
+)
+
+var errUnknownKind = errors.New("unknown email kind")
+
+//go:embed templates/*.tmpl
+var templateFiles embed.FS
+
+var templateFuncs = template.FuncMap{
+	"date": func(t *time.Time) string {
+		return t.UTC().Format("Mon Jan 2 15:04 MST")
+	},
+	"overdue": func(t *time.Time) bool {
+		return t.Before(time.Now())
+	},
+}
+
+// templates holds a template set per kind, since every
+// template defines the same subject and body names
+var templates = func() map[string]*template.Template {
+	sets := map[string]*template.Template{}
+	for _, kind := range []string{KindAssigned, KindDueSoon, KindOverdue, KindDigest} {
+		sets[kind] = template.Must(template.New(kind).Funcs(templateFuncs).ParseFS(templateFiles, "templates/"+kind+".tmpl"))
+	}
+	return sets
+}()
+
+// emailData is what the templates render
+type emailData struct {
+	User  *models.UserResponse
+	Task  *models.Task
+	Tasks []models.Task
+}
+
+// render builds the email of the given kind, addressed to data.User
+func render(kind string, data emailData) (Message, error) {
+	tmpl, ok := templates[kind]
+	if !ok {
+		return Message{}, errUnknownKind
+	}
+
+	var subject, body strings.Builder
+	if err := tmpl.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, err
+	}
+	if err := tmpl.ExecuteTemplate(&body, "body", data); err != nil {
+		return Message{}, err
+	}
+	return Message{To: data.User.Email, Subject: subject.String(), Body: strings.TrimLeft(body.String(), "\n")}, nil
+}
@@ -0,0 +1,345 @@
+// Package gen generates the OpenAPI document of the tasky API.
+//
+// Routes are read from setupRoutes in cmd/main.go and documented from the
+// annotations (@Summary, @Param, @Success, @Router, ...) on their handlers.
+// Every route must have a documented handler whose @Router matches it.
+package gen
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/cmerin0/tasky/internal/openapi"
+	"github.com/cmerin0/tasky/internal/storage"
+)
+
+// apiPrefix is the prefix of the v1 API routes. @Router paths of
+// their handlers are written relative to it, later versions use full paths.
+const apiPrefix = "/api/v1"
+
+var (
+	paramPattern    = regexp.MustCompile(`^(\S+)\s+(path|query|header|body)\s+(\S+)\s+(true|false)\s+"(.*)"$`)
+	versionPattern  = regexp.MustCompile(`^/api/v\d+`)
+	responsePattern = regexp.MustCompile(`^(\d{3})\s+\{(object|array|string)\}\s+(\S+)\s*(.*)$`)
+	statusPattern   = regexp.MustCompile(`^(\d{3})\s*(.*)$`)
+	routerPattern   = regexp.MustCompile(`^(\S+)\s+\[(\w+)\]$`)
+)
+
+// sourcePackage is a parsed package below internal/
+type sourcePackage struct {
+	name  string
+	funcs map[string]*ast.FuncDecl // Functions and methods by name
+	types map[string]*ast.TypeSpec
+}
+
+// generator builds the document, collecting undocumented routes on the way
+type generator struct {
+	packages map[string]*sourcePackage
+	doc      *openapi.Document
+	problems []string
+}
+
+// SpecFile is where the document is kept, relative to the module root
+const SpecFile = "internal/openapi/openapi.json"
+
+// Render generates the document of the module at root as it is written
+// to SpecFile, and returns the routes that aren't properly documented
+func Render(root string) ([]byte, []string, error) {
+	doc, problems, err := Generate(root)
+	if err != nil {
+		return nil, nil, err
+	}
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, nil, err
+	}
+	return append(out, '\n'), problems, nil
+}
+
+// Generate builds the OpenAPI document of the module at root and
+// returns the routes that aren't properly documented
+func Generate(root string) (*openapi.Document, []string, error) {
+	fset := token.NewFileSet()
+
+	routes, err := parseRoutes(fset, filepath.Join(root, "cmd", "main.go"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	g := &generator{
+		packages: map[string]*sourcePackage{},
+		doc: &openapi.Document{
+			OpenAPI: "3.0.3",
+			Info: openapi.Info{
+				Title:       "Tasky API",
+				Description: apiDescription(),
+				Version:     "1.0.0",
+			},
+			Paths:      map[string]*openapi.PathItem{},
+			Components: openapi.Components{Schemas: map[string]*openapi.Schema{}},
+		},
+	}
+	if err := g.parsePackages(fset, filepath.Join(root, "internal")); err != nil {
+		return nil, nil, err
+	}
+
+	for _, r := range routes {
+		g.addRoute(r)
+	}
+	return g.doc, g.problems, nil
+}
+
+// apiDescription introduces the API, listing what only MongoDB serves
+func apiDescription() string {
+	return "Users and their tasks. Every storage serves users, tasks, search, task history and saved views. " +
+		"With a STORAGE other than MongoDB these aren't served: " + strings.Join(storage.MongoOnly, ", ") + "."
+}
+
+// parsePackages parses every package below dir, skipping tests
+func (g *generator) parsePackages(fset *token.FileSet, dir string) error {
+	return filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil || !entry.IsDir() {
+			return err
+		}
+		pkgs, err := parser.ParseDir(fset, path, func(info os.FileInfo) bool {
+			return !strings.HasSuffix(info.Name(), "_test.go")
+		}, parser.ParseComments)
+		if err != nil {
+			return err
+		}
+
+		for name, pkg := range pkgs {
+			source := &sourcePackage{name: name, funcs: map[string]*ast.FuncDecl{}, types: map[string]*ast.TypeSpec{}}
+			for _, file := range pkg.Files {
+				for _, decl := range file.Decls {
+					switch decl := decl.(type) {
+					case *ast.FuncDecl:
+						source.funcs[decl.Name.Name] = decl
+					case *ast.GenDecl:
+						for _, spec := range decl.Specs {
+							if typeSpec, ok := spec.(*ast.TypeSpec); ok {
+								source.types[typeSpec.Name.Name] = typeSpec
+							}
+						}
+					}
+				}
+			}
+			g.packages[name] = source
+		}
+		return nil
+	})
+}
+
+// resolveHandler finds the declaration of a route handler. Package qualified
+// handlers are looked up in that package, methods by name in any package,
+// preferring one documenting a route over repository methods of the same name.
+func (g *generator) resolveHandler(sel *ast.SelectorExpr) (*sourcePackage, *ast.FuncDecl) {
+	if ident, ok := sel.X.(*ast.Ident); ok {
+		if pkg, ok := g.packages[ident.Name]; ok {
+			return pkg, pkg.funcs[sel.Sel.Name]
+		}
+	}
+	names := make([]string, 0, len(g.packages))
+	for name := range g.packages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var fallback *sourcePackage
+	for _, name := range names {
+		pkg := g.packages[name]
+		fn, ok := pkg.funcs[sel.Sel.Name]
+		if !ok || fn.Recv == nil {
+			continue
+		}
+		if fn.Doc != nil && strings.Contains(fn.Doc.Text(), "@Router") {
+			return pkg, fn
+		}
+		if fallback == nil {
+			fallback = pkg
+		}
+	}
+	if fallback == nil {
+		return nil, nil
+	}
+	return fallback, fallback.funcs[sel.Sel.Name]
+}
+
+// addRoute documents a single route from the annotations of its handler
+func (g *generator) addRoute(r route) {
+	where := fmt.Sprintf("%s: %s %s", r.pos, strings.ToUpper(r.method), r.path)
+	if r.handler == nil {
+		g.problems = append(g.problems, where+": handler can't be resolved")
+		return
+	}
+
+	pkg, fn := g.resolveHandler(r.handler)
+	if fn == nil || fn.Doc == nil {
+		g.problems = append(g.problems, where+": handler "+r.handler.Sel.Name+" has no documentation")
+		return
+	}
+
+	op := &openapi.Operation{
+		OperationID: fn.Name.Name,
+		Tags:        []string{routeTag(r.path)},
+		Responses:   map[string]*openapi.Response{},
+	}
+	consumes := []string{"application/json"}
+	produces := "text/html" // Media type of {string} responses
+	routed := false
+
+	for _, line := range strings.Split(fn.Doc.Text(), "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "@") {
+			continue
+		}
+		tag, value, _ := strings.Cut(line, " ")
+		value = strings.TrimSpace(value)
+
+		switch tag {
+		case "@Summary":
+			op.Summary = value
+		case "@Description":
+			op.Description = value
+		case "@Deprecated":
+			op.Deprecated = true
+		case "@Accept":
+			consumes = mediaTypes(value)
+		case "@Produce":
+			produces = mediaTypes(value)[0]
+		case "@Param":
+			g.addParam(op, pkg, value, consumes, where)
+		case "@Success", "@Failure":
+			g.addResponse(op, pkg, value, produces, where)
+		case "@Router":
+			match := routerPattern.FindStringSubmatch(value)
+			if match == nil {
+				g.problems = append(g.problems, where+": malformed @Router "+value)
+				continue
+			}
+			if strings.ToLower(match[2]) == r.method && (match[1] == r.path || apiPrefix+match[1] == r.path) {
+				routed = true
+			}
+		}
+	}
+
+	switch {
+	case op.Summary == "":
+		g.problems = append(g.problems, where+": handler "+fn.Name.Name+" has no @Summary")
+		return
+	case !routed:
+		g.problems = append(g.problems, where+": handler "+fn.Name.Name+" has no matching @Router")
+		return
+	case len(op.Responses) == 0:
+		g.problems = append(g.problems, where+": handler "+fn.Name.Name+" documents no responses")
+		return
+	}
+
+	item, ok := g.doc.Paths[r.path]
+	if !ok {
+		item = &openapi.PathItem{}
+		g.doc.Paths[r.path] = item
+	}
+	(*item)[r.method] = op
+}
+
+// routeTag groups operations by the first path segment of the API
+func routeTag(path string) string {
+	path = versionPattern.ReplaceAllString(path, "")
+	segment, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	switch segment {
+	case "health", "healthz", "readyz":
+		return "health"
+	case "", "docs", "openapi.json":
+		return "docs"
+	}
+	return segment
+}
+
+// mediaTypes expands the short names used by @Accept and @Produce
+func mediaTypes(value string) []string {
+	var types []string
+	for _, item := range strings.Split(value, ",") {
+		switch item = strings.TrimSpace(item); item {
+		case "json":
+			types = append(types, "application/json")
+		case "html":
+			types = append(types, "text/html")
+		case "plain":
+			types = append(types, "text/plain")
+		default:
+			types = append(types, item)
+		}
+	}
+	return types
+}
+
+func (g *generator) addParam(op *openapi.Operation, pkg *sourcePackage, value string, consumes []string, where string) {
+	match := paramPattern.FindStringSubmatch(value)
+	if match == nil {
+		g.problems = append(g.problems, where+": malformed @Param "+value)
+		return
+	}
+	name, in, typ, required, description := match[1], match[2], match[3], match[4] == "true", match[5]
+
+	if in == "body" {
+		content := map[string]openapi.MediaType{}
+		for _, mediaType := range consumes {
+			content[mediaType] = openapi.MediaType{Schema: g.typeSchema(pkg, typ)}
+		}
+		op.RequestBody = &openapi.RequestBody{Description: description, Required: required, Content: content}
+		return
+	}
+
+	op.Parameters = append(op.Parameters, &openapi.Parameter{
+		Name:        name,
+		In:          in,
+		Description: description,
+		Required:    required || in == "path",
+		Schema:      g.typeSchema(pkg, typ),
+	})
+}
+
+func (g *generator) addResponse(op *openapi.Operation, pkg *sourcePackage, value, produces, where string) {
+	if match := responsePattern.FindStringSubmatch(value); match != nil {
+		schema := g.typeSchema(pkg, match[3])
+		if match[2] == "array" {
+			schema = &openapi.Schema{Type: "array", Items: schema}
+		}
+		mediaType := "application/json"
+		if match[2] == "string" {
+			mediaType = produces
+		}
+		op.Responses[match[1]] = &openapi.Response{
+			Description: responseDescription(match[1], match[4]),
+			Content:     map[string]openapi.MediaType{mediaType: {Schema: schema}},
+		}
+		return
+	}
+
+	if match := statusPattern.FindStringSubmatch(value); match != nil {
+		op.Responses[match[1]] = &openapi.Response{
+			Description: responseDescription(match[1], match[2]),
+		}
+		return
+	}
+	g.problems = append(g.problems, where+": malformed response "+value)
+}
+
+// responseDescription falls back to the standard status text
+func responseDescription(code, text string) string {
+	if text = strings.Trim(strings.TrimSpace(text), `"`); text != "" {
+		return text
+	}
+	var status int
+	fmt.Sscan(code, &status)
+	return http.StatusText(status)
+}
@@ -0,0 +1,141 @@
+package gen
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+// routeMethods are the fiber router methods that register a route
+var routeMethods = map[string]bool{
+	"Get": true, "Post": true, "Put": true, "Patch": true, "Delete": true,
+}
+
+// route is a route registered in setupRoutes
+type route struct {
+	method  string
+	path    string
+	handler *ast.SelectorExpr // nil when the handler can't be resolved
+	pos     token.Position
+}
+
+// parseRoutes reads the routes registered by setupRoutes in cmd/main.go.
+// Group prefixes are followed through variables, inline func handlers
+// are not part of the documented API and are skipped.
+func parseRoutes(fset *token.FileSet, mainFile string) ([]route, error) {
+	file, err := parser.ParseFile(fset, mainFile, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var setup *ast.FuncDecl
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Name.Name == "setupRoutes" {
+			setup = fn
+		}
+	}
+	if setup == nil {
+		return nil, nil
+	}
+
+	prefixes := map[string]string{}
+	var routes []route
+
+	ast.Inspect(setup.Body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			// users := api.Group("/users")
+			if len(n.Lhs) != 1 || len(n.Rhs) != 1 {
+				return true
+			}
+			name, ok := n.Lhs[0].(*ast.Ident)
+			call, isCall := n.Rhs[0].(*ast.CallExpr)
+			if !ok || !isCall {
+				return true
+			}
+			if recv, method, ok := methodCall(call); ok && method == "Group" && len(call.Args) > 0 {
+				if prefix, ok := stringLit(call.Args[0]); ok {
+					prefixes[name.Name] = prefixes[recv] + prefix
+				}
+			}
+		case *ast.CallExpr:
+			// users.Get("/:userId", handlers.GetUser)
+			recv, method, ok := methodCall(n)
+			if !ok || !routeMethods[method] || len(n.Args) < 2 {
+				return true
+			}
+			path, ok := stringLit(n.Args[0])
+			if !ok {
+				return true
+			}
+			last := n.Args[len(n.Args)-1]
+			if _, inline := last.(*ast.FuncLit); inline {
+				return true
+			}
+			routes = append(routes, route{
+				method:  strings.ToLower(method),
+				path:    openAPIPath(prefixes[recv] + path),
+				handler: findHandler(last),
+				pos:     fset.Position(n.Pos()),
+			})
+		}
+		return true
+	})
+
+	return routes, nil
+}
+
+// methodCall splits recv.Method(...) calls
+func methodCall(call *ast.CallExpr) (recv, method string, ok bool) {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return "", "", false
+	}
+	ident, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return "", "", false
+	}
+	return ident.Name, sel.Sel.Name, true
+}
+
+// findHandler returns the pkg.Func (or value.Method) expression of a
+// handler, looking through wrappers such as websocket.New(handlers.Events)
+func findHandler(expr ast.Expr) *ast.SelectorExpr {
+	switch expr := expr.(type) {
+	case *ast.SelectorExpr:
+		return expr
+	case *ast.CallExpr:
+		for _, arg := range expr.Args {
+			if sel := findHandler(arg); sel != nil {
+				return sel
+			}
+		}
+	}
+	return nil
+}
+
+func stringLit(expr ast.Expr) (string, bool) {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	value, err := strconv.Unquote(lit.Value)
+	return value, err == nil
+}
+
+// openAPIPath turns a fiber path into an OpenAPI one: /tasks/:taskId/ -> /tasks/{taskId}
+func openAPIPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			segments[i] = "{" + strings.TrimSuffix(segment[1:], "?") + "}"
+		}
+	}
+	path = strings.Join(segments, "/")
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+	return path
+}
@@ -0,0 +1,198 @@
+package gen
+
+import (
+	"go/ast"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/cmerin0/tasky/internal/openapi"
+)
+
+// objectIDPattern matches the hex form of a MongoDB ObjectID
+const objectIDPattern = "^[0-9a-fA-F]{24}$"
+
+// typeSchema returns the schema of a type named in an annotation,
+// such as int, models.Task, []models.Task or a type of the handler package
+func (g *generator) typeSchema(pkg *sourcePackage, typ string) *openapi.Schema {
+	if strings.HasPrefix(typ, "[]") {
+		return &openapi.Schema{Type: "array", Items: g.typeSchema(pkg, typ[2:])}
+	}
+
+	switch typ {
+	case "string":
+		return &openapi.Schema{Type: "string"}
+	case "int", "integer":
+		return &openapi.Schema{Type: "integer"}
+	case "number":
+		return &openapi.Schema{Type: "number"}
+	case "bool", "boolean":
+		return &openapi.Schema{Type: "boolean"}
+	case "object", "fiber.Map":
+		return &openapi.Schema{Type: "object"}
+	case "any":
+		return &openapi.Schema{}
+	}
+
+	pkgName, name, qualified := strings.Cut(typ, ".")
+	if !qualified {
+		pkgName, name = pkg.name, typ
+	}
+	return g.namedSchema(pkgName, name)
+}
+
+// namedSchema returns a reference to the component schema of a named type,
+// adding the component the first time the type is seen
+func (g *generator) namedSchema(pkgName, name string) *openapi.Schema {
+	if schema := knownSchema(pkgName, name); schema != nil {
+		return schema
+	}
+
+	pkg, ok := g.packages[pkgName]
+	if !ok || pkg.types[name] == nil {
+		return &openapi.Schema{Type: "object"}
+	}
+
+	key := pkgName + "." + name
+	ref := &openapi.Schema{Ref: "#/components/schemas/" + key}
+	if _, done := g.doc.Components.Schemas[key]; done {
+		return ref
+	}
+
+	spec := pkg.types[name]
+	if _, isStruct := spec.Type.(*ast.StructType); !isStruct {
+		// Named basic types, e.g. type Op string, are inlined
+		return g.exprSchema(pkg, spec.Type)
+	}
+
+	// Reserve the name first so self references terminate
+	g.doc.Components.Schemas[key] = &openapi.Schema{}
+	*g.doc.Components.Schemas[key] = *g.structSchema(pkg, spec.Type.(*ast.StructType))
+	return ref
+}
+
+// knownSchema maps types from dependencies to their JSON representation
+func knownSchema(pkgName, name string) *openapi.Schema {
+	switch pkgName + "." + name {
+	case "primitive.ObjectID":
+		return &openapi.Schema{Type: "string", Pattern: objectIDPattern}
+	case "time.Time", "primitive.DateTime":
+		return &openapi.Schema{Type: "string", Format: "date-time"}
+	case "fiber.Map", "bson.M", "json.RawMessage":
+		return &openapi.Schema{Type: "object"}
+	}
+	return nil
+}
+
+// structSchema builds an object schema from a struct's json and validate tags
+func (g *generator) structSchema(pkg *sourcePackage, st *ast.StructType) *openapi.Schema {
+	schema := &openapi.Schema{Type: "object", Properties: map[string]*openapi.Schema{}}
+
+	for _, field := range st.Fields.List {
+		tag := reflect.StructTag("")
+		if field.Tag != nil {
+			raw, _ := strconv.Unquote(field.Tag.Value)
+			tag = reflect.StructTag(raw)
+		}
+		jsonName := strings.Split(tag.Get("json"), ",")[0]
+		if jsonName == "-" {
+			continue
+		}
+
+		// Embedded structs without a JSON name are flattened, like encoding/json does
+		if len(field.Names) == 0 && jsonName == "" {
+			embedded := g.exprSchema(pkg, field.Type)
+			if embedded.Ref != "" {
+				embedded = g.doc.Components.Schemas[strings.TrimPrefix(embedded.Ref, "#/components/schemas/")]
+			}
+			for name, property := range embedded.Properties {
+				schema.Properties[name] = property
+			}
+			schema.Required = append(schema.Required, embedded.Required...)
+			continue
+		}
+
+		names := []string{jsonName}
+		if jsonName == "" {
+			names = names[:0]
+			for _, ident := range field.Names {
+				names = append(names, ident.Name)
+			}
+		}
+
+		for _, name := range names {
+			if !ast.IsExported(name) && jsonName == "" {
+				continue
+			}
+			property := g.exprSchema(pkg, field.Type)
+			rules := strings.Split(tag.Get("validate"), ",")
+			// Rules after dive apply to the items of a slice
+			target := property
+			for _, rule := range rules {
+				switch rule {
+				case "required":
+					if target == property {
+						schema.Required = append(schema.Required, name)
+					}
+				case "email":
+					target.Format = "email"
+				case "url", "http_url":
+					target.Format = "uri"
+				case "dive":
+					if target.Items != nil {
+						target = target.Items
+					}
+				}
+				if values, ok := strings.CutPrefix(rule, "oneof="); ok {
+					target.Enum = strings.Fields(values)
+				}
+			}
+			schema.Properties[name] = property
+		}
+	}
+
+	return schema
+}
+
+// exprSchema returns the schema of a Go type expression
+func (g *generator) exprSchema(pkg *sourcePackage, expr ast.Expr) *openapi.Schema {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		switch expr.Name {
+		case "string":
+			return &openapi.Schema{Type: "string"}
+		case "bool":
+			return &openapi.Schema{Type: "boolean"}
+		case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64":
+			return &openapi.Schema{Type: "integer"}
+		case "float32", "float64":
+			return &openapi.Schema{Type: "number"}
+		case "any":
+			return &openapi.Schema{}
+		}
+		return g.namedSchema(pkg.name, expr.Name)
+	case *ast.SelectorExpr:
+		if ident, ok := expr.X.(*ast.Ident); ok {
+			return g.namedSchema(ident.Name, expr.Sel.Name)
+		}
+	case *ast.StarExpr:
+		schema := g.exprSchema(pkg, expr.X)
+		if schema.Ref == "" {
+			schema.Nullable = true
+		}
+		return schema
+	case *ast.ArrayType:
+		// Nil slices and maps are encoded as null
+		if ident, ok := expr.Elt.(*ast.Ident); ok && ident.Name == "byte" {
+			return &openapi.Schema{Type: "string", Format: "byte", Nullable: true}
+		}
+		return &openapi.Schema{Type: "array", Items: g.exprSchema(pkg, expr.Elt), Nullable: true}
+	case *ast.MapType:
+		return &openapi.Schema{Type: "object", AdditionalProperties: g.exprSchema(pkg, expr.Value), Nullable: true}
+	case *ast.InterfaceType:
+		return &openapi.Schema{}
+	case *ast.StructType:
+		return g.structSchema(pkg, expr)
+	}
+	return &openapi.Schema{Type: "object"}
+}
@@ -0,0 +1,74 @@
+// Package openapi serves the OpenAPI document of the tasky API and a
+// Swagger UI to browse it. The document is generated from setupRoutes and
+// the annotations on the handlers, run go generate after changing either.
+package openapi
+
+import (
+	"embed"
+	"encoding/json"
+	"path"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+//go:generate go run ../../cmd/openapi-gen
+
+//go:embed openapi.json
+var specJSON []byte
+
+//go:embed swagger.html
+var swaggerHTML []byte
+
+// swaggerUI holds the vendored Swagger UI files, see swagger-ui/README.md
+//
+//go:embed swagger-ui/*.js swagger-ui/*.css swagger-ui/*.png
+var swaggerUI embed.FS
+
+// Load returns the parsed OpenAPI document
+func Load() (*Document, error) {
+	var doc Document
+	if err := json.Unmarshal(specJSON, &doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// Spec serves the OpenAPI document
+// @Summary OpenAPI document
+// @Description Get the OpenAPI 3 document describing this API
+// @Success 200 {object} fiber.Map
+// @Router /openapi.json [get]
+func Spec(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSONCharsetUTF8)
+	return c.Send(specJSON)
+}
+
+// SwaggerUI serves a Swagger UI page for the OpenAPI document
+// @Summary API documentation
+// @Description Browse the API documentation in Swagger UI
+// @Produce html
+// @Success 200 {string} string
+// @Router /docs [get]
+func SwaggerUI(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return c.Send(swaggerHTML)
+}
+
+// SwaggerAsset serves the scripts, stylesheet and icons of the Swagger UI page
+// @Summary API documentation assets
+// @Description Get a file of the embedded Swagger UI
+// @Param asset path string true "File name, such as swagger-ui-bundle.js"
+// @Produce text/javascript,text/css,image/png
+// @Success 200 {string} string
+// @Failure 404 Not Found
+// @Router /docs/{asset} [get]
+func SwaggerAsset(c *fiber.Ctx) error {
+	name := c.Params("asset")
+	content, err := swaggerUI.ReadFile(path.Join("swagger-ui", name))
+	if err != nil || path.Base(name) != name {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Not found"})
+	}
+	c.Type(path.Ext(name))
+	c.Set(fiber.HeaderCacheControl, "public, max-age=86400")
+	return c.Send(content)
+}
@@ -0,0 +1,37 @@
+package openapi_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cmerin0/tasky/internal/openapi/gen"
+)
+
+// root is the module root, relative to this package
+const root = "../.."
+
+func TestRoutesAreDocumented(t *testing.T) {
+	_, problems, err := gen.Render(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, problem := range problems {
+		t.Error(problem)
+	}
+}
+
+func TestSpecIsUpToDate(t *testing.T) {
+	out, _, err := gen.Render(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, err := os.ReadFile(filepath.Join(root, gen.SpecFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(current, out) {
+		t.Fatal(gen.SpecFile + " is out of date, run go generate ./internal/openapi")
+	}
+}
@@ -0,0 +1,8 @@
+// Loads the tasky API document, kept out of swagger.html so the page needs
+// no inline script
+window.onload = () => {
+  window.ui = SwaggerUIBundle({
+    url: "/api/v1/openapi.json",
+    dom_id: "#swagger-ui",
+  });
+};
@@ -0,0 +1,359 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Violation is a mismatch between a request or response and the document.
+// In is where it was found (path, query, header, body, status or response),
+// Name the parameter or a JSON pointer into the body.
+type Violation struct {
+	In      string `json:"in"`
+	Name    string `json:"name,omitempty"`
+	Message string `json:"message"`
+}
+
+func (v Violation) String() string {
+	if v.Name == "" {
+		return v.In + ": " + v.Message
+	}
+	return v.In + " " + v.Name + ": " + v.Message
+}
+
+// Request is the part of an HTTP request checked against the document
+type Request struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Header http.Header
+	Body   []byte
+}
+
+// patterns caches compiled schema patterns
+var patterns sync.Map
+
+// FindOperation returns the operation serving method and path along with
+// the path parameters. Literal segments win over parameters, so
+// /tasks/bulk is preferred to /tasks/{taskId}.
+func (d *Document) FindOperation(method, path string) (*Operation, map[string]string) {
+	segments := splitPath(path)
+	method = strings.ToLower(method)
+
+	var (
+		found        *Operation
+		foundParams  map[string]string
+		bestLiterals = -1
+	)
+	for template, item := range d.Paths {
+		op := (*item)[method]
+		if op == nil {
+			continue
+		}
+		params, literals, ok := matchPath(splitPath(template), segments)
+		if ok && literals > bestLiterals {
+			found, foundParams, bestLiterals = op, params, literals
+		}
+	}
+	return found, foundParams
+}
+
+func splitPath(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
+
+func matchPath(template, segments []string) (map[string]string, int, bool) {
+	if len(template) != len(segments) {
+		return nil, 0, false
+	}
+	params := map[string]string{}
+	literals := 0
+	for i, segment := range template {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			value, err := url.PathUnescape(segments[i])
+			if err != nil || value == "" {
+				return nil, 0, false
+			}
+			params[segment[1:len(segment)-1]] = value
+			continue
+		}
+		if segment != segments[i] {
+			return nil, 0, false
+		}
+		literals++
+	}
+	return params, literals, true
+}
+
+// ValidateRequest checks the parameters and body of a request against its
+// operation. Requests the document doesn't describe are not checked.
+func (d *Document) ValidateRequest(r Request) []Violation {
+	op, pathParams := d.FindOperation(r.Method, r.Path)
+	if op == nil {
+		return nil
+	}
+
+	var violations []Violation
+	for _, param := range op.Parameters {
+		var (
+			value   string
+			present bool
+		)
+		switch param.In {
+		case "path":
+			value, present = pathParams[param.Name]
+		case "query":
+			present = r.Query.Has(param.Name)
+			value = r.Query.Get(param.Name)
+		case "header":
+			value = r.Header.Get(param.Name)
+			present = value != ""
+		}
+
+		if !present {
+			if param.Required {
+				violations = append(violations, Violation{In: param.In, Name: param.Name, Message: "is required"})
+			}
+			continue
+		}
+		if message := d.checkParam(param.Schema, value); message != "" {
+			violations = append(violations, Violation{In: param.In, Name: param.Name, Message: message})
+		}
+	}
+
+	if op.RequestBody != nil {
+		violations = append(violations, d.checkBody(op.RequestBody, r)...)
+	}
+	return violations
+}
+
+// checkBody checks a request body against the documented content types
+func (d *Document) checkBody(body *RequestBody, r Request) []Violation {
+	if len(bytes.TrimSpace(r.Body)) == 0 {
+		if body.Required {
+			return []Violation{{In: "body", Message: "is required"}}
+		}
+		return nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	content, ok := body.Content[mediaType]
+	if !ok {
+		return []Violation{{In: "header", Name: "Content-Type", Message: fmt.Sprintf("unsupported content type %q", mediaType)}}
+	}
+	return d.checkJSON("body", content.Schema, r.Body)
+}
+
+// ValidateResponse checks a response of the operation serving method and path.
+// Undocumented error statuses are accepted, undocumented successes are not.
+func (d *Document) ValidateResponse(method, path string, status int, contentType string, body []byte) []Violation {
+	op, _ := d.FindOperation(method, path)
+	if op == nil {
+		return nil
+	}
+
+	response, ok := op.Responses[strconv.Itoa(status)]
+	if !ok {
+		if status >= http.StatusBadRequest {
+			return nil
+		}
+		return []Violation{{In: "status", Message: fmt.Sprintf("status %d is not documented", status)}}
+	}
+	if len(response.Content) == 0 || len(body) == 0 {
+		return nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	content, ok := response.Content[mediaType]
+	if !ok {
+		return []Violation{{In: "response", Name: "Content-Type", Message: fmt.Sprintf("undocumented content type %q", mediaType)}}
+	}
+	if !isJSON(mediaType) {
+		return nil
+	}
+	return d.checkJSON("response", content.Schema, body)
+}
+
+func isJSON(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// checkJSON decodes a JSON document and checks it against schema
+func (d *Document) checkJSON(in string, schema *Schema, data []byte) []Violation {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return []Violation{{In: in, Message: "invalid JSON: " + err.Error()}}
+	}
+
+	var violations []Violation
+	d.checkValue(schema, value, "", func(pointer, message string) {
+		violations = append(violations, Violation{In: in, Name: pointer, Message: message})
+	})
+	return violations
+}
+
+// resolve follows a $ref to its component schema
+func (d *Document) resolve(schema *Schema) *Schema {
+	for schema != nil && schema.Ref != "" {
+		schema = d.Components.Schemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]
+	}
+	return schema
+}
+
+// checkValue checks a decoded JSON value, reporting mismatches by JSON pointer
+func (d *Document) checkValue(schema *Schema, value interface{}, pointer string, report func(pointer, message string)) {
+	schema = d.resolve(schema)
+	if schema == nil || schema.Type == "" {
+		return
+	}
+	if value == nil {
+		if !schema.Nullable {
+			report(pointer, "must not be null")
+		}
+		return
+	}
+
+	switch schema.Type {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			report(pointer, "must be an object")
+			return
+		}
+		for _, name := range schema.Required {
+			if _, ok := object[name]; !ok {
+				report(pointer+"/"+escapePointer(name), "is required")
+			}
+		}
+		names := make([]string, 0, len(object))
+		for name := range object {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			property := object[name]
+			if propertySchema, ok := schema.Properties[name]; ok {
+				d.checkValue(propertySchema, property, pointer+"/"+escapePointer(name), report)
+			} else if schema.AdditionalProperties != nil {
+				d.checkValue(schema.AdditionalProperties, property, pointer+"/"+escapePointer(name), report)
+			}
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			report(pointer, "must be an array")
+			return
+		}
+		for i, item := range items {
+			d.checkValue(schema.Items, item, pointer+"/"+strconv.Itoa(i), report)
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			report(pointer, "must be a string")
+			return
+		}
+		if message := checkString(schema, s); message != "" {
+			report(pointer, message)
+		}
+	case "integer":
+		number, ok := value.(json.Number)
+		if _, err := number.Int64(); !ok || err != nil {
+			report(pointer, "must be an integer")
+		}
+	case "number":
+		if _, ok := value.(json.Number); !ok {
+			report(pointer, "must be a number")
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			report(pointer, "must be a boolean")
+		}
+	}
+}
+
+// checkParam checks the raw value of a path, query or header parameter
+func (d *Document) checkParam(schema *Schema, value string) string {
+	schema = d.resolve(schema)
+	if schema == nil {
+		return ""
+	}
+	switch schema.Type {
+	case "integer":
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return "must be an integer"
+		}
+	case "number":
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return "must be a number"
+		}
+	case "boolean":
+		if _, err := strconv.ParseBool(value); err != nil {
+			return "must be a boolean"
+		}
+	case "string":
+		return checkString(schema, value)
+	}
+	return ""
+}
+
+// checkString checks the format and pattern of a string
+func checkString(schema *Schema, s string) string {
+	if len(schema.Enum) > 0 && !slices.Contains(schema.Enum, s) {
+		return "must be one of " + strings.Join(schema.Enum, ", ")
+	}
+
+	switch schema.Format {
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, s); err != nil {
+			return "must be an RFC 3339 date-time"
+		}
+	case "email":
+		if _, err := mail.ParseAddress(s); err != nil {
+			return "must be an email address"
+		}
+	case "uri":
+		if u, err := url.Parse(s); err != nil || !u.IsAbs() {
+			return "must be an absolute URI"
+		}
+	case "byte":
+		if _, err := base64.StdEncoding.DecodeString(s); err != nil {
+			return "must be base64 encoded"
+		}
+	}
+
+	if schema.Pattern != "" {
+		compiled, ok := patterns.Load(schema.Pattern)
+		if !ok {
+			re, err := regexp.Compile(schema.Pattern)
+			if err != nil {
+				return ""
+			}
+			compiled, _ = patterns.LoadOrStore(schema.Pattern, re)
+		}
+		if !compiled.(*regexp.Regexp).MatchString(s) {
+			return "must match " + schema.Pattern
+		}
+	}
+	return ""
+}
+
+// escapePointer escapes a property name for use in a JSON pointer (RFC 6901)
+func escapePointer(name string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(name, "~", "~0"), "/", "~1")
+}
@@ -0,0 +1,65 @@
+// Package outbox relays the events mutations write to the outbox.
+//
+// Events are written in the same transaction as the change they describe,
+// so an event exists if and only if its change was committed. The relay
+// hands each event to every handler, then marks it dispatched. An event is
+// claimed with a lease: if a handler fails or the app crashes, the event is
+// handed out again once the lease expires, so handlers must be idempotent.
+package outbox
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/cmerin0/tasky/internal/models"
+	"github.com/cmerin0/tasky/internal/store"
+	"github.com/cmerin0/tasky/internal/worker"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	pollInterval  = time.Second      // How often an idle relay looks for events
+	leaseDuration = 30 * time.Second // How long a claimed event is reserved
+)
+
+// Handler acts on an outbox event
+type Handler func(ctx context.Context, event *models.OutboxEvent) error
+
+// Run relays events to the handlers until ctx is done
+func Run(ctx context.Context, handlers ...Handler) {
+	worker.Poll(ctx, pollInterval, "relaying outbox events", func(ctx context.Context) (bool, error) {
+		return relayNext(ctx, handlers)
+	})
+}
+
+// relayNext claims the oldest pending event and hands it to the handlers.
+// It reports whether an event was relayed.
+func relayNext(ctx context.Context, handlers []Handler) (bool, error) {
+	now := time.Now().UTC()
+
+	var event models.OutboxEvent
+	err := store.Outbox().FindOneAndUpdate(ctx,
+		bson.M{"dispatchedAt": nil, "lockedUntil": bson.M{"$lte": now}},
+		bson.M{"$set": bson.M{"lockedUntil": now.Add(leaseDuration)}},
+		options.FindOneAndUpdate().SetSort(bson.D{{Key: "_id", Value: 1}}),
+	).Decode(&event)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	for _, handle := range handlers {
+		if err := handle(ctx, &event); err != nil {
+			return false, err
+		}
+	}
+
+	_, err = store.Outbox().UpdateOne(ctx, bson.M{"_id": event.ID}, bson.M{"$set": bson.M{"dispatchedAt": time.Now().UTC()}})
+	return err == nil, err
+}
@@ -0,0 +1,161 @@
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// equalJSON reports whether two JSON documents hold the same value
+func equalJSON(t *testing.T, got []byte, want string) bool {
+	t.Helper()
+	var g, w interface{}
+	if err := json.Unmarshal(got, &g); err != nil {
+		t.Fatalf("result %s: %v", got, err)
+	}
+	if err := json.Unmarshal([]byte(want), &w); err != nil {
+		t.Fatalf("expected %s: %v", want, err)
+	}
+	return reflect.DeepEqual(g, w)
+}
+
+// The examples of RFC 7396 appendix A
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		doc, patch, want string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+		// Removing a member that isn't there is not an error
+		{`{"a":"b"}`, `{"missing":null}`, `{"a":"b"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.doc+" "+tt.patch, func(t *testing.T) {
+			got, err := MergePatch([]byte(tt.doc), []byte(tt.patch))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !equalJSON(t, got, tt.want) {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergePatchInvalid(t *testing.T) {
+	if _, err := MergePatch([]byte(`{"a":`), []byte(`{}`)); err == nil || !strings.HasPrefix(err.Error(), "invalid document") {
+		t.Errorf("got error %v for an invalid document", err)
+	}
+	if _, err := MergePatch([]byte(`{}`), []byte(`{"a"}`)); err == nil || !strings.HasPrefix(err.Error(), "invalid merge patch") {
+		t.Errorf("got error %v for an invalid patch", err)
+	}
+}
+
+// The examples of RFC 6902 appendix A which succeed
+func TestJSONPatch(t *testing.T) {
+	tests := []struct {
+		name, doc, patch, want string
+	}{
+		{"add member", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`},
+		{"add array element", `{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+		{"remove member", `{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+		{"remove array element", `{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+		{"replace", `{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+		{
+			"move member",
+			`{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+			`[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			`{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`,
+		},
+		{"move array element", `{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`},
+		{"test", `{"baz":"qux","foo":["a",2,"c"]}`, `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`, `{"baz":"qux","foo":["a",2,"c"]}`},
+		{"add nested member", `{"foo":"bar"}`, `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`, `{"foo":"bar","child":{"grandchild":{}}}`},
+		{"ignore unknown members", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux","xyz":123}]`, `{"foo":"bar","baz":"qux"}`},
+		{"escaped pointer", `{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":10}]`, `{"/":9,"~1":10}`},
+		{"add array value", `{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, `{"foo":["bar",["abc","def"]]}`},
+		{"append to array", `{"foo":[1,2]}`, `[{"op":"add","path":"/foo/-","value":3}]`, `{"foo":[1,2,3]}`},
+		{"add at the end index", `{"foo":[1,2]}`, `[{"op":"add","path":"/foo/2","value":3}]`, `{"foo":[1,2,3]}`},
+		{"replace whole document", `{"foo":"bar"}`, `[{"op":"add","path":"","value":[1]}]`, `[1]`},
+		{
+			"copy is independent",
+			`{"a":{"b":1}}`,
+			`[{"op":"copy","from":"/a","path":"/c"},{"op":"replace","path":"/c/b","value":2}]`,
+			`{"a":{"b":1},"c":{"b":2}}`,
+		},
+		{"null value", `{}`, `[{"op":"add","path":"/a","value":null},{"op":"test","path":"/a","value":null}]`, `{"a":null}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := JSONPatch([]byte(tt.doc), []byte(tt.patch))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !equalJSON(t, got, tt.want) {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONPatchErrors(t *testing.T) {
+	tests := []struct {
+		name, doc, patch, err string
+	}{
+		{"test value", `{"baz":"qux"}`, `[{"op":"test","path":"/baz","value":"bar"}]`, "operation 0 (test /baz): test operation failed"},
+		{"test string against number", `{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":"10"}]`, "operation 0 (test /~01): test operation failed"},
+		{"test missing member", `{}`, `[{"op":"test","path":"/a","value":1}]`, `operation 0 (test /a): path member "a" not found`},
+		{"add to missing parent", `{"foo":"bar"}`, `[{"op":"add","path":"/baz/bat","value":"qux"}]`, `operation 0 (add /baz/bat): path member "baz" not found`},
+		{"remove missing member", `{"foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `operation 0 (remove /baz): path member "baz" not found`},
+		{"replace missing member", `{"foo":"bar"}`, `[{"op":"replace","path":"/baz","value":1}]`, `operation 0 (replace /baz): path member "baz" not found`},
+		{"move missing member", `{"foo":"bar"}`, `[{"op":"move","from":"/baz","path":"/qux"}]`, `operation 0 (move /qux): path member "baz" not found`},
+		{"move into child", `{"a":{"b":{}}}`, `[{"op":"move","from":"/a","path":"/a/b/c"}]`, "operation 0 (move /a/b/c): cannot move a value into one of its children"},
+		{"copy missing member", `{"foo":"bar"}`, `[{"op":"copy","from":"/baz","path":"/qux"}]`, `operation 0 (copy /qux): path member "baz" not found`},
+		{"copy bad from", `{"foo":"bar"}`, `[{"op":"copy","from":"foo","path":"/qux"}]`, `operation 0 (copy /qux): invalid JSON pointer "foo"`},
+		{"add out of range", `{"foo":[1,2]}`, `[{"op":"add","path":"/foo/3","value":3}]`, "operation 0 (add /foo/3): array index 3 out of range"},
+		{"replace out of range", `{"foo":[1,2]}`, `[{"op":"replace","path":"/foo/2","value":3}]`, "operation 0 (replace /foo/2): array index 2 out of range"},
+		{"remove out of range", `{"foo":[]}`, `[{"op":"remove","path":"/foo/0"}]`, "operation 0 (remove /foo/0): array index 0 out of range"},
+		{"non-numeric index", `{"foo":[1]}`, `[{"op":"add","path":"/foo/bar","value":3}]`, `operation 0 (add /foo/bar): invalid array index "bar"`},
+		{"negative index", `{"foo":[1]}`, `[{"op":"remove","path":"/foo/-1"}]`, `operation 0 (remove /foo/-1): invalid array index "-1"`},
+		{"leading zero", `{"foo":[1,2]}`, `[{"op":"test","path":"/foo/01","value":2}]`, `operation 0 (test /foo/01): invalid array index "01"`},
+		{"dash outside add", `{"foo":[1]}`, `[{"op":"remove","path":"/foo/-"}]`, `operation 0 (remove /foo/-): invalid array index "-"`},
+		{"add to a scalar", `{"foo":1}`, `[{"op":"add","path":"/foo/bar","value":3}]`, `operation 0 (add /foo/bar): cannot add member "bar" to a scalar`},
+		{"missing value", `{}`, `[{"op":"add","path":"/a"}]`, "operation 0 (add /a): missing value"},
+		{"unknown op", `{}`, `[{"op":"merge","path":"/a","value":1}]`, `operation 0 (merge /a): unknown operation "merge"`},
+		{"remove document", `{}`, `[{"op":"remove","path":""}]`, "operation 0 (remove ): cannot remove the whole document"},
+		{"fails as a whole", `{"a":1}`, `[{"op":"remove","path":"/a"},{"op":"test","path":"/a","value":1}]`, `operation 1 (test /a): path member "a" not found`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := JSONPatch([]byte(tt.doc), []byte(tt.patch))
+			if err == nil {
+				t.Fatalf("got %s, want error %q", got, tt.err)
+			}
+			if err.Error() != tt.err {
+				t.Errorf("got error %q, want %q", err, tt.err)
+			}
+		})
+	}
+
+	_, err := JSONPatch([]byte(`{"baz":"qux"}`), []byte(`[{"op":"test","path":"/baz","value":"bar"}]`))
+	if !errors.Is(err, ErrTestFailed) {
+		t.Errorf("got error %v, want %v", err, ErrTestFailed)
+	}
+}
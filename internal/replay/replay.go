@@ -0,0 +1,168 @@
+// Package replay sends example requests to the API and checks every
+// exchange against the OpenAPI document: requests must conform, responses
+// must have the expected status and conform too.
+//
+// Examples run in order and can capture values from a response body
+// (dotted JSON path) or header into variables used as {{name}} by later
+// examples. {{run}} is unique per run, e.g. for email addresses.
+package replay
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cmerin0/tasky/internal/openapi"
+)
+
+//go:embed examples.json
+var defaultExamples []byte
+
+// Example is a request to replay and what to expect back
+type Example struct {
+	Name           string            `json:"name"`
+	Method         string            `json:"method"`
+	Path           string            `json:"path"`
+	Headers        map[string]string `json:"headers,omitempty"`
+	Body           json.RawMessage   `json:"body,omitempty"`
+	Status         int               `json:"status"`
+	Invalid        bool              `json:"invalid,omitempty"` // The request deliberately breaks the spec
+	Capture        map[string]string `json:"capture,omitempty"`
+	CaptureHeaders map[string]string `json:"captureHeaders,omitempty"`
+}
+
+var placeholder = regexp.MustCompile(`\{\{(\w+)\}\}`)
+
+// Examples parses a list of examples, the built in ones when data is nil
+func Examples(data []byte) ([]Example, error) {
+	if data == nil {
+		data = defaultExamples
+	}
+	var examples []Example
+	if err := json.Unmarshal(data, &examples); err != nil {
+		return nil, err
+	}
+	return examples, nil
+}
+
+// Vars returns the variables a run starts with
+func Vars() map[string]string {
+	return map[string]string{"run": strconv.FormatInt(time.Now().UnixNano(), 36)}
+}
+
+// Replay sends a single example to the server at base and returns what
+// went wrong. Captured values are added to vars.
+func Replay(client *http.Client, doc *openapi.Document, base string, example Example, vars map[string]string) []string {
+	expand := func(s string) string {
+		return placeholder.ReplaceAllStringFunc(s, func(match string) string {
+			if value, ok := vars[match[2:len(match)-2]]; ok {
+				return value
+			}
+			return match
+		})
+	}
+
+	path := expand(example.Path)
+	body := []byte(expand(string(example.Body)))
+	req, err := http.NewRequest(example.Method, base+path, bytes.NewReader(body))
+	if err != nil {
+		return []string{err.Error()}
+	}
+	if len(body) > 0 {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range example.Headers {
+		req.Header.Set(name, expand(value))
+	}
+
+	var problems []string
+	violations := doc.ValidateRequest(openapi.Request{
+		Method: req.Method,
+		Path:   req.URL.Path,
+		Query:  req.URL.Query(),
+		Header: req.Header,
+		Body:   body,
+	})
+	if !example.Invalid {
+		for _, violation := range violations {
+			problems = append(problems, "request "+violation.String())
+		}
+	} else if len(violations) == 0 {
+		problems = append(problems, "request is marked invalid but conforms to the spec")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return append(problems, err.Error())
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return append(problems, err.Error())
+	}
+
+	if resp.StatusCode != example.Status {
+		problems = append(problems, fmt.Sprintf("status %d, expected %d: %s", resp.StatusCode, example.Status, truncate(respBody)))
+	}
+	for _, violation := range doc.ValidateResponse(req.Method, req.URL.Path, resp.StatusCode, resp.Header.Get("Content-Type"), respBody) {
+		problems = append(problems, "response "+violation.String())
+	}
+
+	for name, header := range example.CaptureHeaders {
+		vars[name] = resp.Header.Get(header)
+	}
+	for name, field := range example.Capture {
+		value, err := lookup(respBody, field)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("capture %s: %v", name, err))
+			continue
+		}
+		vars[name] = value
+	}
+	return problems
+}
+
+// lookup returns the value at a dotted path of a JSON document, e.g. tasks.0.id
+func lookup(data []byte, field string) (string, error) {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return "", err
+	}
+	for _, key := range strings.Split(field, ".") {
+		switch node := value.(type) {
+		case map[string]interface{}:
+			value = node[key]
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return "", fmt.Errorf("no element %s", key)
+			}
+			value = node[i]
+		default:
+			return "", errors.New("no field " + field)
+		}
+	}
+	switch value := value.(type) {
+	case nil:
+		return "", errors.New("no field " + field)
+	case string:
+		return value, nil
+	default:
+		return fmt.Sprint(value), nil
+	}
+}
+
+func truncate(body []byte) string {
+	if len(body) > 200 {
+		return string(body[:200]) + "..."
+	}
+	return string(body)
+}
@@ -0,0 +1,159 @@
+package rpc
+
+import (
+	"context"
+
+	"github.com/cmerin0/tasky/internal/filter"
+	"github.com/cmerin0/tasky/internal/models"
+	"github.com/cmerin0/tasky/internal/rpc/taskyv1"
+	"github.com/cmerin0/tasky/internal/store"
+
+	"github.com/gofiber/fiber/v2/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// taskService implements taskyv1.TaskServiceServer
+type taskService struct {
+	taskyv1.UnimplementedTaskServiceServer
+
+	tasks store.TaskRepository
+}
+
+func (s *taskService) ListTasks(ctx context.Context, in *taskyv1.ListTasksRequest) (*taskyv1.ListTasksResponse, error) {
+	req, err := pageRequest(in.GetLimit(), in.GetCursor(), in.GetSort(), in.GetCount(), store.TaskSortFields)
+	if err != nil {
+		return nil, err
+	}
+
+	expr, err := filter.Parse(in.GetFilter(), store.TaskFilterFields)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "Invalid filter: "+err.Error())
+	}
+	if in.GetUserId() != "" {
+		userId, err := parseID("user_id", in.GetUserId())
+		if err != nil {
+			return nil, err
+		}
+		expr = filter.AllOf(store.FieldIs(store.TaskFilterFields, "userId", userId), expr)
+	}
+
+	page, err := s.tasks.ListTasks(ctx, expr, req, nil)
+	if err != nil {
+		log.Error("Error fetching tasks: ", err)
+		return nil, statusError(err, "Task not found")
+	}
+
+	out := &taskyv1.ListTasksResponse{
+		Tasks:      make([]*taskyv1.Task, len(page.Items)),
+		NextCursor: cursorToken(page.Next),
+		PrevCursor: cursorToken(page.Prev),
+		Total:      page.Total,
+	}
+	for i := range page.Items {
+		out.Tasks[i] = taskMessage(&page.Items[i])
+	}
+	return out, nil
+}
+
+func (s *taskService) GetTask(ctx context.Context, in *taskyv1.GetTaskRequest) (*taskyv1.Task, error) {
+	id, err := parseID("id", in.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	task, err := s.tasks.GetTask(ctx, id)
+	if err != nil {
+		return nil, statusError(err, "Task not found")
+	}
+	return taskMessage(task), nil
+}
+
+func (s *taskService) CreateTask(ctx context.Context, in *taskyv1.CreateTaskRequest) (*taskyv1.Task, error) {
+	input, err := taskModel(in.GetTask())
+	if err != nil {
+		return nil, err
+	}
+
+	task, err := s.tasks.CreateTask(ctx, input, callActor(ctx))
+	if err != nil {
+		log.Error("Error creating task: ", err)
+		return nil, statusError(err, "Task not found")
+	}
+
+	log.Info("Task created successfully")
+	return taskMessage(task), nil
+}
+
+func (s *taskService) UpdateTask(ctx context.Context, in *taskyv1.UpdateTaskRequest) (*taskyv1.Task, error) {
+	id, err := parseID("id", in.GetId())
+	if err != nil {
+		return nil, err
+	}
+	input, err := taskModel(in.GetTask())
+	if err != nil {
+		return nil, err
+	}
+
+	task, err := s.tasks.ReplaceTask(ctx, id, input, versions(in.Version), callActor(ctx))
+	if err != nil {
+		log.Error("Error updating task: ", err)
+		return nil, statusError(err, "Task not found")
+	}
+
+	log.Info("Task updated successfully")
+	return taskMessage(task), nil
+}
+
+func (s *taskService) DeleteTask(ctx context.Context, in *taskyv1.DeleteTaskRequest) (*taskyv1.DeleteTaskResponse, error) {
+	id, err := parseID("id", in.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.tasks.DeleteTask(ctx, id, versions(in.Version), callActor(ctx)); err != nil {
+		log.Error("Error deleting task: ", err)
+		return nil, statusError(err, "Task not found")
+	}
+
+	log.Info("Task deleted successfully")
+	return &taskyv1.DeleteTaskResponse{}, nil
+}
+
+func taskMessage(task *models.Task) *taskyv1.Task {
+	message := &taskyv1.Task{
+		Id:          task.ID.Hex(),
+		Title:       task.Title,
+		Description: task.Description,
+		Completed:   task.Completed,
+		UserId:      task.UserID.Hex(),
+		Version:     task.Version,
+	}
+	if task.DueAt != nil {
+		message.DueAt = timestamppb.New(*task.DueAt)
+	}
+	return message
+}
+
+// taskModel converts a task input, an empty user ID is left
+// for validation to reject
+func taskModel(in *taskyv1.TaskInput) (models.Task, error) {
+	task := models.Task{
+		Title:       in.GetTitle(),
+		Description: in.GetDescription(),
+		Completed:   in.GetCompleted(),
+	}
+	if in.GetDueAt() != nil {
+		dueAt := in.GetDueAt().AsTime()
+		task.DueAt = &dueAt
+	}
+	if in.GetUserId() != "" {
+		userId, err := parseID("user_id", in.GetUserId())
+		if err != nil {
+			return task, err
+		}
+		task.UserID = userId
+	}
+	return task, nil
+}
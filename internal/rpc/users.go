@@ -0,0 +1,114 @@
+package rpc
+
+import (
+	"context"
+
+	"github.com/cmerin0/tasky/internal/models"
+	"github.com/cmerin0/tasky/internal/rpc/taskyv1"
+	"github.com/cmerin0/tasky/internal/store"
+
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// userService implements taskyv1.UserServiceServer
+type userService struct {
+	taskyv1.UnimplementedUserServiceServer
+
+	users store.UserRepository
+}
+
+func (s *userService) ListUsers(ctx context.Context, in *taskyv1.ListUsersRequest) (*taskyv1.ListUsersResponse, error) {
+	req, err := pageRequest(in.GetLimit(), in.GetCursor(), in.GetSort(), in.GetCount(), store.UserSortFields)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := s.users.ListUsers(ctx, nil, req)
+	if err != nil {
+		log.Error("Error fetching users: ", err)
+		return nil, statusError(err, "User not found")
+	}
+
+	out := &taskyv1.ListUsersResponse{
+		Users:      make([]*taskyv1.User, len(page.Items)),
+		NextCursor: cursorToken(page.Next),
+		PrevCursor: cursorToken(page.Prev),
+		Total:      page.Total,
+	}
+	for i := range page.Items {
+		out.Users[i] = userMessage(&page.Items[i])
+	}
+	return out, nil
+}
+
+func (s *userService) GetUser(ctx context.Context, in *taskyv1.GetUserRequest) (*taskyv1.User, error) {
+	id, err := parseID("id", in.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.users.GetUser(ctx, id)
+	if err != nil {
+		return nil, statusError(err, "User not found")
+	}
+	return userMessage(user), nil
+}
+
+func (s *userService) CreateUser(ctx context.Context, in *taskyv1.CreateUserRequest) (*taskyv1.User, error) {
+	user, err := s.users.CreateUser(ctx, userModel(in.GetUser()))
+	if err != nil {
+		log.Error("Error creating user: ", err)
+		return nil, statusError(err, "User not found")
+	}
+
+	log.Info("User created successfully")
+	return userMessage(user), nil
+}
+
+func (s *userService) UpdateUser(ctx context.Context, in *taskyv1.UpdateUserRequest) (*taskyv1.User, error) {
+	id, err := parseID("id", in.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.users.ReplaceUser(ctx, id, userModel(in.GetUser()), versions(in.Version))
+	if err != nil {
+		log.Error("Error updating user: ", err)
+		return nil, statusError(err, "User not found")
+	}
+
+	log.Info("User updated successfully")
+	return userMessage(user), nil
+}
+
+func (s *userService) DeleteUser(ctx context.Context, in *taskyv1.DeleteUserRequest) (*taskyv1.DeleteUserResponse, error) {
+	id, err := parseID("id", in.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.users.DeleteUser(ctx, id, versions(in.Version)); err != nil {
+		log.Error("Error deleting user: ", err)
+		return nil, statusError(err, "User not found")
+	}
+
+	log.Info("User deleted successfully")
+	return &taskyv1.DeleteUserResponse{}, nil
+}
+
+func userMessage(user *models.UserResponse) *taskyv1.User {
+	return &taskyv1.User{
+		Id:      user.ID.Hex(),
+		Name:    user.Name,
+		Email:   user.Email,
+		Version: user.Version,
+	}
+}
+
+func userModel(in *taskyv1.UserInput) models.User {
+	return models.User{
+		Name:     in.GetName(),
+		Email:    in.GetEmail(),
+		Password: in.GetPassword(),
+	}
+}
@@ -0,0 +1,93 @@
+// Package scheduler runs periodic jobs inside the app.
+//
+// Every app replica runs the scheduler, but a job only runs on the replica
+// holding its lease in MongoDB. The holder renews the lease each time the
+// job runs and a run never outlasts it, so two replicas never run the same
+// job at once. When the holder stops, another replica takes the job over
+// once the lease expires.
+package scheduler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/cmerin0/tasky/internal/store"
+	"github.com/cmerin0/tasky/internal/worker"
+
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// Job is a function run every interval
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// leaseName is the name of the lease of the job
+func (j Job) leaseName() string {
+	return "scheduler:" + j.Name
+}
+
+// leaseTTL is how long a run holds the job. A run is cut at one interval,
+// the second leaves the holder time to renew before anyone takes over.
+func (j Job) leaseTTL() time.Duration {
+	return 2 * j.Interval
+}
+
+// Scheduler runs jobs on one replica at a time
+type Scheduler struct {
+	owner string
+	jobs  []Job
+}
+
+// New returns a scheduler identified by the host name of the replica
+func New() *Scheduler {
+	host, _ := os.Hostname()
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	return &Scheduler{owner: host + "-" + hex.EncodeToString(suffix)}
+}
+
+// Every adds a job run every interval
+func (s *Scheduler) Every(name string, interval time.Duration, run func(ctx context.Context) error) {
+	s.jobs = append(s.jobs, Job{Name: name, Interval: interval, Run: run})
+}
+
+// Run runs the jobs until ctx is done, then releases their leases
+func (s *Scheduler) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, job := range s.jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			worker.Poll(ctx, job.Interval, "running "+job.Name, func(ctx context.Context) (bool, error) {
+				return false, s.runOnce(ctx, job)
+			})
+
+			// Let another replica take over without waiting for the lease to expire
+			releaseCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := store.ReleaseLease(releaseCtx, job.leaseName(), s.owner); err != nil {
+				log.Error("Error releasing the lease of "+job.Name+": ", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// runOnce runs the job if this replica holds or takes its lease
+func (s *Scheduler) runOnce(ctx context.Context, job Job) error {
+	held, err := store.AcquireLease(ctx, job.leaseName(), s.owner, job.leaseTTL())
+	if err != nil || !held {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, job.Interval)
+	defer cancel()
+	return job.Run(ctx)
+}
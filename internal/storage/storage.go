@@ -0,0 +1,95 @@
+// Package storage opens the backend users and tasks are stored in
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cmerin0/tasky/internal/db"
+	"github.com/cmerin0/tasky/internal/store"
+	"github.com/cmerin0/tasky/internal/store/memory"
+	"github.com/cmerin0/tasky/internal/store/postgres"
+	"github.com/cmerin0/tasky/internal/store/sqlite"
+
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// MongoOnly lists what only MongoDB serves. Every storage serves users,
+// tasks, search, task history and saved views, the others don't register
+// these routes and ignore Idempotency-Key.
+var MongoOnly = []string{
+	"task events (/events and /events/ws)",
+	"notification preferences (/users/{userId}/notification-preferences)",
+	"task watches (/tasks/{taskId}/watch)",
+	"in-app notifications (/notifications)",
+	"webhooks (/webhooks)",
+	"background jobs (/admin/jobs)",
+	"the Idempotency-Key header",
+	"assignment emails, due date reminders and digests",
+}
+
+// Open opens the storage described by spec, as set in STORAGE:
+//
+//   - empty or "mongodb": MongoDB at the MONGO_* settings
+//   - "memory": in process, lost on exit
+//   - "postgres://..." or "postgresql://...": PostgreSQL at that URL,
+//     migrated on start
+//   - "sqlite://path": a SQLite file, "sqlite:///data/tasky.db" for an
+//     absolute path, migrated on start
+func Open(spec string) (store.Storage, error) {
+	switch {
+	case spec == "" || spec == "mongodb":
+		return openMongo(mongoURI()), nil
+	case spec == "memory":
+		log.Warn("Users and tasks are stored in memory and lost on exit")
+		return memory.New(), nil
+	case strings.HasPrefix(spec, "postgres://") || strings.HasPrefix(spec, "postgresql://"):
+		log.Info("Connecting to PostgreSQL...")
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		pg, err := postgres.Open(ctx, spec)
+		if err != nil {
+			return nil, err
+		}
+		return pg, nil
+	case strings.HasPrefix(spec, "sqlite://"):
+		path := strings.TrimPrefix(spec, "sqlite://")
+		if path == "" {
+			return nil, fmt.Errorf("storage %q has no path", spec)
+		}
+		log.Info("Opening SQLite database ", path)
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		lite, err := sqlite.Open(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		return lite, nil
+	}
+	return nil, fmt.Errorf("unknown storage %q", spec)
+}
+
+// mongoURI builds the MongoDB URI from the MONGO_* settings
+func mongoURI() string {
+	return fmt.Sprintf("mongodb://%s:%s@%s:%s/%s?authSource=admin",
+		os.Getenv("MONGO_USERNAME"),
+		os.Getenv("MONGO_PASSWORD"),
+		os.Getenv("MONGO_HOST"),
+		os.Getenv("MONGO_PORT"),
+		os.Getenv("MONGO_DBNAME"),
+	)
+}
+
+// openMongo connects to MongoDB and prepares its collections
+func openMongo(uri string) *store.Mongo {
+	// Note: The ConnectDB function should be called only once
+	// to avoid multiple connections to the database.
+	log.Info("Connecting to MongoDB...")
+	db.ConnectDB(uri)
+	db.EnsureIndexes()
+	db.EnableChangeStreamImages()
+	return store.NewMongo()
+}
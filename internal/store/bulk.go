@@ -0,0 +1,230 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/cmerin0/tasky/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Kinds of BulkOp
+const (
+	BulkCreate   = "create"
+	BulkReplace  = "replace"
+	BulkComplete = "complete"
+	BulkDelete   = "delete"
+)
+
+// BulkOp is one write of a batch. Task is the new task of a create or a
+// replace, ID the task every other kind writes.
+type BulkOp struct {
+	Kind string
+	ID   primitive.ObjectID
+	Task models.Task
+}
+
+// BulkResult is the outcome of a BulkOp: the task as written, nil for a
+// deletion, or why it failed
+type BulkResult struct {
+	Task *models.Task
+	Err  error
+}
+
+// ApplyBulk applies a batch one write at a time through repo, for backends
+// without a batch write. Call it in a transaction. ErrNotFound, ErrStale
+// and validation errors are reported per write, any other error is
+// returned and fails the batch.
+func ApplyBulk(ctx context.Context, repo TaskRepository, ops []BulkOp, ordered bool, actor string) ([]BulkResult, error) {
+	results := make([]BulkResult, len(ops))
+	failed := false
+	for i, op := range ops {
+		if failed && ordered {
+			results[i].Err = ErrSkipped
+			continue
+		}
+
+		var task *models.Task
+		var err error
+		switch op.Kind {
+		case BulkCreate:
+			task, err = repo.CreateTask(ctx, op.Task, actor)
+		case BulkReplace:
+			task, err = repo.ReplaceTask(ctx, op.ID, op.Task, nil, actor)
+		case BulkComplete:
+			task, err = repo.UpdateTask(ctx, op.ID, nil, func(task *models.Task) error {
+				task.Completed = true
+				return nil
+			}, actor)
+		case BulkDelete:
+			err = repo.DeleteTask(ctx, op.ID, nil, actor)
+		default:
+			return nil, fmt.Errorf("unknown bulk operation %q", op.Kind)
+		}
+
+		var invalid *ValidationError
+		switch {
+		case err == nil:
+			results[i].Task = task
+		case errors.Is(err, ErrNotFound), errors.Is(err, ErrStale), errors.As(err, &invalid):
+			results[i].Err = err
+			failed = true
+		default:
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// bulkWrite is a write of a batch waiting for its history entry and event
+type bulkWrite struct {
+	before, after *models.Task
+}
+
+// BulkTasks applies a batch with a single BulkWrite, in a transaction
+// along with the history entries and events of the writes. The tasks are
+// read first so missing ones are reported per write, as are invalid ones.
+func (m *Mongo) BulkTasks(ctx context.Context, ops []BulkOp, ordered bool, actor string) ([]BulkResult, error) {
+	var results []BulkResult
+	coll := Tasks()
+	err := WithTransaction(ctx, func(sc mongo.SessionContext) error {
+		// The transaction may be retried, start over every time
+		results = make([]BulkResult, len(ops))
+
+		ids := bson.A{}
+		for _, op := range ops {
+			if op.Kind != BulkCreate {
+				ids = append(ids, op.ID)
+			}
+		}
+		existing := map[primitive.ObjectID]models.Task{}
+		if len(ids) > 0 {
+			cursor, err := coll.Find(sc, bson.M{"_id": bson.M{"$in": ids}})
+			if err != nil {
+				return err
+			}
+			var tasks []models.Task
+			if err := cursor.All(sc, &tasks); err != nil {
+				return err
+			}
+			for _, task := range tasks {
+				existing[task.ID] = task
+			}
+		}
+
+		var batch []mongo.WriteModel
+		var writes []bulkWrite
+		failed := false
+		for i, op := range ops {
+			if failed && ordered {
+				results[i].Err = ErrSkipped
+				continue
+			}
+			write, model, err := planBulkWrite(op, existing)
+			var invalid *ValidationError
+			if err != nil && !errors.Is(err, ErrNotFound) && !errors.As(err, &invalid) {
+				return err
+			}
+			if err != nil {
+				results[i].Err = err
+				failed = true
+				continue
+			}
+			results[i].Task = write.after
+			if model == nil {
+				continue
+			}
+			batch = append(batch, model)
+			writes = append(writes, write)
+
+			// Later writes to the same task build on this one
+			if write.after != nil {
+				existing[op.ID] = *write.after
+			} else {
+				delete(existing, op.ID)
+			}
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+
+		// The tasks were read in the transaction, a write can only fail for
+		// reasons the batch as a whole fails for
+		if _, err := coll.BulkWrite(sc, batch, options.BulkWrite().SetOrdered(ordered)); err != nil {
+			return err
+		}
+		for _, write := range writes {
+			action, event := models.HistoryUpdated, models.EventTaskUpdated
+			switch {
+			case write.before == nil:
+				action, event = models.HistoryCreated, models.EventTaskCreated
+			case write.after == nil:
+				action, event = models.HistoryDeleted, models.EventTaskDeleted
+			}
+			if err := recordTaskHistory(sc, action, actor, write.before, write.after); err != nil {
+				return err
+			}
+			if err := EnqueueTask(sc, event, write.before, write.after); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// planBulkWrite validates a write against the current tasks and returns
+// it with its write model, nil when there is nothing to write
+func planBulkWrite(op BulkOp, existing map[primitive.ObjectID]models.Task) (bulkWrite, mongo.WriteModel, error) {
+	if op.Kind == BulkCreate {
+		if err := Validate(op.Task); err != nil {
+			return bulkWrite{}, nil, err
+		}
+		task := models.Task{
+			ID:          primitive.NewObjectID(),
+			Title:       op.Task.Title,
+			Description: op.Task.Description,
+			Completed:   op.Task.Completed,
+			UserID:      op.Task.UserID,
+			DueAt:       op.Task.DueAt,
+			Version:     1,
+		}
+		return bulkWrite{after: &task}, mongo.NewInsertOneModel().SetDocument(task), nil
+	}
+
+	before, ok := existing[op.ID]
+	if !ok {
+		return bulkWrite{}, nil, ErrNotFound
+	}
+	current := versionFilter(op.ID, []int64{before.Version})
+	switch op.Kind {
+	case BulkReplace:
+		task := op.Task
+		task.ID = op.ID
+		if err := Validate(task); err != nil {
+			return bulkWrite{}, nil, err
+		}
+		task.Version = before.Version + 1
+		return bulkWrite{before: &before, after: &task}, mongo.NewReplaceOneModel().SetFilter(current).SetReplacement(task), nil
+	case BulkComplete:
+		task := before
+		if task.Completed {
+			return bulkWrite{before: &before, after: &task}, nil, nil
+		}
+		task.Completed = true
+		task.Version++
+		return bulkWrite{before: &before, after: &task}, mongo.NewUpdateOneModel().SetFilter(current).
+			SetUpdate(bson.M{"$set": bson.M{"completed": true, "version": task.Version}}), nil
+	case BulkDelete:
+		return bulkWrite{before: &before}, mongo.NewDeleteOneModel().SetFilter(current), nil
+	}
+	return bulkWrite{}, nil, fmt.Errorf("unknown bulk operation %q", op.Kind)
+}
@@ -0,0 +1,147 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/cmerin0/tasky/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TaskHistory returns the history of a task, newest first
+func (m *Mongo) TaskHistory(ctx context.Context, id primitive.ObjectID) ([]models.TaskHistory, error) {
+	cursor, err := History().Find(ctx, bson.M{"taskId": id},
+		options.Find().SetSort(bson.D{{Key: "timestamp", Value: -1}, {Key: "_id", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	history := []models.TaskHistory{}
+	if err := cursor.All(ctx, &history); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// RevertTask restores a task to the snapshot of one of its history entries
+func (m *Mongo) RevertTask(ctx context.Context, id, entryId primitive.ObjectID, actor string) (*models.Task, error) {
+	var snapshot models.Task
+	err := WithTransaction(ctx, func(sc mongo.SessionContext) error {
+		var entry models.TaskHistory
+		err := History().FindOne(sc, bson.M{"_id": entryId, "taskId": id}).Decode(&entry)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		// A revert is a new version of the task, not a rollback of the counter
+		var before models.Task
+		var previous *models.Task
+		query := bson.M{"_id": id}
+		snapshot = entry.Snapshot
+
+		err = Tasks().FindOne(sc, bson.M{"_id": id}).Decode(&before)
+		switch {
+		case err == nil:
+			previous = &before
+			snapshot.Version = before.Version + 1
+			query["version"] = VersionIn([]int64{before.Version})
+		case errors.Is(err, mongo.ErrNoDocuments):
+			// Deleted tasks carry on from the version they were deleted at
+			var last models.TaskHistory
+			err := History().FindOne(sc, bson.M{"taskId": id},
+				options.FindOne().SetSort(bson.D{{Key: "timestamp", Value: -1}, {Key: "_id", Value: -1}})).Decode(&last)
+			if err != nil {
+				return err
+			}
+			snapshot.Version = last.Snapshot.Version + 1
+		default:
+			return err
+		}
+
+		// Upsert so that deleted tasks can be brought back. A restored task
+		// is announced as created, anything else as updated.
+		event := models.EventTaskUpdated
+		if previous == nil {
+			event = models.EventTaskCreated
+		}
+		result, err := Tasks().ReplaceOne(sc, query, snapshot, options.Replace().SetUpsert(previous == nil))
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 && result.UpsertedCount == 0 {
+			return ErrStale
+		}
+		if err := recordTaskHistory(sc, models.HistoryReverted, actor, previous, &snapshot); err != nil {
+			return err
+		}
+		return EnqueueTask(sc, event, previous, &snapshot)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// recordTaskHistory stores a history entry for a task mutation, in the
+// transaction of the mutation. before is nil for creations and after is
+// nil for deletions.
+func recordTaskHistory(sc mongo.SessionContext, action, actor string, before, after *models.Task) error {
+	entry := models.TaskHistory{
+		Action:    action,
+		Actor:     actor,
+		Timestamp: time.Now().UTC(),
+		Changes:   DiffTasks(before, after),
+	}
+
+	// Deletions keep the last known state so they can be reverted
+	if after != nil {
+		entry.TaskID = after.ID
+		entry.Snapshot = *after
+	} else {
+		entry.TaskID = before.ID
+		entry.Snapshot = *before
+	}
+
+	_, err := History().InsertOne(sc, entry)
+	return err
+}
+
+// DiffTasks returns the field level changes between two versions of a task.
+// Fields are named after their bson tags so they match the stored document.
+// The version counter itself is not reported as a change.
+func DiffTasks(before, after *models.Task) []models.FieldChange {
+	changes := []models.FieldChange{}
+	taskType := reflect.TypeOf(models.Task{})
+
+	for i := 0; i < taskType.NumField(); i++ {
+		field := taskType.Field(i)
+		name := strings.Split(field.Tag.Get("bson"), ",")[0]
+		if name == "" || name == "_id" || name == "version" {
+			continue
+		}
+
+		var from, to interface{}
+		if before != nil {
+			from = reflect.ValueOf(*before).Field(i).Interface()
+		}
+		if after != nil {
+			to = reflect.ValueOf(*after).Field(i).Interface()
+		}
+
+		if !reflect.DeepEqual(from, to) {
+			changes = append(changes, models.FieldChange{Field: name, From: from, To: to})
+		}
+	}
+
+	return changes
+}
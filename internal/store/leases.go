@@ -0,0 +1,37 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AcquireLease takes or renews the lease called name for owner, for ttl.
+// It reports false when another owner holds an unexpired lease.
+// Expiry is checked against the clock of the database, not of the replicas.
+func AcquireLease(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	filter := bson.M{"_id": name, "$or": bson.A{
+		bson.M{"owner": owner},
+		bson.M{"$expr": bson.M{"$lte": bson.A{"$expiresAt", "$$NOW"}}},
+	}}
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{
+		"owner":     owner,
+		"expiresAt": bson.M{"$add": bson.A{"$$NOW", ttl.Milliseconds()}},
+	}}}}
+
+	// A lease held by someone else doesn't match, so the upsert collides on _id
+	_, err := Leases().UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// ReleaseLease gives up the lease called name if owner holds it
+func ReleaseLease(ctx context.Context, name, owner string) error {
+	_, err := Leases().DeleteOne(ctx, bson.M{"_id": name, "owner": owner})
+	return err
+}
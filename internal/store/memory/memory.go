@@ -0,0 +1,117 @@
+// Package memory stores users and tasks in process, for running tasky
+// without a database. Everything is lost when the process exits.
+//
+// It follows the MongoDB backend: the same validation, versioning, task
+// history, pagination and filtering, and the same errors. Changes aren't
+// relayed as events, so webhooks and notifications stay silent.
+package memory
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/cmerin0/tasky/internal/models"
+	"github.com/cmerin0/tasky/internal/store"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Store holds users, tasks and saved views in memory, it is safe for
+// concurrent use
+type Store struct {
+	mu      sync.Mutex
+	users   map[primitive.ObjectID]models.User
+	tasks   map[primitive.ObjectID]models.Task
+	history []models.TaskHistory
+	views   map[primitive.ObjectID]models.View
+}
+
+// New returns an empty store
+func New() *Store {
+	return &Store{
+		users: map[primitive.ObjectID]models.User{},
+		tasks: map[primitive.ObjectID]models.Task{},
+		views: map[primitive.ObjectID]models.View{},
+	}
+}
+
+var (
+	_ store.Storage        = (*Store)(nil)
+	_ store.ViewRepository = (*Store)(nil)
+)
+
+// txKey marks the context of a transaction, its value is the store running it
+type txKey struct{}
+
+// lock locks the store for a call, unless the call is part of a
+// transaction which already holds the lock
+func (s *Store) lock(ctx context.Context) func() {
+	if ctx.Value(txKey{}) == s {
+		return func() {}
+	}
+	s.mu.Lock()
+	return s.mu.Unlock
+}
+
+// InTransaction runs fn with the store locked, restoring every user, task
+// and history entry if it fails. Calls made with the context it is given
+// join the transaction.
+func (s *Store) InTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(txKey{}) == s {
+		return fn(ctx)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	users := make(map[primitive.ObjectID]models.User, len(s.users))
+	for id, user := range s.users {
+		users[id] = user
+	}
+	tasks := make(map[primitive.ObjectID]models.Task, len(s.tasks))
+	for id, task := range s.tasks {
+		tasks[id] = task
+	}
+	history := s.history[:len(s.history):len(s.history)]
+
+	if err := fn(context.WithValue(ctx, txKey{}, s)); err != nil {
+		s.users, s.tasks, s.history = users, tasks, history
+		return err
+	}
+	return nil
+}
+
+// versionMatches reports whether a stored version is one of the accepted
+// versions, nil accepts any
+func versionMatches(version int64, versions []int64) bool {
+	if versions == nil {
+		return true
+	}
+	for _, v := range versions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// storedTime returns a copy of a time as MongoDB would store it:
+// in UTC, to the millisecond
+func storedTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	stored := t.UTC().Truncate(time.Millisecond)
+	return &stored
+}
+
+// sortByID sorts items by ID, which orders them by creation
+func sortByID[T any](items []T, id func(item T) primitive.ObjectID) {
+	slices.SortFunc(items, func(a, b T) int {
+		aID, bID := id(a), id(b)
+		return bytes.Compare(aID[:], bID[:])
+	})
+}
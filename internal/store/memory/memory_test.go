@@ -0,0 +1,15 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/cmerin0/tasky/internal/store"
+	"github.com/cmerin0/tasky/internal/store/memory"
+	"github.com/cmerin0/tasky/internal/store/storetest"
+)
+
+func TestConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Storage {
+		return memory.New()
+	})
+}
@@ -0,0 +1,75 @@
+package memory
+
+import (
+	"slices"
+
+	"github.com/cmerin0/tasky/internal/filter"
+	"github.com/cmerin0/tasky/internal/store"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// compareKeys orders sort keys, missing keys first
+func compareKeys(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	cmp, _ := filter.Compare(a, b)
+	return cmp
+}
+
+// paginate returns one page of items the way store.Paginate does: sorted by
+// the requested field with the ID as tie breaker, continuing from the cursor.
+// field returns the value of a stored field of an item.
+func paginate[T any](items []T, req store.PageRequest, field func(item T, name string) interface{}) *store.Page[T] {
+	id := func(item T) primitive.ObjectID {
+		return field(item, "_id").(primitive.ObjectID)
+	}
+	compare := func(a T, key interface{}, aID, bID primitive.ObjectID) int {
+		if req.Field != "_id" {
+			if cmp := compareKeys(field(a, req.Field), key); cmp != 0 {
+				return cmp
+			}
+		}
+		return compareKeys(aID, bID)
+	}
+
+	// Walking backwards reverses the sort
+	backwards := req.Cursor != nil && req.Cursor.Prev
+	descending := req.Desc != backwards
+
+	var page []T
+	for _, item := range items {
+		if cur := req.Cursor; cur != nil {
+			cmp := compare(item, cur.Value, id(item), cur.ID)
+			if cmp == 0 || (cmp > 0) == descending {
+				continue
+			}
+		}
+		page = append(page, item)
+	}
+	slices.SortFunc(page, func(a, b T) int {
+		cmp := compare(a, field(b, req.Field), id(a), id(b))
+		if descending {
+			return -cmp
+		}
+		return cmp
+	})
+	if len(page) > req.Limit+1 {
+		page = page[:req.Limit+1]
+	}
+
+	result := store.NewPage(page, req, func(item T) (interface{}, primitive.ObjectID) {
+		return field(item, req.Field), id(item)
+	})
+	if req.Count {
+		total := int64(len(items))
+		result.Total = &total
+	}
+	return result
+}
@@ -0,0 +1,307 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/cmerin0/tasky/internal/filter"
+	"github.com/cmerin0/tasky/internal/models"
+	"github.com/cmerin0/tasky/internal/store"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// taskField returns a stored task field by its storage name
+func taskField(task models.Task, name string) interface{} {
+	switch name {
+	case "_id":
+		return task.ID
+	case "title":
+		return task.Title
+	case "description":
+		return task.Description
+	case "completed":
+		return task.Completed
+	case "userId":
+		return task.UserID
+	case "dueAt":
+		if task.DueAt != nil {
+			return *task.DueAt
+		}
+	case "version":
+		return task.Version
+	}
+	return nil
+}
+
+// matchingTasks returns the tasks matching where, s.mu must be held
+func (s *Store) matchingTasks(where filter.Expr) []models.Task {
+	tasks := []models.Task{}
+	for _, task := range s.tasks {
+		if filter.Eval(where, func(name string) interface{} { return taskField(task, name) }) {
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks
+}
+
+// findTask returns a task at one of the versions, s.mu must be held
+func (s *Store) findTask(id primitive.ObjectID, versions []int64) (models.Task, error) {
+	task, ok := s.tasks[id]
+	if !ok {
+		return task, store.ErrNotFound
+	}
+	if !versionMatches(task.Version, versions) {
+		return task, store.ErrStale
+	}
+	return task, nil
+}
+
+// GetTask returns a task by ID
+func (s *Store) GetTask(ctx context.Context, id primitive.ObjectID) (*models.Task, error) {
+	defer s.lock(ctx)()
+
+	task, ok := s.tasks[id]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return &task, nil
+}
+
+// ListTasks returns a page of tasks
+func (s *Store) ListTasks(ctx context.Context, where filter.Expr, req store.PageRequest, fields []string) (*store.Page[models.Task], error) {
+	defer s.lock(ctx)()
+
+	page := paginate(s.matchingTasks(where), req, taskField)
+	if fields != nil {
+		fields = append(slices.Clip(fields), req.Field)
+		for i, task := range page.Items {
+			page.Items[i] = projectTask(task, fields)
+		}
+	}
+	return page, nil
+}
+
+// projectTask returns a copy of a task holding only its ID and the listed
+// stored fields
+func projectTask(task models.Task, fields []string) models.Task {
+	projected := models.Task{ID: task.ID}
+	for _, field := range fields {
+		switch field {
+		case "title":
+			projected.Title = task.Title
+		case "description":
+			projected.Description = task.Description
+		case "completed":
+			projected.Completed = task.Completed
+		case "userId":
+			projected.UserID = task.UserID
+		case "dueAt":
+			projected.DueAt = task.DueAt
+		case "version":
+			projected.Version = task.Version
+		}
+	}
+	return projected
+}
+
+// FindTasks returns every matching task, oldest first
+func (s *Store) FindTasks(ctx context.Context, where filter.Expr) ([]models.Task, error) {
+	defer s.lock(ctx)()
+
+	tasks := s.matchingTasks(where)
+	sortByID(tasks, func(task models.Task) primitive.ObjectID { return task.ID })
+	return tasks, nil
+}
+
+// SearchTasks returns up to limit matching tasks, most relevant first
+func (s *Store) SearchTasks(ctx context.Context, text string, where filter.Expr, limit int) ([]store.TaskMatch, error) {
+	defer s.lock(ctx)()
+
+	query := store.NewTextSearch(text)
+	matches := []store.TaskMatch{}
+	for _, task := range s.matchingTasks(where) {
+		if score, ok := query.Score(task); ok {
+			matches = append(matches, store.TaskMatch{Task: task, Score: score})
+		}
+	}
+
+	// Equal scores keep a stable order, oldest first
+	sortByID(matches, func(match store.TaskMatch) primitive.ObjectID { return match.ID })
+	return store.RankMatches(matches, limit), nil
+}
+
+// CreateTask validates and stores a new task at version 1
+func (s *Store) CreateTask(ctx context.Context, task models.Task, actor string) (*models.Task, error) {
+	if err := store.Validate(task); err != nil {
+		return nil, err
+	}
+	defer s.lock(ctx)()
+
+	newTask := models.Task{
+		ID:          primitive.NewObjectID(),
+		Title:       task.Title,
+		Description: task.Description,
+		Completed:   task.Completed,
+		UserID:      task.UserID,
+		DueAt:       storedTime(task.DueAt),
+		Version:     1,
+	}
+	s.tasks[newTask.ID] = newTask
+
+	s.recordHistory(models.HistoryCreated, actor, nil, &newTask)
+	return &newTask, nil
+}
+
+// ReplaceTask validates and replaces every field of a task, bumping its version
+func (s *Store) ReplaceTask(ctx context.Context, id primitive.ObjectID, task models.Task, versions []int64, actor string) (*models.Task, error) {
+	task.ID = id
+	if err := store.Validate(task); err != nil {
+		return nil, err
+	}
+	defer s.lock(ctx)()
+
+	before, err := s.findTask(id, versions)
+	if err != nil {
+		return nil, err
+	}
+	task.DueAt = storedTime(task.DueAt)
+	task.Version = before.Version + 1
+	s.tasks[id] = task
+
+	s.recordHistory(models.HistoryUpdated, actor, &before, &task)
+	return &task, nil
+}
+
+// UpdateTask writes the fields change modified
+func (s *Store) UpdateTask(ctx context.Context, id primitive.ObjectID, versions []int64, change func(task *models.Task) error, actor string) (*models.Task, error) {
+	defer s.lock(ctx)()
+
+	before, err := s.findTask(id, versions)
+	if err != nil {
+		return nil, err
+	}
+
+	after := before
+	if err := change(&after); err != nil {
+		return nil, err
+	}
+	after.ID, after.Version = before.ID, before.Version
+	after.DueAt = storedTime(after.DueAt)
+	if err := store.Validate(after); err != nil {
+		return nil, err
+	}
+
+	if len(store.DiffTasks(&before, &after)) == 0 {
+		return &after, nil
+	}
+	after.Version++
+	s.tasks[id] = after
+
+	s.recordHistory(models.HistoryUpdated, actor, &before, &after)
+	return &after, nil
+}
+
+// DeleteTask deletes a task
+func (s *Store) DeleteTask(ctx context.Context, id primitive.ObjectID, versions []int64, actor string) error {
+	defer s.lock(ctx)()
+
+	deleted, err := s.findTask(id, versions)
+	if err != nil {
+		return err
+	}
+	delete(s.tasks, id)
+
+	s.recordHistory(models.HistoryDeleted, actor, &deleted, nil)
+	return nil
+}
+
+// TaskHistory returns the history of a task, newest first
+func (s *Store) TaskHistory(ctx context.Context, id primitive.ObjectID) ([]models.TaskHistory, error) {
+	defer s.lock(ctx)()
+
+	history := []models.TaskHistory{}
+	for i := len(s.history) - 1; i >= 0; i-- {
+		if s.history[i].TaskID == id {
+			history = append(history, s.history[i])
+		}
+	}
+	return history, nil
+}
+
+// BulkTasks applies a batch one write at a time, in a transaction
+func (s *Store) BulkTasks(ctx context.Context, ops []store.BulkOp, ordered bool, actor string) ([]store.BulkResult, error) {
+	var results []store.BulkResult
+	err := s.InTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		results, err = store.ApplyBulk(ctx, s, ops, ordered, actor)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// RevertTask restores a task, even a deleted one, to the snapshot of one
+// of its history entries
+func (s *Store) RevertTask(ctx context.Context, id, entryId primitive.ObjectID, actor string) (*models.Task, error) {
+	defer s.lock(ctx)()
+
+	i := slices.IndexFunc(s.history, func(entry models.TaskHistory) bool {
+		return entry.ID == entryId && entry.TaskID == id
+	})
+	if i < 0 {
+		return nil, store.ErrNotFound
+	}
+
+	// A revert is a new version of the task, not a rollback of the counter
+	snapshot := s.history[i].Snapshot
+	before, exists := s.tasks[id]
+	var previous *models.Task
+	if exists {
+		previous = &before
+		snapshot.Version = before.Version + 1
+	} else {
+		// Deleted tasks carry on from the version they were deleted at
+		snapshot.Version = s.lastVersion(id) + 1
+	}
+	s.tasks[id] = snapshot
+
+	s.recordHistory(models.HistoryReverted, actor, previous, &snapshot)
+	return &snapshot, nil
+}
+
+// lastVersion returns the version of a task in its newest history entry,
+// s.mu must be held
+func (s *Store) lastVersion(id primitive.ObjectID) int64 {
+	for i := len(s.history) - 1; i >= 0; i-- {
+		if s.history[i].TaskID == id {
+			return s.history[i].Snapshot.Version
+		}
+	}
+	return 0
+}
+
+// recordHistory stores a history entry for a task mutation, s.mu must be held.
+// before is nil for creations and after is nil for deletions.
+func (s *Store) recordHistory(action, actor string, before, after *models.Task) {
+	entry := models.TaskHistory{
+		ID:        primitive.NewObjectID(),
+		Action:    action,
+		Actor:     actor,
+		Timestamp: time.Now().UTC().Truncate(time.Millisecond),
+		Changes:   store.DiffTasks(before, after),
+	}
+
+	// Deletions keep the last known state so they can be reverted
+	if after != nil {
+		entry.TaskID = after.ID
+		entry.Snapshot = *after
+	} else {
+		entry.TaskID = before.ID
+		entry.Snapshot = *before
+	}
+	s.history = append(s.history, entry)
+}
@@ -0,0 +1,158 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/cmerin0/tasky/internal/filter"
+	"github.com/cmerin0/tasky/internal/models"
+	"github.com/cmerin0/tasky/internal/store"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// userField returns a stored user field by its storage name
+func userField(user models.UserResponse, name string) interface{} {
+	switch name {
+	case "_id":
+		return user.ID
+	case "name":
+		return user.Name
+	case "email":
+		return user.Email
+	case "version":
+		return user.Version
+	}
+	return nil
+}
+
+// response returns a user without its password
+func response(user models.User) models.UserResponse {
+	return models.UserResponse{ID: user.ID, Name: user.Name, Email: user.Email, Version: user.Version}
+}
+
+// matchingUsers returns the users matching where, s.mu must be held
+func (s *Store) matchingUsers(where filter.Expr) []models.UserResponse {
+	users := []models.UserResponse{}
+	for _, user := range s.users {
+		found := response(user)
+		if filter.Eval(where, func(name string) interface{} { return userField(found, name) }) {
+			users = append(users, found)
+		}
+	}
+	return users
+}
+
+// findUser returns a user at one of the versions, s.mu must be held
+func (s *Store) findUser(id primitive.ObjectID, versions []int64) (models.User, error) {
+	user, ok := s.users[id]
+	if !ok {
+		return user, store.ErrNotFound
+	}
+	if !versionMatches(user.Version, versions) {
+		return user, store.ErrStale
+	}
+	return user, nil
+}
+
+// GetUser returns a user by ID, without the password
+func (s *Store) GetUser(ctx context.Context, id primitive.ObjectID) (*models.UserResponse, error) {
+	defer s.lock(ctx)()
+
+	user, ok := s.users[id]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	found := response(user)
+	return &found, nil
+}
+
+// ListUsers returns a page of users
+func (s *Store) ListUsers(ctx context.Context, where filter.Expr, req store.PageRequest) (*store.Page[models.UserResponse], error) {
+	defer s.lock(ctx)()
+	return paginate(s.matchingUsers(where), req, userField), nil
+}
+
+// FindUsers returns every matching user, oldest first
+func (s *Store) FindUsers(ctx context.Context, where filter.Expr) ([]models.UserResponse, error) {
+	defer s.lock(ctx)()
+
+	users := s.matchingUsers(where)
+	sortByID(users, func(user models.UserResponse) primitive.ObjectID { return user.ID })
+	return users, nil
+}
+
+// CreateUser validates and stores a new user at version 1
+func (s *Store) CreateUser(ctx context.Context, user models.User) (*models.UserResponse, error) {
+	if err := store.Validate(user); err != nil {
+		return nil, err
+	}
+	defer s.lock(ctx)()
+
+	newUser := models.User{
+		ID:       primitive.NewObjectID(),
+		Name:     user.Name,
+		Email:    user.Email,
+		Password: user.Password,
+		Version:  1,
+	}
+	s.users[newUser.ID] = newUser
+
+	created := response(newUser)
+	return &created, nil
+}
+
+// ReplaceUser validates and replaces every field of a user, bumping its version
+func (s *Store) ReplaceUser(ctx context.Context, id primitive.ObjectID, user models.User, versions []int64) (*models.UserResponse, error) {
+	user.ID = id
+	if err := store.Validate(user); err != nil {
+		return nil, err
+	}
+	defer s.lock(ctx)()
+
+	current, err := s.findUser(id, versions)
+	if err != nil {
+		return nil, err
+	}
+	user.Version = current.Version + 1
+	s.users[id] = user
+
+	replaced := response(user)
+	return &replaced, nil
+}
+
+// UpdateUser writes the fields change modified
+func (s *Store) UpdateUser(ctx context.Context, id primitive.ObjectID, versions []int64, change func(user *models.User) error) (*models.UserResponse, error) {
+	defer s.lock(ctx)()
+
+	before, err := s.findUser(id, versions)
+	if err != nil {
+		return nil, err
+	}
+
+	after := before
+	if err := change(&after); err != nil {
+		return nil, err
+	}
+	after.ID, after.Version = before.ID, before.Version
+	if err := store.Validate(after); err != nil {
+		return nil, err
+	}
+
+	if after != before {
+		after.Version++
+		s.users[id] = after
+	}
+	updated := response(after)
+	return &updated, nil
+}
+
+// DeleteUser deletes a user
+func (s *Store) DeleteUser(ctx context.Context, id primitive.ObjectID, versions []int64) error {
+	defer s.lock(ctx)()
+
+	if _, err := s.findUser(id, versions); err != nil {
+		return err
+	}
+	delete(s.users, id)
+	return nil
+}
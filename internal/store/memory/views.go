@@ -0,0 +1,79 @@
+package memory
+
+import (
+	"context"
+	"slices"
+
+	"github.com/cmerin0/tasky/internal/models"
+	"github.com/cmerin0/tasky/internal/store"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// storedView returns a copy of a view which doesn't share its columns
+func storedView(view models.View) models.View {
+	view.Columns = slices.Clone(view.Columns)
+	return view
+}
+
+// GetView returns a saved view by ID
+func (s *Store) GetView(ctx context.Context, id primitive.ObjectID) (*models.View, error) {
+	defer s.lock(ctx)()
+
+	view, ok := s.views[id]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	view = storedView(view)
+	return &view, nil
+}
+
+// ListViews returns the views of owner plus those shared with project or workspace
+func (s *Store) ListViews(ctx context.Context, owner primitive.ObjectID, project, workspace string) ([]models.View, error) {
+	defer s.lock(ctx)()
+
+	views := []models.View{}
+	for _, view := range s.views {
+		switch {
+		case view.OwnerID == owner,
+			project != "" && view.Visibility == models.ViewProject && view.SharedWith == project,
+			workspace != "" && view.Visibility == models.ViewWorkspace && view.SharedWith == workspace:
+			views = append(views, storedView(view))
+		}
+	}
+	sortByID(views, func(view models.View) primitive.ObjectID { return view.ID })
+	return views, nil
+}
+
+// CreateView stores a new view
+func (s *Store) CreateView(ctx context.Context, view models.View) (*models.View, error) {
+	defer s.lock(ctx)()
+
+	view.ID = primitive.NewObjectID()
+	s.views[view.ID] = storedView(view)
+	return &view, nil
+}
+
+// UpdateView replaces a view of its owner
+func (s *Store) UpdateView(ctx context.Context, view models.View) error {
+	defer s.lock(ctx)()
+
+	stored, ok := s.views[view.ID]
+	if !ok || stored.OwnerID != view.OwnerID {
+		return store.ErrNotFound
+	}
+	s.views[view.ID] = storedView(view)
+	return nil
+}
+
+// DeleteView deletes a view of its owner
+func (s *Store) DeleteView(ctx context.Context, id, owner primitive.ObjectID) error {
+	defer s.lock(ctx)()
+
+	view, ok := s.views[id]
+	if !ok || view.OwnerID != owner {
+		return store.ErrNotFound
+	}
+	delete(s.views, id)
+	return nil
+}
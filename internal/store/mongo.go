@@ -0,0 +1,46 @@
+package store
+
+import (
+	"context"
+
+	"github.com/cmerin0/tasky/internal/db"
+	"github.com/cmerin0/tasky/internal/filter"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Mongo stores users and tasks in MongoDB. Every change is written in a
+// transaction along with its outbox event, so webhooks, notifications and
+// real-time clients hear about it.
+type Mongo struct{}
+
+// NewMongo returns the MongoDB repositories, db.ConnectDB must have been called
+func NewMongo() *Mongo {
+	return &Mongo{}
+}
+
+var (
+	_ Storage                = (*Mongo)(nil)
+	_ Pinger                 = (*Mongo)(nil)
+	_ ViewRepository         = (*Mongo)(nil)
+	_ NotificationRepository = (*Mongo)(nil)
+)
+
+// Ping pings the primary
+func (m *Mongo) Ping(ctx context.Context) error {
+	return db.Client.Ping(ctx, nil)
+}
+
+// InTransaction runs fn in a transaction, writes made with the context
+// it is given join it
+func (m *Mongo) InTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return WithTransaction(ctx, func(sc mongo.SessionContext) error {
+		return fn(sc)
+	})
+}
+
+// mongoFilter compiles a query into a MongoDB filter
+func mongoFilter(expr filter.Expr) bson.M {
+	return filter.ToBSON(expr)
+}
@@ -0,0 +1,30 @@
+package store_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/cmerin0/tasky/internal/db"
+	"github.com/cmerin0/tasky/internal/store"
+	"github.com/cmerin0/tasky/internal/store/storetest"
+)
+
+// TestConformance runs against the MongoDB at TEST_MONGO_URI, such as
+// mongodb://localhost:27017/?replicaSet=rs0, in the database named by
+// MONGO_DBNAME or tasky_test. Transactions need a replica set. The checks
+// leave their documents behind: use a scratch database.
+func TestConformance(t *testing.T) {
+	uri := os.Getenv("TEST_MONGO_URI")
+	if uri == "" {
+		t.Skip("TEST_MONGO_URI is not set")
+	}
+	if os.Getenv("MONGO_DBNAME") == "" {
+		t.Setenv("MONGO_DBNAME", "tasky_test")
+	}
+	db.ConnectDB(uri)
+	db.EnsureIndexes()
+
+	storetest.Run(t, func(t *testing.T) store.Storage {
+		return store.NewMongo()
+	})
+}
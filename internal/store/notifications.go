@@ -0,0 +1,147 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/cmerin0/tasky/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MaxNotifications is how many notifications a user keeps,
+// adding one prunes the oldest beyond it
+const MaxNotifications = 200
+
+// AddNotification stores a notification in the inbox of its user.
+// A notification is stored once per user and event, adding it again is a no-op.
+func AddNotification(ctx context.Context, notification models.Notification) error {
+	notification.ID = primitive.NilObjectID
+	notification.Read, notification.ReadAt = false, nil
+	notification.CreatedAt = time.Now().UTC()
+
+	_, err := Notifications().InsertOne(ctx, notification)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return pruneNotifications(ctx, notification.UserID)
+}
+
+// pruneNotifications deletes the notifications of a user beyond MaxNotifications
+func pruneNotifications(ctx context.Context, userId primitive.ObjectID) error {
+	var oldest struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	opts := options.FindOne().
+		SetSort(bson.D{{Key: "_id", Value: -1}}).
+		SetSkip(MaxNotifications - 1).
+		SetProjection(bson.M{"_id": 1})
+	err := Notifications().FindOne(ctx, bson.M{"userId": userId}, opts).Decode(&oldest)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = Notifications().DeleteMany(ctx, bson.M{"userId": userId, "_id": bson.M{"$lt": oldest.ID}})
+	return err
+}
+
+// ListNotifications returns a page of the notifications of a user
+func (m *Mongo) ListNotifications(ctx context.Context, userId primitive.ObjectID, unreadOnly bool, req PageRequest) (*Page[models.Notification], error) {
+	filter := bson.M{"userId": userId}
+	if unreadOnly {
+		filter["read"] = false
+	}
+	return Paginate[models.Notification](ctx, Notifications(), filter, nil, req)
+}
+
+// CountUnread returns the number of unread notifications of a user
+func CountUnread(ctx context.Context, userId primitive.ObjectID) (int64, error) {
+	return Notifications().CountDocuments(ctx, bson.M{"userId": userId, "read": false})
+}
+
+// CountUnread returns the number of unread notifications of a user
+func (m *Mongo) CountUnread(ctx context.Context, userId primitive.ObjectID) (int64, error) {
+	return CountUnread(ctx, userId)
+}
+
+// MarkNotificationRead marks a notification of a user as read.
+// Marking a read notification again keeps its first read time.
+func (m *Mongo) MarkNotificationRead(ctx context.Context, userId, id primitive.ObjectID) (*models.Notification, error) {
+	var notification models.Notification
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := Notifications().FindOneAndUpdate(ctx,
+		bson.M{"_id": id, "userId": userId},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{
+			"read":   true,
+			"readAt": bson.M{"$ifNull": bson.A{"$readAt", "$$NOW"}},
+		}}}},
+		opts,
+	).Decode(&notification)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &notification, nil
+}
+
+// MarkAllNotificationsRead marks every unread notification of a user as read
+// and returns how many there were
+func (m *Mongo) MarkAllNotificationsRead(ctx context.Context, userId primitive.ObjectID) (int64, error) {
+	result, err := Notifications().UpdateMany(ctx,
+		bson.M{"userId": userId, "read": false},
+		bson.M{"$set": bson.M{"read": true, "readAt": time.Now().UTC()}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// WatchTask subscribes a user to the completion of a task, watching it again is a no-op
+func (m *Mongo) WatchTask(ctx context.Context, taskId, userId primitive.ObjectID) error {
+	_, err := TaskWatches().UpdateOne(ctx,
+		bson.M{"taskId": taskId, "userId": userId},
+		bson.M{"$setOnInsert": bson.M{"createdAt": time.Now().UTC()}},
+		options.Update().SetUpsert(true),
+	)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+	return err
+}
+
+// UnwatchTask removes the subscription of a user to a task, if any
+func (m *Mongo) UnwatchTask(ctx context.Context, taskId, userId primitive.ObjectID) error {
+	_, err := TaskWatches().DeleteOne(ctx, bson.M{"taskId": taskId, "userId": userId})
+	return err
+}
+
+// TaskWatchers returns the users watching a task
+func TaskWatchers(ctx context.Context, taskId primitive.ObjectID) ([]primitive.ObjectID, error) {
+	cursor, err := TaskWatches().Find(ctx, bson.M{"taskId": taskId})
+	if err != nil {
+		return nil, err
+	}
+	var watches []models.TaskWatch
+	if err := cursor.All(ctx, &watches); err != nil {
+		return nil, err
+	}
+
+	users := make([]primitive.ObjectID, 0, len(watches))
+	for _, watch := range watches {
+		users = append(users, watch.UserID)
+	}
+	return users, nil
+}
@@ -0,0 +1,87 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/cmerin0/tasky/internal/db"
+	"github.com/cmerin0/tasky/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// EventBody is the JSON body of an outbox event
+type EventBody struct {
+	ID        primitive.ObjectID `json:"id"`
+	Type      string             `json:"type"`
+	CreatedAt time.Time          `json:"createdAt"`
+	Data      interface{}        `json:"data"`
+}
+
+// Enqueue writes an event to the outbox. Pass the session context of the
+// mutation the event describes, so both are committed or neither is.
+func Enqueue(ctx context.Context, eventType string, data interface{}) error {
+	body := EventBody{
+		ID:        primitive.NewObjectID(),
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	_, err = Outbox().InsertOne(ctx, models.OutboxEvent{
+		ID:        body.ID,
+		Type:      body.Type,
+		Payload:   payload,
+		CreatedAt: body.CreatedAt,
+	})
+	return err
+}
+
+// EnqueueTask writes the event of a task mutation, followed by a
+// task.assigned event when the task got a new owner and a task.completed
+// event when it got completed.
+// before is nil for creations and after is nil for deletions.
+func EnqueueTask(ctx context.Context, eventType string, before, after *models.Task) error {
+	data := after
+	if data == nil {
+		data = before
+	}
+	if err := Enqueue(ctx, eventType, data); err != nil {
+		return err
+	}
+	if after != nil && (before == nil || before.UserID != after.UserID) {
+		if err := Enqueue(ctx, models.EventTaskAssigned, after); err != nil {
+			return err
+		}
+	}
+	if before != nil && after != nil && !before.Completed && after.Completed {
+		return Enqueue(ctx, models.EventTaskCompleted, after)
+	}
+	return nil
+}
+
+// WithTransaction runs fn in a transaction, retrying it on transient errors.
+// fn must use the session context it is given for every read and write.
+// When ctx is already part of a transaction, fn joins it.
+func WithTransaction(ctx context.Context, fn func(sc mongo.SessionContext) error) error {
+	if session := mongo.SessionFromContext(ctx); session != nil {
+		return fn(mongo.NewSessionContext(ctx, session))
+	}
+
+	session, err := db.Client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+	return err
+}
@@ -0,0 +1,341 @@
+package store
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/cmerin0/tasky/internal/filter"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	MaxPageLimit     = 30 // Maximum number of items to return in a single request
+	DefaultPageLimit = 10 // Number of items returned when no limit is given
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Sortable fields per resource, keyed by the name clients use
+var (
+	TaskSortFields = map[string]string{"id": "_id", "title": "title", "completed": "completed"}
+	UserSortFields = map[string]string{"id": "_id", "name": "name", "email": "email"}
+	// Notifications are only listed newest first
+	NotificationSortFields = map[string]string{"id": "_id"}
+)
+
+// sortKeyTypes is the BSON type of the values each sortable field holds.
+// Cursor values come from clients, so anything else, such as a document
+// which would become a query operator, is refused.
+var sortKeyTypes = map[string]bsontype.Type{
+	"title":     bson.TypeString,
+	"completed": bson.TypeBoolean,
+	"name":      bson.TypeString,
+	"email":     bson.TypeString,
+	"status":    bson.TypeString,
+	"runAt":     bson.TypeDateTime,
+	"createdAt": bson.TypeDateTime,
+}
+
+// Filterable task fields, keyed by the name clients use
+var TaskFilterFields = filter.Fields{
+	"id":          {Name: "_id", Type: filter.ObjectID},
+	"title":       {Name: "title", Type: filter.String},
+	"description": {Name: "description", Type: filter.String},
+	"completed":   {Name: "completed", Type: filter.Bool},
+	"userId":      {Name: "userId", Type: filter.ObjectID},
+	"dueAt":       {Name: "dueAt", Type: filter.Time},
+}
+
+// Filterable user fields, keyed by the name clients use
+var UserFilterFields = filter.Fields{
+	"id":    {Name: "_id", Type: filter.ObjectID},
+	"name":  {Name: "name", Type: filter.String},
+	"email": {Name: "email", Type: filter.String},
+}
+
+// Cursor is the opaque position a page continues from.
+// It stores the sort key of the boundary item plus its _id as a tie breaker,
+// so pages stay stable while documents are inserted or deleted.
+type Cursor struct {
+	Field string             `bson:"f"`
+	Desc  bool               `bson:"d,omitempty"`
+	Value interface{}        `bson:"v"`
+	ID    primitive.ObjectID `bson:"i"`
+	Prev  bool               `bson:"p,omitempty"`
+}
+
+// PageRequest holds the pagination parameters of a request
+type PageRequest struct {
+	Limit  int
+	Field  string
+	Desc   bool
+	Cursor *Cursor
+	Count  bool
+}
+
+// Page is a single page of results
+type Page[T any] struct {
+	Items []T
+	Next  *Cursor
+	Prev  *Cursor
+	Total *int64
+}
+
+// EncodeCursor turns a cursor into an opaque URL safe token
+func EncodeCursor(cur *Cursor) string {
+	raw, err := bson.Marshal(cur)
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// DecodeCursor parses a token produced by EncodeCursor
+func DecodeCursor(token string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cur Cursor
+	if err := bson.Unmarshal(raw, &cur); err != nil || cur.Field == "" {
+		return nil, ErrInvalidCursor
+	}
+	return &cur, nil
+}
+
+// NewPageRequest builds a page request, clamping the limit to the allowed range
+func NewPageRequest(limit int, token, sort string, count bool, sortFields map[string]string) (PageRequest, error) {
+	req := PageRequest{Field: "_id", Limit: limit, Count: count}
+	if req.Limit < 1 {
+		req.Limit = DefaultPageLimit
+	}
+	if req.Limit > MaxPageLimit {
+		req.Limit = MaxPageLimit
+	}
+
+	// A cursor carries its own sort so links keep working without repeating
+	// it. Its field is used as is in queries, so it must be one clients may
+	// sort by, and the sort the request repeats.
+	if token != "" {
+		cur, err := DecodeCursor(token)
+		if err != nil {
+			return req, err
+		}
+		if !slices.Contains(slices.Collect(maps.Values(sortFields)), cur.Field) || !validCursorValue(cur) {
+			return req, ErrInvalidCursor
+		}
+		if sort != "" {
+			if err := req.ApplySort(sort, sortFields); err != nil {
+				return req, err
+			}
+			if req.Field != cur.Field || req.Desc != cur.Desc {
+				return req, errors.New("cursor doesn't match sort " + sort)
+			}
+		}
+		req.Cursor = cur
+		req.Field = cur.Field
+		req.Desc = cur.Desc
+		return req, nil
+	}
+
+	if sort != "" {
+		if err := req.ApplySort(sort, sortFields); err != nil {
+			return req, err
+		}
+	}
+
+	return req, nil
+}
+
+// validCursorValue reports whether the sort key of a cursor has the type of
+// its field. A missing value is allowed, documents may lack the field.
+// Cursors sorted by _id only carry the ID.
+func validCursorValue(cur *Cursor) bool {
+	if cur.Value == nil {
+		return true
+	}
+	want, ok := sortKeyTypes[cur.Field]
+	if !ok {
+		return false
+	}
+	switch cur.Value.(type) {
+	case string:
+		return want == bson.TypeString
+	case bool:
+		return want == bson.TypeBoolean
+	case primitive.DateTime:
+		return want == bson.TypeDateTime
+	}
+	return false
+}
+
+// ApplySort sets the sort of the request from a sort expression such as -title
+func (req *PageRequest) ApplySort(sort string, sortFields map[string]string) error {
+	field, ok := sortFields[strings.TrimPrefix(sort, "-")]
+	if !ok {
+		return errors.New("unsupported sort field: " + strings.TrimPrefix(sort, "-"))
+	}
+	req.Field = field
+	req.Desc = strings.HasPrefix(sort, "-")
+	return nil
+}
+
+// keysetFilter returns the condition selecting documents after (or before) the cursor
+func keysetFilter(req PageRequest) bson.M {
+	cur := req.Cursor
+	op := "$gt"
+	if req.Desc != cur.Prev {
+		op = "$lt"
+	}
+
+	if req.Field == "_id" {
+		return bson.M{"_id": bson.M{op: cur.ID}}
+	}
+	return bson.M{"$or": bson.A{
+		bson.M{req.Field: bson.M{op: cur.Value}},
+		bson.M{req.Field: cur.Value, "_id": bson.M{op: cur.ID}},
+	}}
+}
+
+// Paginate runs filter against the collection and returns one page of results.
+// projection may be nil to return whole documents.
+func Paginate[T any](ctx context.Context, coll *mongo.Collection, filter bson.M, projection bson.M, req PageRequest) (*Page[T], error) {
+	result := &Page[T]{Items: []T{}}
+
+	if req.Count {
+		total, err := coll.CountDocuments(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		result.Total = &total
+	}
+
+	query := filter
+	if req.Cursor != nil {
+		query = bson.M{"$and": bson.A{filter, keysetFilter(req)}}
+	}
+
+	// Walking backwards reverses the sort, the page is flipped back afterwards
+	backwards := req.Cursor != nil && req.Cursor.Prev
+	order := 1
+	if req.Desc != backwards {
+		order = -1
+	}
+	sort := bson.D{{Key: "_id", Value: order}}
+	if req.Field != "_id" {
+		sort = bson.D{{Key: req.Field, Value: order}, {Key: "_id", Value: order}}
+	}
+
+	opts := options.Find().SetSort(sort).SetLimit(int64(req.Limit + 1))
+	if projection != nil {
+		// The sort key is needed to build cursors even if the client didn't ask for it
+		withKey := bson.M{req.Field: 1}
+		for field, value := range projection {
+			withKey[field] = value
+		}
+		opts.SetProjection(withKey)
+	}
+
+	cursor, err := coll.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var docs []bson.Raw
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	more := len(docs) > req.Limit
+	if more {
+		docs = docs[:req.Limit]
+	}
+	if backwards {
+		for i, j := 0, len(docs)-1; i < j; i, j = i+1, j-1 {
+			docs[i], docs[j] = docs[j], docs[i]
+		}
+	}
+
+	for _, doc := range docs {
+		var item T
+		if err := bson.Unmarshal(doc, &item); err != nil {
+			return nil, err
+		}
+		result.Items = append(result.Items, item)
+	}
+
+	if len(docs) == 0 {
+		return result, nil
+	}
+
+	// There is a next page when more items follow, or when we came from it.
+	// Likewise for the previous page.
+	if (!backwards && more) || backwards {
+		result.Next = boundaryCursor(docs[len(docs)-1], req, false)
+	}
+	if (backwards && more) || (!backwards && req.Cursor != nil) {
+		result.Prev = boundaryCursor(docs[0], req, true)
+	}
+
+	return result, nil
+}
+
+// NewPage builds a page out of the items a backend fetched for a request,
+// for backends which don't page with Paginate. items come in the order the
+// request walks, which is reversed when going back, with one extra item
+// when more follow. key returns the sort key and ID of an item.
+func NewPage[T any](items []T, req PageRequest, key func(item T) (interface{}, primitive.ObjectID)) *Page[T] {
+	result := &Page[T]{Items: []T{}}
+
+	backwards := req.Cursor != nil && req.Cursor.Prev
+	more := len(items) > req.Limit
+	if more {
+		items = items[:req.Limit]
+	}
+	if backwards {
+		slices.Reverse(items)
+	}
+	result.Items = append(result.Items, items...)
+
+	if len(items) == 0 {
+		return result
+	}
+
+	boundary := func(item T, prev bool) *Cursor {
+		value, id := key(item)
+		cur := &Cursor{Field: req.Field, Desc: req.Desc, ID: id, Prev: prev}
+		if req.Field != "_id" {
+			cur.Value = value
+		}
+		return cur
+	}
+	// Same as Paginate: there is a next page when more items follow, or
+	// when we came from it. Likewise for the previous page.
+	if (!backwards && more) || backwards {
+		result.Next = boundary(items[len(items)-1], false)
+	}
+	if (backwards && more) || (!backwards && req.Cursor != nil) {
+		result.Prev = boundary(items[0], true)
+	}
+	return result
+}
+
+// boundaryCursor builds the cursor pointing after (or before) a document
+func boundaryCursor(doc bson.Raw, req PageRequest, prev bool) *Cursor {
+	cur := &Cursor{Field: req.Field, Desc: req.Desc, Prev: prev}
+	cur.ID, _ = doc.Lookup("_id").ObjectIDOK()
+	if req.Field != "_id" {
+		_ = doc.Lookup(req.Field).Unmarshal(&cur.Value)
+	}
+	return cur
+}
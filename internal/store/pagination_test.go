@@ -0,0 +1,51 @@
+package store
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestNewPageRequestCursorValue(t *testing.T) {
+	id := primitive.NewObjectID()
+	tests := []struct {
+		name   string
+		cursor *Cursor
+		fields map[string]string
+		valid  bool
+	}{
+		{"string key", &Cursor{Field: "title", Value: "Ship", ID: id}, TaskSortFields, true},
+		{"bool key", &Cursor{Field: "completed", Value: true, ID: id}, TaskSortFields, true},
+		{"time key", &Cursor{Field: "createdAt", Value: primitive.NewDateTimeFromTime(time.Now()), ID: id}, map[string]string{"createdAt": "createdAt"}, true},
+		{"missing key", &Cursor{Field: "title", ID: id}, TaskSortFields, true},
+		{"id", &Cursor{Field: "_id", ID: id}, TaskSortFields, true},
+		{"operator", &Cursor{Field: "title", Value: bson.M{"$ne": nil}, ID: id}, TaskSortFields, false},
+		{"value on id sort", &Cursor{Field: "_id", Value: bson.M{"$gt": ""}, ID: id}, TaskSortFields, false},
+		{"array", &Cursor{Field: "title", Value: bson.A{"a"}, ID: id}, TaskSortFields, false},
+		{"wrong type", &Cursor{Field: "completed", Value: "true", ID: id}, TaskSortFields, false},
+		{"number", &Cursor{Field: "title", Value: int32(1), ID: id}, TaskSortFields, false},
+		{"regex", &Cursor{Field: "name", Value: primitive.Regex{Pattern: "."}, ID: id}, UserSortFields, false},
+		{"unsortable field", &Cursor{Field: "description", Value: "x", ID: id}, TaskSortFields, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := NewPageRequest(10, EncodeCursor(tt.cursor), "", false, tt.fields)
+			if !tt.valid {
+				if !errors.Is(err, ErrInvalidCursor) {
+					t.Fatalf("got error %v, want %v", err, ErrInvalidCursor)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if req.Cursor == nil || req.Field != tt.cursor.Field {
+				t.Fatalf("request %+v doesn't continue from the cursor", req)
+			}
+		})
+	}
+}
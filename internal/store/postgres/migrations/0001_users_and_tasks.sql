@@ -0,0 +1,42 @@
+-- Users, tasks and the task history. IDs are ObjectID hex strings, text
+-- compares bytewise ("C") so sorting and ranges match MongoDB.
+
+CREATE TABLE users (
+    id       text COLLATE "C" PRIMARY KEY,
+    name     text COLLATE "C" NOT NULL,
+    email    text COLLATE "C" NOT NULL,
+    password text NOT NULL,
+    version  bigint NOT NULL
+);
+
+CREATE TABLE tasks (
+    id          text COLLATE "C" PRIMARY KEY,
+    title       text COLLATE "C" NOT NULL,
+    description text COLLATE "C" NOT NULL DEFAULT '',
+    completed   boolean NOT NULL DEFAULT false,
+    user_id     text COLLATE "C" NOT NULL,
+    due_at      timestamptz,
+    version     bigint NOT NULL,
+    -- Full text search, matches in titles rank higher
+    search      tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', title), 'A') ||
+        setweight(to_tsvector('english', description), 'D')
+    ) STORED
+);
+
+CREATE INDEX tasks_user_id ON tasks (user_id, id);
+-- Due date reminders look for open tasks by due date
+CREATE INDEX tasks_completed_due_at ON tasks (completed, due_at);
+CREATE INDEX tasks_search ON tasks USING gin (search);
+
+CREATE TABLE task_history (
+    id        text COLLATE "C" PRIMARY KEY,
+    task_id   text COLLATE "C" NOT NULL,
+    action    text NOT NULL,
+    actor     text NOT NULL,
+    timestamp timestamptz NOT NULL,
+    changes   jsonb NOT NULL,
+    snapshot  jsonb NOT NULL
+);
+
+CREATE INDEX task_history_task_id ON task_history (task_id, timestamp DESC);
@@ -0,0 +1,17 @@
+-- Saved task views. Columns are a JSON array, shared_with is empty for
+-- private views.
+
+CREATE TABLE views (
+    id          text COLLATE "C" PRIMARY KEY,
+    name        text NOT NULL,
+    owner_id    text COLLATE "C" NOT NULL,
+    filter      text NOT NULL DEFAULT '',
+    sort        text NOT NULL DEFAULT '',
+    columns     jsonb NOT NULL DEFAULT '[]',
+    visibility  text NOT NULL,
+    shared_with text NOT NULL DEFAULT ''
+);
+
+CREATE INDEX views_owner_id ON views (owner_id, id);
+-- Views shared with a project or workspace are listed by what they're shared with
+CREATE INDEX views_shared_with ON views (visibility, shared_with, id);
@@ -0,0 +1,86 @@
+// Package postgres stores users and tasks in PostgreSQL, through sqlstore.
+//
+// The schema is created and upgraded by the versioned migrations in
+// migrations/, applied when the store is opened. Rows about to be written
+// are locked with FOR UPDATE and words are searched with the tsvector of
+// the tasks_search index.
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cmerin0/tasky/internal/store"
+	"github.com/cmerin0/tasky/internal/store/sqlstore"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	_ "github.com/jackc/pgx/v5/stdlib" // Registers the pgx driver
+)
+
+// uniqueViolation is the SQLSTATE of a duplicate key
+const uniqueViolation = "23505"
+
+// Store holds users and tasks in PostgreSQL
+type Store = sqlstore.Store
+
+// dialect is the SQL of PostgreSQL
+var dialect = sqlstore.Dialect{
+	Placeholder: func(n int) string {
+		return "$" + strconv.Itoa(n)
+	},
+	// In UTC to the millisecond, like MongoDB keeps them
+	Time: func(t time.Time) interface{} {
+		return t.UTC().Truncate(time.Millisecond)
+	},
+	Lock: " FOR UPDATE",
+	IsUniqueViolation: func(err error) bool {
+		var pgErr *pgconn.PgError
+		return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+	},
+	TextSearch: textSearch,
+}
+
+// Open connects to the database at a postgres:// URL and migrates its schema
+func Open(ctx context.Context, url string) (*Store, error) {
+	db, err := sql.Open("pgx", url)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := migrate(ctx, db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrating the schema: %w", err)
+	}
+	return sqlstore.New(db, dialect), nil
+}
+
+// textSearch matches the words of a query against the search column.
+// Without phrases tasks must match a word, and they rank by every word,
+// phrases included.
+func textSearch(p *sqlstore.Params, query store.SearchQuery) (condition, score string) {
+	tsquery := func(words []string) string {
+		queries := make([]string, len(words))
+		for i, word := range words {
+			queries[i] = "plainto_tsquery('english', " + p.Add(word) + ")"
+		}
+		return "(" + strings.Join(queries, " || ") + ")"
+	}
+
+	score = "ts_rank(search, " + tsquery(append(query.Words, query.Phrases...)) + ")"
+	var conditions []string
+	if len(query.Phrases) == 0 {
+		conditions = append(conditions, "search @@ "+tsquery(query.Words))
+	}
+	for _, word := range query.NegatedWords {
+		conditions = append(conditions, "NOT search @@ "+tsquery([]string{word}))
+	}
+	return strings.Join(conditions, " AND "), score
+}
@@ -0,0 +1,43 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/cmerin0/tasky/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GetNotificationPreferences returns the preferences of a user,
+// or the defaults when they never saved any
+func GetNotificationPreferences(ctx context.Context, userId primitive.ObjectID) (*models.NotificationPreferences, error) {
+	var prefs models.NotificationPreferences
+	err := NotificationPreferences().FindOne(ctx, bson.M{"_id": userId}).Decode(&prefs)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		prefs = models.DefaultNotificationPreferences(userId)
+		return &prefs, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &prefs, nil
+}
+
+// GetNotificationPreferences returns the preferences of a user,
+// or the defaults when they never saved any
+func (m *Mongo) GetNotificationPreferences(ctx context.Context, userId primitive.ObjectID) (*models.NotificationPreferences, error) {
+	return GetNotificationPreferences(ctx, userId)
+}
+
+// SaveNotificationPreferences validates and stores the preferences of a user
+func (m *Mongo) SaveNotificationPreferences(ctx context.Context, prefs models.NotificationPreferences) error {
+	if err := Validate(prefs); err != nil {
+		return err
+	}
+	_, err := NotificationPreferences().ReplaceOne(ctx, bson.M{"_id": prefs.UserID}, prefs, options.Replace().SetUpsert(true))
+	return err
+}
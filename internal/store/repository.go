@@ -0,0 +1,175 @@
+package store
+
+import (
+	"context"
+
+	"github.com/cmerin0/tasky/internal/filter"
+	"github.com/cmerin0/tasky/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UserRepository stores users. Every implementation returns ErrNotFound
+// for missing users, ErrStale when a write expected another version and a
+// *ValidationError for invalid users. Passwords are written but never read
+// back, except by UpdateUser.
+//
+// Queries are filter expressions over UserFilterFields, nil matches every user.
+// versions lists the versions a write accepts, nil accepts any.
+type UserRepository interface {
+	GetUser(ctx context.Context, id primitive.ObjectID) (*models.UserResponse, error)
+	ListUsers(ctx context.Context, where filter.Expr, req PageRequest) (*Page[models.UserResponse], error)
+	// FindUsers returns every matching user, oldest first
+	FindUsers(ctx context.Context, where filter.Expr) ([]models.UserResponse, error)
+	CreateUser(ctx context.Context, user models.User) (*models.UserResponse, error)
+	ReplaceUser(ctx context.Context, id primitive.ObjectID, user models.User, versions []int64) (*models.UserResponse, error)
+	// UpdateUser reads a user, lets change modify it and writes the fields
+	// that changed, bumping its version. An error from change is returned as is.
+	UpdateUser(ctx context.Context, id primitive.ObjectID, versions []int64, change func(user *models.User) error) (*models.UserResponse, error)
+	DeleteUser(ctx context.Context, id primitive.ObjectID, versions []int64) error
+}
+
+// TaskRepository stores tasks along with their history. Every change is
+// recorded in the history under actor. Errors and versions work as in
+// UserRepository, queries are filter expressions over TaskFilterFields.
+type TaskRepository interface {
+	GetTask(ctx context.Context, id primitive.ObjectID) (*models.Task, error)
+	// ListTasks returns a page of tasks. fields lists the stored fields to
+	// read, the others are left empty; nil reads them all. The ID and the
+	// sort field are always read.
+	ListTasks(ctx context.Context, where filter.Expr, req PageRequest, fields []string) (*Page[models.Task], error)
+	// FindTasks returns every matching task, oldest first
+	FindTasks(ctx context.Context, where filter.Expr) ([]models.Task, error)
+	// SearchTasks returns up to limit matching tasks containing the words
+	// of text in their title or description, most relevant first
+	SearchTasks(ctx context.Context, text string, where filter.Expr, limit int) ([]TaskMatch, error)
+	CreateTask(ctx context.Context, task models.Task, actor string) (*models.Task, error)
+	ReplaceTask(ctx context.Context, id primitive.ObjectID, task models.Task, versions []int64, actor string) (*models.Task, error)
+	// UpdateTask reads a task, lets change modify it and writes the fields
+	// that changed, bumping its version. An error from change is returned as is.
+	UpdateTask(ctx context.Context, id primitive.ObjectID, versions []int64, change func(task *models.Task) error, actor string) (*models.Task, error)
+	DeleteTask(ctx context.Context, id primitive.ObjectID, versions []int64, actor string) error
+	// BulkTasks applies a batch of writes and returns the outcome of each,
+	// in order. With ordered set it stops at the first failure and the
+	// writes after it fail with ErrSkipped. An error fails the whole batch,
+	// none of its writes are applied.
+	BulkTasks(ctx context.Context, ops []BulkOp, ordered bool, actor string) ([]BulkResult, error)
+	// TaskHistory returns the history of a task, newest first
+	TaskHistory(ctx context.Context, id primitive.ObjectID) ([]models.TaskHistory, error)
+	// RevertTask restores a task, even a deleted one, to the snapshot of
+	// one of its history entries. It returns ErrNotFound when the task
+	// has no such entry.
+	RevertTask(ctx context.Context, id, entryId primitive.ObjectID, actor string) (*models.Task, error)
+	// InTransaction runs fn so that the writes it makes through the
+	// repository with the context it is given are applied all or none
+	InTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// Storage is a backend storing both users and tasks
+type Storage interface {
+	UserRepository
+	TaskRepository
+}
+
+// ViewRepository stores saved task views. Only their owner changes a view,
+// updating or deleting the view of someone else is ErrNotFound like a
+// missing one.
+type ViewRepository interface {
+	GetView(ctx context.Context, id primitive.ObjectID) (*models.View, error)
+	// ListViews returns the views of owner plus, when they aren't empty,
+	// the views shared with project or workspace, oldest first
+	ListViews(ctx context.Context, owner primitive.ObjectID, project, workspace string) ([]models.View, error)
+	CreateView(ctx context.Context, view models.View) (*models.View, error)
+	// UpdateView replaces the view with the ID of view, if view.OwnerID owns it
+	UpdateView(ctx context.Context, view models.View) error
+	DeleteView(ctx context.Context, id, owner primitive.ObjectID) error
+}
+
+// NotificationRepository stores the in-app notifications of users, their
+// notification preferences and the tasks they watch. Notifications are
+// only read and changed by their user, those of someone else are
+// ErrNotFound.
+type NotificationRepository interface {
+	// GetNotificationPreferences returns the preferences of a user,
+	// or the defaults when they never saved any
+	GetNotificationPreferences(ctx context.Context, userId primitive.ObjectID) (*models.NotificationPreferences, error)
+	SaveNotificationPreferences(ctx context.Context, prefs models.NotificationPreferences) error
+	// ListNotifications returns a page of the notifications of a user
+	ListNotifications(ctx context.Context, userId primitive.ObjectID, unreadOnly bool, req PageRequest) (*Page[models.Notification], error)
+	CountUnread(ctx context.Context, userId primitive.ObjectID) (int64, error)
+	// MarkNotificationRead marks a notification as read, marking it
+	// again keeps its first read time
+	MarkNotificationRead(ctx context.Context, userId, id primitive.ObjectID) (*models.Notification, error)
+	// MarkAllNotificationsRead returns how many notifications it marked
+	MarkAllNotificationsRead(ctx context.Context, userId primitive.ObjectID) (int64, error)
+	// WatchTask subscribes a user to a task, watching it again is a no-op
+	WatchTask(ctx context.Context, taskId, userId primitive.ObjectID) error
+	UnwatchTask(ctx context.Context, taskId, userId primitive.ObjectID) error
+}
+
+// WebhookRepository stores webhook subscriptions and their delivery log.
+// Webhooks are read back without their secret. Only their owner sees and
+// changes a webhook, the webhook of someone else is ErrNotFound like a
+// missing one, and so are missing deliveries.
+type WebhookRepository interface {
+	ListWebhooks(ctx context.Context, owner primitive.ObjectID) ([]models.Webhook, error)
+	GetWebhook(ctx context.Context, id, owner primitive.ObjectID) (*models.Webhook, error)
+	// CreateWebhook returns the webhook with its ID and secret
+	CreateWebhook(ctx context.Context, webhook models.Webhook) (*models.Webhook, error)
+	// UpdateWebhook replaces the URL, events and active flag of a webhook,
+	// and its secret when webhook has one, if webhook.OwnerID owns it
+	UpdateWebhook(ctx context.Context, id primitive.ObjectID, webhook models.Webhook) (*models.Webhook, error)
+	// DeleteWebhook deletes a webhook along with its delivery log
+	DeleteWebhook(ctx context.Context, id, owner primitive.ObjectID) error
+	// ListDeliveries returns a page of the deliveries of a webhook,
+	// only those with the given status when it isn't empty
+	ListDeliveries(ctx context.Context, webhookId primitive.ObjectID, status string, req PageRequest) (*Page[models.WebhookDelivery], error)
+	GetDelivery(ctx context.Context, webhookId, id primitive.ObjectID) (*models.WebhookDelivery, error)
+	// Redeliver queues a new delivery of the event and payload of a delivery
+	Redeliver(ctx context.Context, original *models.WebhookDelivery) (*models.WebhookDelivery, error)
+}
+
+// JobRepository lists background jobs and changes their status. Missing
+// jobs are ErrNotFound.
+type JobRepository interface {
+	// ListJobs returns a page of jobs, only those of the given type and
+	// status when they aren't empty
+	ListJobs(ctx context.Context, jobType, status string, req PageRequest) (*Page[models.Job], error)
+	GetJob(ctx context.Context, id primitive.ObjectID) (*models.Job, error)
+	// RetryJob makes a dead, cancelled or pending job run now, from its first attempt
+	RetryJob(ctx context.Context, id primitive.ObjectID) (*models.Job, error)
+	// CancelJob stops a pending job from running. A running job is left
+	// to finish but isn't retried.
+	CancelJob(ctx context.Context, id primitive.ObjectID) (*models.Job, error)
+}
+
+// Pinger is implemented by backends behind a connection, Ping reports
+// whether it is up
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// TaskMatch is a task found by a search with its relevance, higher is better
+type TaskMatch struct {
+	models.Task `bson:",inline"`
+	Score       float64 `bson:"score"`
+}
+
+// IDIn matches the users or tasks with one of the given IDs
+func IDIn(ids []primitive.ObjectID) filter.Expr {
+	return FieldIn(UserFilterFields, "id", ids)
+}
+
+// FieldIn matches when one of the ID fields holds any of the given IDs
+func FieldIn(fields filter.Fields, name string, ids []primitive.ObjectID) filter.Expr {
+	values := make([]interface{}, len(ids))
+	for i, id := range ids {
+		values[i] = id
+	}
+	return filter.In{Field: fields[name], Values: values}
+}
+
+// FieldIs matches when one of the fields equals value
+func FieldIs(fields filter.Fields, name string, value interface{}) filter.Expr {
+	return filter.Comparison{Field: fields[name], Op: filter.Eq, Value: value}
+}
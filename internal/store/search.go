@@ -0,0 +1,55 @@
+package store
+
+import (
+	"strings"
+	"unicode"
+)
+
+// SearchQuery is a text search as SearchTasks takes it, in the syntax of
+// MongoDB $text: tasks must contain every phrase, or any word when there
+// are no phrases, and none of the negated words and phrases.
+// Everything is lower case.
+type SearchQuery struct {
+	Words          []string
+	Phrases        []string
+	NegatedWords   []string
+	NegatedPhrases []string
+}
+
+// ParseSearch splits a text search into words and "quoted phrases",
+// either prefixed with - to negate it
+func ParseSearch(text string) SearchQuery {
+	var q SearchQuery
+	text = strings.TrimSpace(strings.ToLower(text))
+	for text != "" {
+		negated := strings.HasPrefix(text, "-")
+		rest := strings.TrimPrefix(text, "-")
+
+		var term string
+		phrase := strings.HasPrefix(rest, `"`)
+		if phrase {
+			term, text, _ = strings.Cut(rest[1:], `"`)
+			term = strings.TrimSpace(term)
+		} else if i := strings.IndexFunc(rest, unicode.IsSpace); i >= 0 {
+			term, text = rest[:i], rest[i:]
+		} else {
+			term, text = rest, ""
+		}
+		text = strings.TrimLeftFunc(text, unicode.IsSpace)
+		if term == "" {
+			continue
+		}
+
+		switch {
+		case phrase && negated:
+			q.NegatedPhrases = append(q.NegatedPhrases, term)
+		case phrase:
+			q.Phrases = append(q.Phrases, term)
+		case negated:
+			q.NegatedWords = append(q.NegatedWords, term)
+		default:
+			q.Words = append(q.Words, term)
+		}
+	}
+	return q
+}
@@ -0,0 +1,37 @@
+-- Users, tasks and the task history. IDs are ObjectID hex strings, times
+-- are Unix milliseconds in UTC and text compares bytewise, so sorting and
+-- ranges match MongoDB.
+
+CREATE TABLE users (
+    id       TEXT PRIMARY KEY,
+    name     TEXT NOT NULL,
+    email    TEXT NOT NULL,
+    password TEXT NOT NULL,
+    version  INTEGER NOT NULL
+) STRICT;
+
+CREATE TABLE tasks (
+    id          TEXT PRIMARY KEY,
+    title       TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    completed   INTEGER NOT NULL DEFAULT 0,
+    user_id     TEXT NOT NULL,
+    due_at      INTEGER,
+    version     INTEGER NOT NULL
+) STRICT;
+
+CREATE INDEX tasks_user_id ON tasks (user_id, id);
+-- Due date reminders look for open tasks by due date
+CREATE INDEX tasks_completed_due_at ON tasks (completed, due_at);
+
+CREATE TABLE task_history (
+    id        TEXT PRIMARY KEY,
+    task_id   TEXT NOT NULL,
+    action    TEXT NOT NULL,
+    actor     TEXT NOT NULL,
+    timestamp INTEGER NOT NULL,
+    changes   TEXT NOT NULL,
+    snapshot  TEXT NOT NULL
+) STRICT;
+
+CREATE INDEX task_history_task_id ON task_history (task_id, timestamp DESC);
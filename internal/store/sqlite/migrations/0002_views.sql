@@ -0,0 +1,17 @@
+-- Saved task views. Columns are a JSON array, shared_with is empty for
+-- private views.
+
+CREATE TABLE views (
+    id          TEXT PRIMARY KEY,
+    name        TEXT NOT NULL,
+    owner_id    TEXT NOT NULL,
+    filter      TEXT NOT NULL DEFAULT '',
+    sort        TEXT NOT NULL DEFAULT '',
+    columns     TEXT NOT NULL DEFAULT '[]',
+    visibility  TEXT NOT NULL,
+    shared_with TEXT NOT NULL DEFAULT ''
+) STRICT;
+
+CREATE INDEX views_owner_id ON views (owner_id, id);
+-- Views shared with a project or workspace are listed by what they're shared with
+CREATE INDEX views_shared_with ON views (visibility, shared_with, id);
@@ -0,0 +1,70 @@
+// Package sqlite stores users and tasks in a SQLite file, through sqlstore,
+// for deployments without a database server. The driver is pure Go, so the
+// binary stays statically linked.
+//
+// The schema is created and upgraded by the versioned migrations in
+// migrations/, applied when the store is opened. Times are stored as Unix
+// milliseconds. Transactions take the write lock when they start, so rows
+// aren't locked one by one, and words are searched in process.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/cmerin0/tasky/internal/store/sqlstore"
+
+	_ "modernc.org/sqlite" // Registers the sqlite driver
+)
+
+// Store holds users and tasks in a SQLite database
+type Store = sqlstore.Store
+
+// dialect is the SQL of SQLite
+var dialect = sqlstore.Dialect{
+	Placeholder: func(n int) string {
+		return "$" + strconv.Itoa(n)
+	},
+	Time: func(t time.Time) interface{} {
+		return t.UnixMilli()
+	},
+}
+
+// Open opens the database file at path, creating it if needed, and
+// migrates its schema
+func Open(ctx context.Context, path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+
+	// Writers wait for each other instead of failing, and take the write
+	// lock when their transaction starts so they can't deadlock upgrading it
+	options := url.Values{
+		"_pragma": {"busy_timeout(5000)", "journal_mode(WAL)", "synchronous(NORMAL)"},
+		"_txlock": {"immediate"},
+	}
+	db, err := sql.Open("sqlite", "file:"+path+"?"+options.Encode())
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite has a single writer anyway, one connection queues everything
+	// in process so transactions never see each other
+	db.SetMaxOpenConns(1)
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := migrate(ctx, db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrating the schema: %w", err)
+	}
+	return sqlstore.New(db, dialect), nil
+}
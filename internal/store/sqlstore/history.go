@@ -0,0 +1,150 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/cmerin0/tasky/internal/models"
+	"github.com/cmerin0/tasky/internal/store"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TaskHistory returns the history of a task, newest first
+func (s *Store) TaskHistory(ctx context.Context, id primitive.ObjectID) ([]models.TaskHistory, error) {
+	p := s.params()
+	rows, err := s.conn(ctx).QueryContext(ctx,
+		"SELECT id, action, actor, timestamp, changes, snapshot FROM task_history"+
+			" WHERE task_id = "+p.Add(id)+" ORDER BY timestamp DESC, id DESC", p.Args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []models.TaskHistory{}
+	for rows.Next() {
+		entry := models.TaskHistory{TaskID: id}
+		var entryID string
+		var timestamp nullTime
+		var changes, snapshot []byte
+		if err := rows.Scan(&entryID, &entry.Action, &entry.Actor, &timestamp, &changes, &snapshot); err != nil {
+			return nil, err
+		}
+		if entry.ID, err = parseID(entryID); err != nil {
+			return nil, err
+		}
+		if timestamp.time != nil {
+			entry.Timestamp = *timestamp.time
+		}
+		if err := json.Unmarshal(changes, &entry.Changes); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(snapshot, &entry.Snapshot); err != nil {
+			return nil, err
+		}
+		history = append(history, entry)
+	}
+	return history, rows.Err()
+}
+
+// RevertTask restores a task, even a deleted one, to the snapshot of one
+// of its history entries
+func (s *Store) RevertTask(ctx context.Context, id, entryId primitive.ObjectID, actor string) (*models.Task, error) {
+	var reverted models.Task
+	err := s.InTransaction(ctx, func(ctx context.Context) error {
+		var snapshot []byte
+		p := s.params()
+		err := s.conn(ctx).QueryRowContext(ctx,
+			"SELECT snapshot FROM task_history WHERE id = "+p.Add(entryId)+" AND task_id = "+p.Add(id), p.Args...,
+		).Scan(&snapshot)
+		if errors.Is(err, sql.ErrNoRows) {
+			return store.ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(snapshot, &reverted); err != nil {
+			return err
+		}
+		reverted.ID = id
+		reverted.DueAt = storedTime(reverted.DueAt)
+
+		// A revert is a new version of the task, not a rollback of the counter
+		before, err := s.lockTask(ctx, id, nil)
+		switch {
+		case err == nil:
+			reverted.Version = before.Version + 1
+			if err := s.saveTask(ctx, reverted); err != nil {
+				return err
+			}
+			return s.recordHistory(ctx, models.HistoryReverted, actor, &before, &reverted)
+		case errors.Is(err, store.ErrNotFound):
+			// Deleted tasks come back, unless another revert beat us to it,
+			// and carry on from the version they were deleted at
+			version, err := s.lastVersion(ctx, id)
+			if err != nil {
+				return err
+			}
+			reverted.Version = version + 1
+			err = s.insertTask(ctx, reverted)
+			if err != nil && s.dialect.IsUniqueViolation != nil && s.dialect.IsUniqueViolation(err) {
+				return store.ErrStale
+			}
+			if err != nil {
+				return err
+			}
+			return s.recordHistory(ctx, models.HistoryReverted, actor, nil, &reverted)
+		default:
+			return err
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &reverted, nil
+}
+
+// lastVersion returns the version of a task in its newest history entry
+func (s *Store) lastVersion(ctx context.Context, id primitive.ObjectID) (int64, error) {
+	var snapshot []byte
+	p := s.params()
+	err := s.conn(ctx).QueryRowContext(ctx,
+		"SELECT snapshot FROM task_history WHERE task_id = "+p.Add(id)+" ORDER BY timestamp DESC, id DESC LIMIT 1", p.Args...,
+	).Scan(&snapshot)
+	if err != nil {
+		return 0, err
+	}
+	var last models.Task
+	if err := json.Unmarshal(snapshot, &last); err != nil {
+		return 0, err
+	}
+	return last.Version, nil
+}
+
+// recordHistory stores a history entry for a task mutation, in the
+// transaction of the mutation. before is nil for creations and after is
+// nil for deletions.
+func (s *Store) recordHistory(ctx context.Context, action, actor string, before, after *models.Task) error {
+	// Deletions keep the last known state so they can be reverted
+	snapshot := after
+	if snapshot == nil {
+		snapshot = before
+	}
+
+	changes, err := json.Marshal(store.DiffTasks(before, after))
+	if err != nil {
+		return err
+	}
+	state, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	// JSON is passed as text, which both JSON and text columns take
+	p := s.params()
+	return s.exec(ctx, "INSERT INTO task_history (id, task_id, action, actor, timestamp, changes, snapshot) VALUES ("+
+		p.List(primitive.NewObjectID(), snapshot.ID, action, actor, time.Now(), string(changes), string(state))+")", p)
+}
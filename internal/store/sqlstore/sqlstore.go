@@ -0,0 +1,221 @@
+// Package sqlstore stores users, tasks and saved views in a SQL database
+// through database/sql. The postgres and sqlite packages open it with
+// their Dialect, which covers what their SQL doesn't share, and their own
+// migrations.
+//
+// IDs are ObjectIDs kept as their hex string, so clients see the same IDs
+// as with MongoDB. It follows the MongoDB backend: the same validation,
+// versioning, task history, pagination, filtering and search syntax, and
+// the same errors. Changes aren't relayed as events, so webhooks and
+// notifications stay silent.
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/cmerin0/tasky/internal/store"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Dialect is what differs between the SQL databases of a Store
+type Dialect struct {
+	// Placeholder returns the placeholder of the nth argument of a query,
+	// counting from 1
+	Placeholder func(n int) string
+	// Time returns a time as it is stored
+	Time func(t time.Time) interface{}
+	// Lock is appended to the queries reading rows about to be written,
+	// to lock them until the end of the transaction
+	Lock string
+	// IsUniqueViolation reports whether an error is a duplicate key, nil
+	// when writers can't race each other to insert a row
+	IsUniqueViolation func(err error) bool
+	// TextSearch, when set, matches and ranks tasks in the database. It
+	// returns the condition matching tasks meet, for the words only, and
+	// the expression of their score. Without it tasks are scored in process.
+	TextSearch func(p *Params, query store.SearchQuery) (condition, score string)
+}
+
+// Store holds users and tasks in a SQL database
+type Store struct {
+	db      *sql.DB
+	dialect Dialect
+}
+
+var (
+	_ store.Storage = (*Store)(nil)
+	_ store.Pinger  = (*Store)(nil)
+)
+
+// New returns a store of the migrated database db
+func New(db *sql.DB, dialect Dialect) *Store {
+	return &Store{db: db, dialect: dialect}
+}
+
+// Close closes the database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Ping checks the database can be reached
+func (s *Store) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// querier runs queries, either on the database or in a transaction
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// txKey marks the context of a transaction
+type txKey struct{}
+
+// transaction is a transaction of a store
+type transaction struct {
+	store *Store
+	tx    *sql.Tx
+}
+
+// tx returns the transaction of this store ctx belongs to, if any
+func (s *Store) tx(ctx context.Context) *sql.Tx {
+	if t, ok := ctx.Value(txKey{}).(*transaction); ok && t.store == s {
+		return t.tx
+	}
+	return nil
+}
+
+// conn returns where to run the queries of a call: its transaction, if
+// it is part of one, or else the database. Calls made during a transaction
+// must be part of it, the transaction may hold the only connection.
+func (s *Store) conn(ctx context.Context) querier {
+	if tx := s.tx(ctx); tx != nil {
+		return tx
+	}
+	return s.db
+}
+
+// InTransaction runs fn in a transaction, calls made with the context it
+// is given join it
+func (s *Store) InTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if s.tx(ctx) != nil {
+		return fn(ctx)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(context.WithValue(ctx, txKey{}, &transaction{store: s, tx: tx})); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// exec runs a statement built with p
+func (s *Store) exec(ctx context.Context, query string, p *Params) error {
+	_, err := s.conn(ctx).ExecContext(ctx, query, p.Args...)
+	return err
+}
+
+// params starts the arguments of a query
+func (s *Store) params() *Params {
+	return &Params{dialect: &s.dialect}
+}
+
+// Params collects the arguments of a query. Arguments are added in the
+// order they appear in the query, placeholders may be positional.
+type Params struct {
+	Args    []interface{}
+	dialect *Dialect
+}
+
+// Add adds an argument and returns its placeholder. IDs are stored as hex
+// strings and times as the dialect keeps them.
+func (p *Params) Add(value interface{}) string {
+	switch v := value.(type) {
+	case primitive.ObjectID:
+		value = v.Hex()
+	case time.Time:
+		value = p.dialect.Time(v)
+	case *time.Time:
+		if v == nil {
+			value = nil
+		} else {
+			value = p.dialect.Time(*v)
+		}
+	}
+	p.Args = append(p.Args, value)
+	return p.dialect.Placeholder(len(p.Args))
+}
+
+// List adds arguments and returns their placeholders, separated by commas
+func (p *Params) List(values ...interface{}) string {
+	list := ""
+	for i, value := range values {
+		if i > 0 {
+			list += ", "
+		}
+		list += p.Add(value)
+	}
+	return list
+}
+
+// storedTime returns a copy of a time as it is stored: in UTC, to the millisecond
+func storedTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	stored := time.UnixMilli(t.UnixMilli()).UTC()
+	return &stored
+}
+
+// nullTime scans a stored time, whether the driver returns it as a time or
+// as Unix milliseconds
+type nullTime struct {
+	time *time.Time
+}
+
+func (t *nullTime) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		t.time = nil
+	case time.Time:
+		t.time = storedTime(&v)
+	case int64:
+		stored := time.UnixMilli(v).UTC()
+		t.time = &stored
+	default:
+		return fmt.Errorf("unsupported stored time %T", src)
+	}
+	return nil
+}
+
+// parseID parses a stored ID
+func parseID(hex string) (primitive.ObjectID, error) {
+	id, err := primitive.ObjectIDFromHex(hex)
+	if err != nil {
+		return id, fmt.Errorf("invalid stored ID %q: %w", hex, err)
+	}
+	return id, nil
+}
+
+// versionMatches reports whether a stored version is one of the accepted
+// versions, nil accepts any
+func versionMatches(version int64, versions []int64) bool {
+	if versions == nil {
+		return true
+	}
+	for _, v := range versions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
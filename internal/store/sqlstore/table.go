@@ -0,0 +1,183 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/cmerin0/tasky/internal/filter"
+	"github.com/cmerin0/tasky/internal/store"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// scanner reads a row, either a *sql.Row or *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// table reads the rows of a table as T
+type table[T any] struct {
+	name string
+	// all lists every stored field, the ID first
+	all []string
+	// fields maps the stored fields queries use to columns
+	fields map[string]string
+	// scan reads a row holding the columns of the given stored fields, in order
+	scan func(row scanner, fields []string) (T, error)
+	// field returns the value of a stored field of an item, for cursors
+	field func(item T, name string) interface{}
+}
+
+// selection returns the stored fields to read: the ID and the known
+// fields of a projection, or every field for nil
+func (t table[T]) selection(fields []string) []string {
+	if fields == nil {
+		return t.all
+	}
+	selected := []string{"_id"}
+	for _, field := range fields {
+		if _, ok := t.fields[field]; ok && !slices.Contains(selected, field) {
+			selected = append(selected, field)
+		}
+	}
+	return selected
+}
+
+// columns returns the columns of stored fields, for a SELECT
+func (t table[T]) columns(fields []string) string {
+	columns := make([]string, len(fields))
+	for i, field := range fields {
+		columns[i] = t.fields[field]
+	}
+	return strings.Join(columns, ", ")
+}
+
+// column returns the column of a stored field. Unknown fields are
+// missing, which is NULL.
+func (t table[T]) column(field string) string {
+	if column, ok := t.fields[field]; ok {
+		return column
+	}
+	return "NULL"
+}
+
+// condition compiles a query into a WHERE condition
+func (t table[T]) condition(where filter.Expr, p *Params) string {
+	return filter.ToSQL(where, t.column, p.Add)
+}
+
+// query runs a query selecting the columns of fields from rows of the table
+func (t table[T]) query(ctx context.Context, s *Store, query string, p *Params, fields []string) ([]T, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx, query, p.Args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []T{}
+	for rows.Next() {
+		item, err := t.scan(rows, fields)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// get returns a row by ID
+func (t table[T]) get(ctx context.Context, s *Store, id primitive.ObjectID) (*T, error) {
+	p := s.params()
+	item, err := t.scan(s.conn(ctx).QueryRowContext(ctx,
+		"SELECT "+t.columns(t.all)+" FROM "+t.name+" WHERE id = "+p.Add(id), p.Args...), t.all)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, store.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// lock reads a row by ID at one of the versions, locking it until the end
+// of the transaction when the dialect can
+func (t table[T]) lock(ctx context.Context, s *Store, id primitive.ObjectID, versions []int64, version func(item T) int64) (T, error) {
+	p := s.params()
+	item, err := t.scan(s.conn(ctx).QueryRowContext(ctx,
+		"SELECT "+t.columns(t.all)+" FROM "+t.name+" WHERE id = "+p.Add(id)+s.dialect.Lock, p.Args...), t.all)
+	if errors.Is(err, sql.ErrNoRows) {
+		return item, store.ErrNotFound
+	}
+	if err != nil {
+		return item, err
+	}
+	if !versionMatches(version(item), versions) {
+		return item, store.ErrStale
+	}
+	return item, nil
+}
+
+// find returns every matching row, oldest first
+func (t table[T]) find(ctx context.Context, s *Store, where filter.Expr) ([]T, error) {
+	p := s.params()
+	return t.query(ctx, s, "SELECT "+t.columns(t.all)+" FROM "+t.name+" WHERE "+t.condition(where, p)+" ORDER BY id", p, t.all)
+}
+
+// page returns one page of matching rows the way store.Paginate does:
+// sorted by the requested field with the ID as tie breaker, continuing
+// from the cursor. Only the ID, the sort field and fields are read, nil
+// reads every field.
+func (t table[T]) page(ctx context.Context, s *Store, where filter.Expr, req store.PageRequest, fields []string) (*store.Page[T], error) {
+	if fields != nil {
+		fields = append(slices.Clip(fields), req.Field)
+	}
+	selected := t.selection(fields)
+
+	p := s.params()
+	condition := t.condition(where, p)
+
+	var total *int64
+	if req.Count {
+		total = new(int64)
+		if err := s.conn(ctx).QueryRowContext(ctx, "SELECT count(*) FROM "+t.name+" WHERE "+condition, p.Args...).Scan(total); err != nil {
+			return nil, err
+		}
+	}
+
+	// Walking backwards reverses the sort, NewPage flips the page back
+	backwards := req.Cursor != nil && req.Cursor.Prev
+	op, dir := ">", " ASC"
+	if req.Desc != backwards {
+		op, dir = "<", " DESC"
+	}
+
+	column := t.column(req.Field)
+	order := "id" + dir
+	if req.Field != "_id" {
+		order = column + dir + ", " + order
+	}
+	if cur := req.Cursor; cur != nil {
+		if req.Field == "_id" {
+			condition += " AND id " + op + " " + p.Add(cur.ID)
+		} else {
+			condition += " AND (" + column + " " + op + " " + p.Add(cur.Value) +
+				" OR (" + column + " = " + p.Add(cur.Value) + " AND id " + op + " " + p.Add(cur.ID) + "))"
+		}
+	}
+
+	items, err := t.query(ctx, s, "SELECT "+t.columns(selected)+" FROM "+t.name+
+		" WHERE "+condition+" ORDER BY "+order+" LIMIT "+strconv.Itoa(req.Limit+1), p, selected)
+	if err != nil {
+		return nil, err
+	}
+
+	result := store.NewPage(items, req, func(item T) (interface{}, primitive.ObjectID) {
+		return t.field(item, req.Field), t.field(item, "_id").(primitive.ObjectID)
+	})
+	result.Total = total
+	return result, nil
+}
@@ -0,0 +1,317 @@
+package sqlstore
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/cmerin0/tasky/internal/filter"
+	"github.com/cmerin0/tasky/internal/models"
+	"github.com/cmerin0/tasky/internal/store"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// tasks reads tasks
+var tasks = table[models.Task]{
+	name: "tasks",
+	all:  []string{"_id", "title", "description", "completed", "userId", "dueAt", "version"},
+	fields: map[string]string{
+		"_id":         "id",
+		"title":       "title",
+		"description": "description",
+		"completed":   "completed",
+		"userId":      "user_id",
+		"dueAt":       "due_at",
+		"version":     "version",
+	},
+	scan: func(row scanner, fields []string) (models.Task, error) {
+		var task models.Task
+		var id, userID string
+		var dueAt nullTime
+		dest := make([]interface{}, len(fields))
+		for i, field := range fields {
+			switch field {
+			case "_id":
+				dest[i] = &id
+			case "title":
+				dest[i] = &task.Title
+			case "description":
+				dest[i] = &task.Description
+			case "completed":
+				dest[i] = &task.Completed
+			case "userId":
+				dest[i] = &userID
+			case "dueAt":
+				dest[i] = &dueAt
+			case "version":
+				dest[i] = &task.Version
+			}
+		}
+		if err := row.Scan(dest...); err != nil {
+			return task, err
+		}
+		task.DueAt = dueAt.time
+		var err error
+		if task.ID, err = parseID(id); err != nil {
+			return task, err
+		}
+		if userID != "" {
+			task.UserID, err = parseID(userID)
+		}
+		return task, err
+	},
+	field: func(task models.Task, name string) interface{} {
+		switch name {
+		case "_id":
+			return task.ID
+		case "title":
+			return task.Title
+		case "description":
+			return task.Description
+		case "completed":
+			return task.Completed
+		case "userId":
+			return task.UserID
+		case "dueAt":
+			if task.DueAt != nil {
+				return *task.DueAt
+			}
+		case "version":
+			return task.Version
+		}
+		return nil
+	},
+}
+
+// taskVersion returns the version of a task, for locking
+func taskVersion(task models.Task) int64 {
+	return task.Version
+}
+
+// lockTask reads a task at one of the versions, locking it until the end
+// of the transaction when the dialect can
+func (s *Store) lockTask(ctx context.Context, id primitive.ObjectID, versions []int64) (models.Task, error) {
+	return tasks.lock(ctx, s, id, versions, taskVersion)
+}
+
+// GetTask returns a task by ID
+func (s *Store) GetTask(ctx context.Context, id primitive.ObjectID) (*models.Task, error) {
+	return tasks.get(ctx, s, id)
+}
+
+// ListTasks returns a page of tasks, selecting only the columns of fields
+func (s *Store) ListTasks(ctx context.Context, where filter.Expr, req store.PageRequest, fields []string) (*store.Page[models.Task], error) {
+	return tasks.page(ctx, s, where, req, fields)
+}
+
+// FindTasks returns every matching task, oldest first
+func (s *Store) FindTasks(ctx context.Context, where filter.Expr) ([]models.Task, error) {
+	return tasks.find(ctx, s, where)
+}
+
+// scoredRow reads a task row followed by its search score
+type scoredRow struct {
+	row   scanner
+	score *float64
+}
+
+func (r scoredRow) Scan(dest ...interface{}) error {
+	return r.row.Scan(append(dest, r.score)...)
+}
+
+// SearchTasks runs a full text search. text follows the $text syntax of
+// MongoDB: words match any, "quoted phrases" must all appear and -negated
+// words or phrases must not.
+//
+// Phrases are matched as is in the database, like MongoDB does. Words are
+// matched by the TextSearch of the dialect or else scored in process the
+// way the tasks_text index of MongoDB does.
+func (s *Store) SearchTasks(ctx context.Context, text string, where filter.Expr, limit int) ([]store.TaskMatch, error) {
+	query := store.ParseSearch(text)
+	if len(query.Words) == 0 && len(query.Phrases) == 0 {
+		return []store.TaskMatch{}, nil
+	}
+
+	contains := func(phrase string) filter.Expr {
+		return filter.Or{Terms: []filter.Expr{
+			filter.Comparison{Field: store.TaskFilterFields["title"], Op: filter.Match, Value: phrase},
+			filter.Comparison{Field: store.TaskFilterFields["description"], Op: filter.Match, Value: phrase},
+		}}
+	}
+	for _, phrase := range query.Phrases {
+		where = filter.AllOf(where, contains(phrase))
+	}
+	for _, phrase := range query.NegatedPhrases {
+		where = filter.AllOf(where, filter.Not{Term: contains(phrase)})
+	}
+
+	if s.dialect.TextSearch == nil {
+		candidates, err := tasks.find(ctx, s, where)
+		if err != nil {
+			return nil, err
+		}
+
+		// Equal scores keep a stable order, oldest first
+		search := store.NewTextSearch(text)
+		matches := []store.TaskMatch{}
+		for _, task := range candidates {
+			if score, ok := search.Score(task); ok {
+				matches = append(matches, store.TaskMatch{Task: task, Score: score})
+			}
+		}
+		return store.RankMatches(matches, limit), nil
+	}
+
+	p := s.params()
+	match, score := s.dialect.TextSearch(p, query)
+	condition := tasks.condition(where, p)
+	if match != "" {
+		condition = match + " AND " + condition
+	}
+	rows, err := s.conn(ctx).QueryContext(ctx, "SELECT "+tasks.columns(tasks.all)+", "+score+" AS score FROM tasks"+
+		" WHERE "+condition+" ORDER BY score DESC, id LIMIT "+strconv.Itoa(limit), p.Args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	matches := []store.TaskMatch{}
+	for rows.Next() {
+		var match store.TaskMatch
+		if match.Task, err = tasks.scan(scoredRow{row: rows, score: &match.Score}, tasks.all); err != nil {
+			return nil, err
+		}
+		matches = append(matches, match)
+	}
+	return matches, rows.Err()
+}
+
+// CreateTask validates and stores a new task at version 1
+func (s *Store) CreateTask(ctx context.Context, task models.Task, actor string) (*models.Task, error) {
+	if err := store.Validate(task); err != nil {
+		return nil, err
+	}
+
+	newTask := models.Task{
+		ID:          primitive.NewObjectID(),
+		Title:       task.Title,
+		Description: task.Description,
+		Completed:   task.Completed,
+		UserID:      task.UserID,
+		DueAt:       storedTime(task.DueAt),
+		Version:     1,
+	}
+	err := s.InTransaction(ctx, func(ctx context.Context) error {
+		if err := s.insertTask(ctx, newTask); err != nil {
+			return err
+		}
+		return s.recordHistory(ctx, models.HistoryCreated, actor, nil, &newTask)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &newTask, nil
+}
+
+// ReplaceTask validates and replaces every field of a task, bumping its version
+func (s *Store) ReplaceTask(ctx context.Context, id primitive.ObjectID, task models.Task, versions []int64, actor string) (*models.Task, error) {
+	task.ID = id
+	if err := store.Validate(task); err != nil {
+		return nil, err
+	}
+
+	task.DueAt = storedTime(task.DueAt)
+	err := s.InTransaction(ctx, func(ctx context.Context) error {
+		before, err := s.lockTask(ctx, id, versions)
+		if err != nil {
+			return err
+		}
+		task.Version = before.Version + 1
+		if err := s.saveTask(ctx, task); err != nil {
+			return err
+		}
+		return s.recordHistory(ctx, models.HistoryUpdated, actor, &before, &task)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// UpdateTask writes the fields change modified
+func (s *Store) UpdateTask(ctx context.Context, id primitive.ObjectID, versions []int64, change func(task *models.Task) error, actor string) (*models.Task, error) {
+	var after models.Task
+	err := s.InTransaction(ctx, func(ctx context.Context) error {
+		before, err := s.lockTask(ctx, id, versions)
+		if err != nil {
+			return err
+		}
+
+		after = before
+		if err := change(&after); err != nil {
+			return err
+		}
+		after.ID, after.Version = before.ID, before.Version
+		after.DueAt = storedTime(after.DueAt)
+		if err := store.Validate(after); err != nil {
+			return err
+		}
+
+		if len(store.DiffTasks(&before, &after)) == 0 {
+			return nil
+		}
+		after.Version++
+		if err := s.saveTask(ctx, after); err != nil {
+			return err
+		}
+		return s.recordHistory(ctx, models.HistoryUpdated, actor, &before, &after)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &after, nil
+}
+
+// BulkTasks applies a batch one write at a time, in a transaction
+func (s *Store) BulkTasks(ctx context.Context, ops []store.BulkOp, ordered bool, actor string) ([]store.BulkResult, error) {
+	var results []store.BulkResult
+	err := s.InTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		results, err = store.ApplyBulk(ctx, s, ops, ordered, actor)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// DeleteTask deletes a task
+func (s *Store) DeleteTask(ctx context.Context, id primitive.ObjectID, versions []int64, actor string) error {
+	return s.InTransaction(ctx, func(ctx context.Context) error {
+		deleted, err := s.lockTask(ctx, id, versions)
+		if err != nil {
+			return err
+		}
+		p := s.params()
+		if err := s.exec(ctx, "DELETE FROM tasks WHERE id = "+p.Add(id), p); err != nil {
+			return err
+		}
+		return s.recordHistory(ctx, models.HistoryDeleted, actor, &deleted, nil)
+	})
+}
+
+// insertTask inserts a task
+func (s *Store) insertTask(ctx context.Context, task models.Task) error {
+	p := s.params()
+	return s.exec(ctx, "INSERT INTO tasks ("+tasks.columns(tasks.all)+") VALUES ("+
+		p.List(task.ID, task.Title, task.Description, task.Completed, task.UserID, task.DueAt, task.Version)+")", p)
+}
+
+// saveTask writes every field of a locked task
+func (s *Store) saveTask(ctx context.Context, task models.Task) error {
+	p := s.params()
+	return s.exec(ctx, "UPDATE tasks SET title = "+p.Add(task.Title)+", description = "+p.Add(task.Description)+
+		", completed = "+p.Add(task.Completed)+", user_id = "+p.Add(task.UserID)+", due_at = "+p.Add(task.DueAt)+
+		", version = "+p.Add(task.Version)+" WHERE id = "+p.Add(task.ID), p)
+}
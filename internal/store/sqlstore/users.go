@@ -0,0 +1,190 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/cmerin0/tasky/internal/filter"
+	"github.com/cmerin0/tasky/internal/models"
+	"github.com/cmerin0/tasky/internal/store"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// users reads users, without their password
+var users = table[models.UserResponse]{
+	name: "users",
+	all:  []string{"_id", "name", "email", "version"},
+	fields: map[string]string{
+		"_id":     "id",
+		"name":    "name",
+		"email":   "email",
+		"version": "version",
+	},
+	scan: func(row scanner, fields []string) (models.UserResponse, error) {
+		var user models.UserResponse
+		var id string
+		dest := make([]interface{}, len(fields))
+		for i, field := range fields {
+			switch field {
+			case "_id":
+				dest[i] = &id
+			case "name":
+				dest[i] = &user.Name
+			case "email":
+				dest[i] = &user.Email
+			case "version":
+				dest[i] = &user.Version
+			}
+		}
+		if err := row.Scan(dest...); err != nil {
+			return user, err
+		}
+		var err error
+		user.ID, err = parseID(id)
+		return user, err
+	},
+	field: func(user models.UserResponse, name string) interface{} {
+		switch name {
+		case "_id":
+			return user.ID
+		case "name":
+			return user.Name
+		case "email":
+			return user.Email
+		case "version":
+			return user.Version
+		}
+		return nil
+	},
+}
+
+// response returns a user without its password
+func response(user models.User) models.UserResponse {
+	return models.UserResponse{ID: user.ID, Name: user.Name, Email: user.Email, Version: user.Version}
+}
+
+// lockUser reads a whole user at one of the versions, locking it until
+// the end of the transaction when the dialect can
+func (s *Store) lockUser(ctx context.Context, id primitive.ObjectID, versions []int64) (models.User, error) {
+	user := models.User{ID: id}
+	var hex string
+	p := s.params()
+	err := s.conn(ctx).QueryRowContext(ctx,
+		"SELECT id, name, email, password, version FROM users WHERE id = "+p.Add(id)+s.dialect.Lock, p.Args...,
+	).Scan(&hex, &user.Name, &user.Email, &user.Password, &user.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return user, store.ErrNotFound
+	}
+	if err != nil {
+		return user, err
+	}
+	if !versionMatches(user.Version, versions) {
+		return user, store.ErrStale
+	}
+	return user, nil
+}
+
+// GetUser returns a user by ID, without the password
+func (s *Store) GetUser(ctx context.Context, id primitive.ObjectID) (*models.UserResponse, error) {
+	return users.get(ctx, s, id)
+}
+
+// ListUsers returns a page of users
+func (s *Store) ListUsers(ctx context.Context, where filter.Expr, req store.PageRequest) (*store.Page[models.UserResponse], error) {
+	return users.page(ctx, s, where, req, nil)
+}
+
+// FindUsers returns every matching user, oldest first
+func (s *Store) FindUsers(ctx context.Context, where filter.Expr) ([]models.UserResponse, error) {
+	return users.find(ctx, s, where)
+}
+
+// CreateUser validates and stores a new user at version 1
+func (s *Store) CreateUser(ctx context.Context, user models.User) (*models.UserResponse, error) {
+	if err := store.Validate(user); err != nil {
+		return nil, err
+	}
+
+	user.ID, user.Version = primitive.NewObjectID(), 1
+	p := s.params()
+	err := s.exec(ctx, "INSERT INTO users (id, name, email, password, version) VALUES ("+
+		p.List(user.ID, user.Name, user.Email, user.Password, user.Version)+")", p)
+	if err != nil {
+		return nil, err
+	}
+	created := response(user)
+	return &created, nil
+}
+
+// ReplaceUser validates and replaces every field of a user, bumping its version
+func (s *Store) ReplaceUser(ctx context.Context, id primitive.ObjectID, user models.User, versions []int64) (*models.UserResponse, error) {
+	user.ID = id
+	if err := store.Validate(user); err != nil {
+		return nil, err
+	}
+
+	err := s.InTransaction(ctx, func(ctx context.Context) error {
+		current, err := s.lockUser(ctx, id, versions)
+		if err != nil {
+			return err
+		}
+		user.Version = current.Version + 1
+		return s.saveUser(ctx, user)
+	})
+	if err != nil {
+		return nil, err
+	}
+	replaced := response(user)
+	return &replaced, nil
+}
+
+// UpdateUser writes the fields change modified
+func (s *Store) UpdateUser(ctx context.Context, id primitive.ObjectID, versions []int64, change func(user *models.User) error) (*models.UserResponse, error) {
+	var after models.User
+	err := s.InTransaction(ctx, func(ctx context.Context) error {
+		before, err := s.lockUser(ctx, id, versions)
+		if err != nil {
+			return err
+		}
+
+		after = before
+		if err := change(&after); err != nil {
+			return err
+		}
+		after.ID, after.Version = before.ID, before.Version
+		if err := store.Validate(after); err != nil {
+			return err
+		}
+
+		if after == before {
+			return nil
+		}
+		after.Version++
+		return s.saveUser(ctx, after)
+	})
+	if err != nil {
+		return nil, err
+	}
+	updated := response(after)
+	return &updated, nil
+}
+
+// DeleteUser deletes a user
+func (s *Store) DeleteUser(ctx context.Context, id primitive.ObjectID, versions []int64) error {
+	return s.InTransaction(ctx, func(ctx context.Context) error {
+		if _, err := s.lockUser(ctx, id, versions); err != nil {
+			return err
+		}
+		p := s.params()
+		return s.exec(ctx, "DELETE FROM users WHERE id = "+p.Add(id), p)
+	})
+}
+
+// saveUser writes every field of a locked user
+func (s *Store) saveUser(ctx context.Context, user models.User) error {
+	p := s.params()
+	return s.exec(ctx, "UPDATE users SET name = "+p.Add(user.Name)+", email = "+p.Add(user.Email)+
+		", password = "+p.Add(user.Password)+", version = "+p.Add(user.Version)+" WHERE id = "+p.Add(user.ID), p)
+}
@@ -0,0 +1,133 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/cmerin0/tasky/internal/models"
+	"github.com/cmerin0/tasky/internal/store"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var _ store.ViewRepository = (*Store)(nil)
+
+// viewColumns are the columns a view is read from, in scanView order
+const viewColumns = "id, name, owner_id, filter, sort, columns, visibility, shared_with"
+
+// scanView reads a view selected with viewColumns
+func scanView(row scanner) (models.View, error) {
+	var view models.View
+	var id, owner string
+	var columns []byte
+	err := row.Scan(&id, &view.Name, &owner, &view.Filter, &view.Sort, &columns, &view.Visibility, &view.SharedWith)
+	if err != nil {
+		return view, err
+	}
+	if view.ID, err = parseID(id); err != nil {
+		return view, err
+	}
+	if view.OwnerID, err = parseID(owner); err != nil {
+		return view, err
+	}
+	return view, json.Unmarshal(columns, &view.Columns)
+}
+
+// GetView returns a saved view by ID
+func (s *Store) GetView(ctx context.Context, id primitive.ObjectID) (*models.View, error) {
+	p := s.params()
+	view, err := scanView(s.conn(ctx).QueryRowContext(ctx,
+		"SELECT "+viewColumns+" FROM views WHERE id = "+p.Add(id), p.Args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, store.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &view, nil
+}
+
+// ListViews returns the views of owner plus those shared with project or
+// workspace, oldest first
+func (s *Store) ListViews(ctx context.Context, owner primitive.ObjectID, project, workspace string) ([]models.View, error) {
+	p := s.params()
+	query := "SELECT " + viewColumns + " FROM views WHERE owner_id = " + p.Add(owner)
+	if project != "" {
+		query += " OR (visibility = " + p.Add(models.ViewProject) + " AND shared_with = " + p.Add(project) + ")"
+	}
+	if workspace != "" {
+		query += " OR (visibility = " + p.Add(models.ViewWorkspace) + " AND shared_with = " + p.Add(workspace) + ")"
+	}
+	rows, err := s.conn(ctx).QueryContext(ctx, query+" ORDER BY id", p.Args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	views := []models.View{}
+	for rows.Next() {
+		view, err := scanView(rows)
+		if err != nil {
+			return nil, err
+		}
+		views = append(views, view)
+	}
+	return views, rows.Err()
+}
+
+// CreateView stores a new view
+func (s *Store) CreateView(ctx context.Context, view models.View) (*models.View, error) {
+	columns, err := json.Marshal(view.Columns)
+	if err != nil {
+		return nil, err
+	}
+
+	view.ID = primitive.NewObjectID()
+	p := s.params()
+	err = s.exec(ctx, "INSERT INTO views ("+viewColumns+") VALUES ("+
+		p.List(view.ID, view.Name, view.OwnerID, view.Filter, view.Sort, string(columns), view.Visibility, view.SharedWith)+")", p)
+	if err != nil {
+		return nil, err
+	}
+	return &view, nil
+}
+
+// UpdateView replaces a view of its owner
+func (s *Store) UpdateView(ctx context.Context, view models.View) error {
+	columns, err := json.Marshal(view.Columns)
+	if err != nil {
+		return err
+	}
+
+	p := s.params()
+	result, err := s.conn(ctx).ExecContext(ctx, "UPDATE views SET name = "+p.Add(view.Name)+
+		", filter = "+p.Add(view.Filter)+", sort = "+p.Add(view.Sort)+", columns = "+p.Add(string(columns))+
+		", visibility = "+p.Add(view.Visibility)+", shared_with = "+p.Add(view.SharedWith)+
+		" WHERE id = "+p.Add(view.ID)+" AND owner_id = "+p.Add(view.OwnerID), p.Args...)
+	return affected(result, err)
+}
+
+// DeleteView deletes a view of its owner
+func (s *Store) DeleteView(ctx context.Context, id, owner primitive.ObjectID) error {
+	p := s.params()
+	result, err := s.conn(ctx).ExecContext(ctx,
+		"DELETE FROM views WHERE id = "+p.Add(id)+" AND owner_id = "+p.Add(owner), p.Args...)
+	return affected(result, err)
+}
+
+// affected returns ErrNotFound when a statement changed no row
+func affected(result sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return store.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+// Package store holds the data access for users and tasks shared by the
+// REST, GraphQL and gRPC APIs: validation, versioning and task history
+// are applied here so every API writes documents the same way.
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/cmerin0/tasky/internal/db"
+	"github.com/cmerin0/tasky/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	// ErrNotFound is returned when the document doesn't exist
+	ErrNotFound = errors.New("not found")
+	// ErrStale is returned when a write expected another version of the document
+	ErrStale = errors.New("the resource was modified, fetch it again and retry")
+	// ErrSkipped is returned for the writes of an ordered batch after the
+	// one that failed
+	ErrSkipped = errors.New("not applied because an earlier operation failed")
+)
+
+// ValidationError is returned when a document fails validation
+type ValidationError struct {
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// Validate wraps the validation error of a model, if any
+func Validate(model interface{}) error {
+	if err := models.Validate(model); err != nil {
+		return &ValidationError{Err: err}
+	}
+	return nil
+}
+
+var (
+	userCollection       *mongo.Collection
+	taskCollection       *mongo.Collection
+	historyCollection    *mongo.Collection
+	outboxCollection     *mongo.Collection
+	webhookCollection    *mongo.Collection
+	deliveryCollection   *mongo.Collection
+	preferenceCollection *mongo.Collection
+	sentCollection       *mongo.Collection
+	inboxCollection      *mongo.Collection
+	watchCollection      *mongo.Collection
+	leaseCollection      *mongo.Collection
+	jobCollection        *mongo.Collection
+	viewCollection       *mongo.Collection
+)
+
+// Users returns the user collection
+// from the database. It initializes it if not already done.
+func Users() *mongo.Collection {
+	if userCollection == nil {
+		userCollection = db.GetCollection("users")
+	}
+	return userCollection
+}
+
+// Tasks returns the task collection
+// from the database. It initializes it if not already done.
+func Tasks() *mongo.Collection {
+	if taskCollection == nil {
+		taskCollection = db.GetCollection("tasks")
+	}
+	return taskCollection
+}
+
+// History returns the task history collection
+// from the database. It initializes it if not already done.
+func History() *mongo.Collection {
+	if historyCollection == nil {
+		historyCollection = db.GetCollection("task_history")
+	}
+	return historyCollection
+}
+
+// Outbox returns the outbox collection
+// from the database. It initializes it if not already done.
+func Outbox() *mongo.Collection {
+	if outboxCollection == nil {
+		outboxCollection = db.GetCollection("outbox")
+	}
+	return outboxCollection
+}
+
+// Webhooks returns the webhook collection
+// from the database. It initializes it if not already done.
+func Webhooks() *mongo.Collection {
+	if webhookCollection == nil {
+		webhookCollection = db.GetCollection("webhooks")
+	}
+	return webhookCollection
+}
+
+// WebhookDeliveries returns the webhook delivery collection
+// from the database. It initializes it if not already done.
+func WebhookDeliveries() *mongo.Collection {
+	if deliveryCollection == nil {
+		deliveryCollection = db.GetCollection("webhook_deliveries")
+	}
+	return deliveryCollection
+}
+
+// NotificationPreferences returns the notification preference collection
+// from the database. It initializes it if not already done.
+func NotificationPreferences() *mongo.Collection {
+	if preferenceCollection == nil {
+		preferenceCollection = db.GetCollection("notification_preferences")
+	}
+	return preferenceCollection
+}
+
+// SentNotifications returns the collection recording the notifications
+// already sent. It initializes it if not already done.
+func SentNotifications() *mongo.Collection {
+	if sentCollection == nil {
+		sentCollection = db.GetCollection("notifications_sent")
+	}
+	return sentCollection
+}
+
+// Notifications returns the in-app notification collection
+// from the database. It initializes it if not already done.
+func Notifications() *mongo.Collection {
+	if inboxCollection == nil {
+		inboxCollection = db.GetCollection("notifications")
+	}
+	return inboxCollection
+}
+
+// TaskWatches returns the task watch collection
+// from the database. It initializes it if not already done.
+func TaskWatches() *mongo.Collection {
+	if watchCollection == nil {
+		watchCollection = db.GetCollection("task_watches")
+	}
+	return watchCollection
+}
+
+// Leases returns the collection of the leases held by app replicas
+// from the database. It initializes it if not already done.
+func Leases() *mongo.Collection {
+	if leaseCollection == nil {
+		leaseCollection = db.GetCollection("leases")
+	}
+	return leaseCollection
+}
+
+// Jobs returns the background job collection
+// from the database. It initializes it if not already done.
+func Jobs() *mongo.Collection {
+	if jobCollection == nil {
+		jobCollection = db.GetCollection("jobs")
+	}
+	return jobCollection
+}
+
+// Views returns the saved view collection
+// from the database. It initializes it if not already done.
+func Views() *mongo.Collection {
+	if viewCollection == nil {
+		viewCollection = db.GetCollection("views")
+	}
+	return viewCollection
+}
+
+// VersionIn matches documents at any of the given versions.
+// Documents written before versioning have no version field and count as version 0.
+func VersionIn(versions []int64) bson.M {
+	values := bson.A{}
+	for _, version := range versions {
+		values = append(values, version)
+		if version == 0 {
+			values = append(values, nil)
+		}
+	}
+	return bson.M{"$in": values}
+}
+
+// versionFilter selects a document by ID and, when versions is not nil,
+// only at one of those versions
+func versionFilter(id primitive.ObjectID, versions []int64) bson.M {
+	query := bson.M{"_id": id}
+	if versions != nil {
+		query["version"] = VersionIn(versions)
+	}
+	return query
+}
+
+// missingOrStale tells a write on a missing document apart
+// from one on a document at another version
+func missingOrStale(ctx context.Context, coll *mongo.Collection, id primitive.ObjectID) error {
+	count, err := coll.CountDocuments(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrStale
+	}
+	return ErrNotFound
+}
@@ -0,0 +1,112 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/cmerin0/tasky/internal/models"
+	"github.com/cmerin0/tasky/internal/store"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// bulkErrors describes the outcome of each write of a batch
+func bulkErrors(results []store.BulkResult) []string {
+	outcomes := []string{}
+	for _, result := range results {
+		var invalid *store.ValidationError
+		switch {
+		case result.Err == nil:
+			outcomes = append(outcomes, "ok")
+		case errors.As(result.Err, &invalid):
+			outcomes = append(outcomes, "invalid")
+		default:
+			outcomes = append(outcomes, result.Err.Error())
+		}
+	}
+	return outcomes
+}
+
+func checkBulk(ctx context.Context, s store.Storage, tag string) error {
+	owner := primitive.NewObjectID()
+	task, err := s.CreateTask(ctx, models.Task{Title: "existing", UserID: owner}, tag)
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	missing := primitive.NewObjectID()
+
+	// Unordered batches carry on after failures
+	results, err := s.BulkTasks(ctx, []store.BulkOp{
+		{Kind: store.BulkCreate, Task: models.Task{Title: "new", UserID: owner}},
+		{Kind: store.BulkComplete, ID: task.ID},
+		{Kind: store.BulkDelete, ID: missing},
+		{Kind: store.BulkReplace, ID: task.ID, Task: models.Task{UserID: owner}},
+		{Kind: store.BulkReplace, ID: task.ID, Task: models.Task{Title: "replaced", UserID: owner, Completed: true}},
+	}, false, tag)
+	if err != nil {
+		return fmt.Errorf("unordered: %w", err)
+	}
+	if err := expectEqual("unordered outcomes", bulkErrors(results),
+		[]string{"ok", "ok", store.ErrNotFound.Error(), "invalid", "ok"}); err != nil {
+		return err
+	}
+	created := results[0].Task
+	if err := first(
+		expectEqual("created title", created.Title, "new"),
+		expectEqual("created version", created.Version, int64(1)),
+		expectEqual("completed version", results[1].Task.Version, int64(2)),
+		expectEqual("replaced version", results[4].Task.Version, int64(3)),
+	); err != nil {
+		return err
+	}
+	got, err := s.GetTask(ctx, created.ID)
+	if err != nil {
+		return fmt.Errorf("get created: %w", err)
+	}
+	if err := expectTask("created", *got, *created); err != nil {
+		return err
+	}
+	if got, err = s.GetTask(ctx, task.ID); err != nil {
+		return fmt.Errorf("get replaced: %w", err)
+	}
+	if err := expectEqual("replaced", []interface{}{got.Title, got.Completed, got.Version}, []interface{}{"replaced", true, int64(3)}); err != nil {
+		return err
+	}
+
+	// Ordered batches skip everything after the first failure
+	results, err = s.BulkTasks(ctx, []store.BulkOp{
+		{Kind: store.BulkDelete, ID: created.ID},
+		{Kind: store.BulkComplete, ID: missing},
+		{Kind: store.BulkDelete, ID: task.ID},
+	}, true, tag)
+	if err != nil {
+		return fmt.Errorf("ordered: %w", err)
+	}
+	if err := expectEqual("ordered outcomes", bulkErrors(results),
+		[]string{"ok", store.ErrNotFound.Error(), store.ErrSkipped.Error()}); err != nil {
+		return err
+	}
+	_, err = s.GetTask(ctx, created.ID)
+	if err := expectErr("get deleted", err, store.ErrNotFound); err != nil {
+		return err
+	}
+	if _, err := s.GetTask(ctx, task.ID); err != nil {
+		return fmt.Errorf("get skipped: %w", err)
+	}
+
+	// Every write is in the history
+	history, err := s.TaskHistory(ctx, task.ID)
+	if err != nil {
+		return fmt.Errorf("history: %w", err)
+	}
+	if err := expectEqual("actions", historyActions(history),
+		[]string{models.HistoryUpdated, models.HistoryUpdated, models.HistoryCreated}); err != nil {
+		return err
+	}
+	if history, err = s.TaskHistory(ctx, created.ID); err != nil {
+		return fmt.Errorf("history of created: %w", err)
+	}
+	return expectEqual("actions of created", historyActions(history),
+		[]string{models.HistoryDeleted, models.HistoryCreated})
+}
@@ -0,0 +1,109 @@
+package storetest
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/cmerin0/tasky/internal/filter"
+	"github.com/cmerin0/tasky/internal/models"
+	"github.com/cmerin0/tasky/internal/store"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func checkFilters(ctx context.Context, s store.Storage, tag string) error {
+	owner := primitive.NewObjectID()
+	for _, task := range []models.Task{
+		{Title: "Buy Milk", Description: "two litres", DueAt: dueAt(0)},
+		{Title: "buy bread", Completed: true, DueAt: dueAt(2)},
+		{Title: "call the bank", Description: "about the MILK bill"},
+		{Title: "file taxes (2029)", Completed: true, DueAt: dueAt(5)},
+	} {
+		task.UserID = owner
+		if _, err := s.CreateTask(ctx, task, tag); err != nil {
+			return fmt.Errorf("create: %w", err)
+		}
+	}
+
+	mine := `userId:` + owner.Hex()
+	day := func(days int) string {
+		return `"` + dueAt(days).UTC().Format("2006-01-02T15:04:05.000Z07:00") + `"`
+	}
+	queries := []struct {
+		query string
+		want  []string
+	}{
+		{``, []string{"Buy Milk", "buy bread", "call the bank", "file taxes (2029)"}},
+		{`title:"buy bread"`, []string{"buy bread"}},
+		{`title:"Buy bread"`, []string{}},
+		{`title ~ buy`, []string{"Buy Milk", "buy bread"}},
+		{`title ~ "(2029)"`, []string{"file taxes (2029)"}},
+		{`title ~ "." or description ~ "*"`, []string{}},
+		{`title ~ milk or description ~ milk`, []string{"Buy Milk", "call the bank"}},
+		{`completed:true`, []string{"buy bread", "file taxes (2029)"}},
+		{`completed != true`, []string{"Buy Milk", "call the bank"}},
+		{`not completed:false and title ~ taxes`, []string{"file taxes (2029)"}},
+		{`title in ("Buy Milk", "call the bank", "nothing")`, []string{"Buy Milk", "call the bank"}},
+		{`dueAt > ` + day(0), []string{"buy bread", "file taxes (2029)"}},
+		{`dueAt >= ` + day(0), []string{"Buy Milk", "buy bread", "file taxes (2029)"}},
+		{`dueAt <= ` + day(2), []string{"Buy Milk", "buy bread"}},
+		{`dueAt:` + day(2), []string{"buy bread"}},
+		// Tasks without a due date only match !=
+		{`dueAt != ` + day(2), []string{"Buy Milk", "call the bank", "file taxes (2029)"}},
+		{`dueAt < 2099-01-01`, []string{"Buy Milk", "buy bread", "file taxes (2029)"}},
+		{`(completed:true or dueAt < ` + day(1) + `) and not title ~ bread`, []string{"Buy Milk", "file taxes (2029)"}},
+	}
+	for _, q := range queries {
+		where, err := filter.Parse(q.query, store.TaskFilterFields)
+		if err != nil {
+			return fmt.Errorf("%s: %w", q.query, err)
+		}
+		tasks, err := s.FindTasks(ctx, filter.AllOf(mustParse(mine, store.TaskFilterFields), where))
+		if err != nil {
+			return fmt.Errorf("%s: %w", q.query, err)
+		}
+		titles := taskTitles(tasks)
+		slices.Sort(titles)
+		want := slices.Clone(q.want)
+		slices.Sort(want)
+		if err := expectEqual(q.query, titles, want); err != nil {
+			return err
+		}
+
+		// Lists and counts agree with finds
+		req, err := store.NewPageRequest(store.MaxPageLimit, "", "", true, store.TaskSortFields)
+		if err != nil {
+			return err
+		}
+		page, err := s.ListTasks(ctx, filter.AllOf(mustParse(mine, store.TaskFilterFields), where), req, nil)
+		if err != nil {
+			return fmt.Errorf("list %s: %w", q.query, err)
+		}
+		if err := first(
+			expectEqual("list "+q.query, taskTitles(page.Items), taskTitles(tasks)),
+			expectEqual("count "+q.query, *page.Total, int64(len(q.want))),
+		); err != nil {
+			return err
+		}
+	}
+
+	user, err := s.CreateUser(ctx, newUser(tag, "Barbara"))
+	if err != nil {
+		return fmt.Errorf("create user: %w", err)
+	}
+	users, err := s.FindUsers(ctx, mustParse(`name ~ barb and email ~ "`+tag+`" and id:`+user.ID.Hex(), store.UserFilterFields))
+	if err != nil {
+		return fmt.Errorf("find users: %w", err)
+	}
+	return expectEqual("find users", userNames(users), []string{"Barbara"})
+}
+
+// mustParse parses a filter the suite itself wrote
+func mustParse(query string, fields filter.Fields) filter.Expr {
+	where, err := filter.Parse(query, fields)
+	if err != nil {
+		panic(err)
+	}
+	return where
+}
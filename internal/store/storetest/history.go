@@ -0,0 +1,207 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/cmerin0/tasky/internal/models"
+	"github.com/cmerin0/tasky/internal/store"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// historyActions returns the actions of history entries, in order
+func historyActions(history []models.TaskHistory) []string {
+	actions := []string{}
+	for _, entry := range history {
+		actions = append(actions, entry.Action)
+	}
+	return actions
+}
+
+func checkHistory(ctx context.Context, s store.Storage, tag string) error {
+	task, err := s.CreateTask(ctx, models.Task{Title: "draft", UserID: primitive.NewObjectID()}, tag)
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	if _, err := s.UpdateTask(ctx, task.ID, nil, func(task *models.Task) error {
+		task.Title = "final"
+		return nil
+	}, tag); err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+	// Updates without changes aren't recorded
+	if _, err := s.UpdateTask(ctx, task.ID, nil, func(*models.Task) error { return nil }, tag); err != nil {
+		return fmt.Errorf("update without changes: %w", err)
+	}
+	if err := s.DeleteTask(ctx, task.ID, nil, tag); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	history, err := s.TaskHistory(ctx, task.ID)
+	if err != nil {
+		return fmt.Errorf("history: %w", err)
+	}
+	if err := expectEqual("actions", historyActions(history),
+		[]string{models.HistoryDeleted, models.HistoryUpdated, models.HistoryCreated}); err != nil {
+		return err
+	}
+
+	updated := history[1]
+	if err := first(
+		expectEqual("task ID", updated.TaskID, task.ID),
+		expectEqual("actor", updated.Actor, tag),
+		expectEqual("changes", updated.Changes, []models.FieldChange{{Field: "title", From: "draft", To: "final"}}),
+		expectEqual("snapshot version", updated.Snapshot.Version, int64(2)),
+		expectEqual("deleted snapshot", history[0].Snapshot.Title, "final"),
+		expectEqual("timestamp set", updated.Timestamp.IsZero(), false),
+	); err != nil {
+		return err
+	}
+
+	none, err := s.TaskHistory(ctx, primitive.NewObjectID())
+	if err != nil {
+		return fmt.Errorf("history of an unknown task: %w", err)
+	}
+	return expectEqual("history of an unknown task", len(none), 0)
+}
+
+func checkRevert(ctx context.Context, s store.Storage, tag string) error {
+	task, err := s.CreateTask(ctx, models.Task{Title: "first", UserID: primitive.NewObjectID(), DueAt: dueAt(0)}, tag)
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	if _, err := s.ReplaceTask(ctx, task.ID, models.Task{Title: "second", UserID: task.UserID}, nil, tag); err != nil {
+		return fmt.Errorf("replace: %w", err)
+	}
+	history, err := s.TaskHistory(ctx, task.ID)
+	if err != nil {
+		return fmt.Errorf("history: %w", err)
+	}
+	created := history[len(history)-1]
+
+	// Reverting makes a new version
+	reverted, err := s.RevertTask(ctx, task.ID, created.ID, tag)
+	if err != nil {
+		return fmt.Errorf("revert: %w", err)
+	}
+	want := *task
+	want.Version = 3
+	if err := expectTask("revert", *reverted, want); err != nil {
+		return err
+	}
+	got, err := s.GetTask(ctx, task.ID)
+	if err != nil {
+		return fmt.Errorf("get reverted: %w", err)
+	}
+	if err := expectTask("get reverted", *got, want); err != nil {
+		return err
+	}
+
+	// Deleted tasks come back with their ID
+	if err := s.DeleteTask(ctx, task.ID, nil, tag); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+	history, err = s.TaskHistory(ctx, task.ID)
+	if err != nil {
+		return fmt.Errorf("history: %w", err)
+	}
+	if err := expectEqual("actions", historyActions(history), []string{
+		models.HistoryDeleted, models.HistoryReverted, models.HistoryUpdated, models.HistoryCreated,
+	}); err != nil {
+		return err
+	}
+	restored, err := s.RevertTask(ctx, task.ID, history[0].ID, tag)
+	if err != nil {
+		return fmt.Errorf("revert deleted: %w", err)
+	}
+	want.Version = 4
+	if err := expectTask("revert deleted", *restored, want); err != nil {
+		return err
+	}
+	if _, err := s.GetTask(ctx, task.ID); err != nil {
+		return fmt.Errorf("get restored: %w", err)
+	}
+
+	// and carry on from the version they were deleted at, whichever entry
+	// they are reverted to
+	if err := s.DeleteTask(ctx, task.ID, nil, tag); err != nil {
+		return fmt.Errorf("delete again: %w", err)
+	}
+	restored, err = s.RevertTask(ctx, task.ID, created.ID, tag)
+	if err != nil {
+		return fmt.Errorf("revert deleted to creation: %w", err)
+	}
+	want.Version = 5
+	if err := expectTask("revert deleted to creation", *restored, want); err != nil {
+		return err
+	}
+
+	// Entries only revert their own task
+	other, err := s.CreateTask(ctx, models.Task{Title: "other", UserID: task.UserID}, tag)
+	if err != nil {
+		return fmt.Errorf("create other: %w", err)
+	}
+	_, otherErr := s.RevertTask(ctx, other.ID, created.ID, tag)
+	_, unknownErr := s.RevertTask(ctx, task.ID, primitive.NewObjectID(), tag)
+	return first(
+		expectErr("revert with the entry of another task", otherErr, store.ErrNotFound),
+		expectErr("revert with an unknown entry", unknownErr, store.ErrNotFound),
+	)
+}
+
+func checkTransactions(ctx context.Context, s store.Storage, tag string) error {
+	owner := primitive.NewObjectID()
+	kept, err := s.CreateTask(ctx, models.Task{Title: "kept", UserID: owner}, tag)
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+
+	// A failing transaction leaves nothing behind
+	var created *models.Task
+	rollback := errors.New("rollback")
+	err = s.InTransaction(ctx, func(ctx context.Context) error {
+		if created, err = s.CreateTask(ctx, models.Task{Title: "created", UserID: owner}, tag); err != nil {
+			return err
+		}
+		if _, err := s.UpdateTask(ctx, kept.ID, nil, func(task *models.Task) error {
+			task.Completed = true
+			return nil
+		}, tag); err != nil {
+			return err
+		}
+		// Reads inside see the writes
+		if _, err := s.GetTask(ctx, created.ID); err != nil {
+			return err
+		}
+		return rollback
+	})
+	if err := expectErr("failing transaction", err, rollback); err != nil {
+		return err
+	}
+	_, getErr := s.GetTask(ctx, created.ID)
+	got, err := s.GetTask(ctx, kept.ID)
+	if err != nil {
+		return fmt.Errorf("get: %w", err)
+	}
+	if err := first(
+		expectErr("task created in a failed transaction", getErr, store.ErrNotFound),
+		expectTask("task updated in a failed transaction", *got, *kept),
+	); err != nil {
+		return err
+	}
+
+	// Nested transactions join the outer one
+	err = s.InTransaction(ctx, func(ctx context.Context) error {
+		return s.InTransaction(ctx, func(ctx context.Context) error {
+			created, err = s.CreateTask(ctx, models.Task{Title: "nested", UserID: owner}, tag)
+			return err
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("nested transaction: %w", err)
+	}
+	_, err = s.GetTask(ctx, created.ID)
+	return err
+}
@@ -0,0 +1,242 @@
+package storetest
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cmerin0/tasky/internal/filter"
+	"github.com/cmerin0/tasky/internal/models"
+	"github.com/cmerin0/tasky/internal/store"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// walk follows a cursor as clients do, through its encoded token
+func walk(cur *store.Cursor, limit int, sortFields map[string]string) (store.PageRequest, error) {
+	if cur == nil {
+		return store.PageRequest{}, fmt.Errorf("missing cursor")
+	}
+	return store.NewPageRequest(limit, store.EncodeCursor(cur), "", false, sortFields)
+}
+
+func checkPagination(ctx context.Context, s store.Storage, tag string) error {
+	owner := primitive.NewObjectID()
+	where := store.FieldIs(store.TaskFilterFields, "userId", owner)
+
+	// Equal titles are ordered by ID
+	for _, task := range []models.Task{
+		{Title: "d"}, {Title: "b", Completed: true}, {Title: "e"}, {Title: "a", Completed: true}, {Title: "c"}, {Title: "b"},
+	} {
+		task.UserID = owner
+		if _, err := s.CreateTask(ctx, task, tag); err != nil {
+			return fmt.Errorf("create: %w", err)
+		}
+	}
+
+	req, err := store.NewPageRequest(2, "", "title", true, store.TaskSortFields)
+	if err != nil {
+		return err
+	}
+	pages := [][]string{{"a", "b"}, {"b", "c"}, {"d", "e"}}
+	var page *store.Page[models.Task]
+	for i, want := range pages {
+		if i > 0 {
+			if req, err = walk(page.Next, 2, store.TaskSortFields); err != nil {
+				return fmt.Errorf("page %d: %w", i+1, err)
+			}
+		}
+		if page, err = s.ListTasks(ctx, where, req, nil); err != nil {
+			return fmt.Errorf("page %d: %w", i+1, err)
+		}
+		if err := expectEqual(fmt.Sprintf("page %d", i+1), taskTitles(page.Items), want); err != nil {
+			return err
+		}
+		if err := expectEqual(fmt.Sprintf("page %d has a previous page", i+1), page.Prev != nil, i > 0); err != nil {
+			return err
+		}
+		if i == 0 {
+			if page.Total == nil || *page.Total != 6 {
+				return fmt.Errorf("page 1: got total %v, want 6", page.Total)
+			}
+		}
+	}
+	if page.Next != nil {
+		return fmt.Errorf("last page: got a next page")
+	}
+
+	// And back again
+	for i := len(pages) - 2; i >= 0; i-- {
+		if req, err = walk(page.Prev, 2, store.TaskSortFields); err != nil {
+			return fmt.Errorf("back to page %d: %w", i+1, err)
+		}
+		if page, err = s.ListTasks(ctx, where, req, nil); err != nil {
+			return fmt.Errorf("back to page %d: %w", i+1, err)
+		}
+		if err := first(
+			expectEqual(fmt.Sprintf("back to page %d", i+1), taskTitles(page.Items), pages[i]),
+			expectEqual(fmt.Sprintf("back to page %d has a next page", i+1), page.Next != nil, true),
+			expectEqual(fmt.Sprintf("back to page %d has a previous page", i+1), page.Prev != nil, i > 0),
+		); err != nil {
+			return err
+		}
+	}
+
+	// Descending, by a boolean, then by ID
+	req, err = store.NewPageRequest(3, "", "-completed", false, store.TaskSortFields)
+	if err != nil {
+		return err
+	}
+	page, err = s.ListTasks(ctx, where, req, nil)
+	if err != nil {
+		return fmt.Errorf("sort by completed: %w", err)
+	}
+	if page.Total != nil {
+		return fmt.Errorf("sort by completed: got a total without asking for it")
+	}
+	if err := expectEqual("sort by completed", taskTitles(page.Items), []string{"a", "b", "b"}); err != nil {
+		return err
+	}
+	if req, err = walk(page.Next, 3, store.TaskSortFields); err != nil {
+		return fmt.Errorf("sort by completed, page 2: %w", err)
+	}
+	page, err = s.ListTasks(ctx, where, req, nil)
+	if err != nil {
+		return fmt.Errorf("sort by completed, page 2: %w", err)
+	}
+	if err := first(
+		expectEqual("sort by completed, page 2", taskTitles(page.Items), []string{"c", "e", "d"}),
+		expectEqual("sort by completed, page 2 has a next page", page.Next != nil, false),
+	); err != nil {
+		return err
+	}
+
+	// Newest first
+	req, err = store.NewPageRequest(4, "", "-id", false, store.TaskSortFields)
+	if err != nil {
+		return err
+	}
+	page, err = s.ListTasks(ctx, where, req, nil)
+	if err != nil {
+		return fmt.Errorf("sort by ID: %w", err)
+	}
+	if err := expectEqual("sort by ID", taskTitles(page.Items), []string{"b", "c", "a", "e"}); err != nil {
+		return err
+	}
+	if req, err = walk(page.Next, 4, store.TaskSortFields); err != nil {
+		return fmt.Errorf("sort by ID, page 2: %w", err)
+	}
+	page, err = s.ListTasks(ctx, where, req, nil)
+	if err != nil {
+		return fmt.Errorf("sort by ID, page 2: %w", err)
+	}
+	if err := expectEqual("sort by ID, page 2", taskTitles(page.Items), []string{"b", "d"}); err != nil {
+		return err
+	}
+
+	// No matches is an empty page
+	req, err = store.NewPageRequest(2, "", "", true, store.TaskSortFields)
+	if err != nil {
+		return err
+	}
+	page, err = s.ListTasks(ctx, store.FieldIs(store.TaskFilterFields, "userId", primitive.NewObjectID()), req, nil)
+	if err != nil {
+		return fmt.Errorf("no matches: %w", err)
+	}
+	if len(page.Items) != 0 || page.Items == nil || page.Next != nil || page.Prev != nil || page.Total == nil || *page.Total != 0 {
+		return fmt.Errorf("no matches: got %+v", page)
+	}
+	return nil
+}
+
+func checkUserPagination(ctx context.Context, s store.Storage, tag string) error {
+	where, err := filter.Parse(`email ~ "`+tag+`" and name ~ "pager"`, store.UserFilterFields)
+	if err != nil {
+		return err
+	}
+	for _, name := range []string{"pager-c", "pager-a", "pager-b"} {
+		if _, err := s.CreateUser(ctx, newUser(tag, name)); err != nil {
+			return fmt.Errorf("create: %w", err)
+		}
+	}
+
+	req, err := store.NewPageRequest(2, "", "-name", true, store.UserSortFields)
+	if err != nil {
+		return err
+	}
+	page, err := s.ListUsers(ctx, where, req)
+	if err != nil {
+		return fmt.Errorf("page 1: %w", err)
+	}
+	if err := first(
+		expectEqual("page 1", userNames(page.Items), []string{"pager-c", "pager-b"}),
+		expectEqual("page 1 total", page.Total != nil && *page.Total == 3, true),
+	); err != nil {
+		return err
+	}
+	if req, err = walk(page.Next, 2, store.UserSortFields); err != nil {
+		return fmt.Errorf("page 2: %w", err)
+	}
+	page, err = s.ListUsers(ctx, where, req)
+	if err != nil {
+		return fmt.Errorf("page 2: %w", err)
+	}
+	return first(
+		expectEqual("page 2", userNames(page.Items), []string{"pager-a"}),
+		expectEqual("page 2 has a next page", page.Next != nil, false),
+		expectEqual("page 2 has a previous page", page.Prev != nil, true),
+	)
+}
+
+// userNames returns the names of users, in order
+func userNames(users []models.UserResponse) []string {
+	names := []string{}
+	for _, user := range users {
+		names = append(names, user.Name)
+	}
+	return names
+}
+
+func checkProjection(ctx context.Context, s store.Storage, tag string) error {
+	owner := primitive.NewObjectID()
+	where := store.FieldIs(store.TaskFilterFields, "userId", owner)
+	for _, title := range []string{"b", "a"} {
+		task := models.Task{Title: title, Description: "unread", UserID: owner, DueAt: dueAt(1)}
+		if _, err := s.CreateTask(ctx, task, tag); err != nil {
+			return fmt.Errorf("create: %w", err)
+		}
+	}
+
+	// The ID and the sort field are read along with the projection
+	req, err := store.NewPageRequest(1, "", "title", false, store.TaskSortFields)
+	if err != nil {
+		return err
+	}
+	page, err := s.ListTasks(ctx, where, req, []string{"completed"})
+	if err != nil {
+		return fmt.Errorf("page 1: %w", err)
+	}
+	if len(page.Items) != 1 {
+		return fmt.Errorf("page 1: got %d tasks, want 1", len(page.Items))
+	}
+	task := page.Items[0]
+	if err := first(
+		expectEqual("title", task.Title, "a"),
+		expectEqual("ID read", task.ID.IsZero(), false),
+		expectEqual("description", task.Description, ""),
+		expectEqual("owner", task.UserID, primitive.ObjectID{}),
+		expectEqual("due date", task.DueAt, (*time.Time)(nil)),
+		expectEqual("version", task.Version, int64(0)),
+	); err != nil {
+		return err
+	}
+
+	// Cursors of projected pages still walk
+	if req, err = walk(page.Next, 1, store.TaskSortFields); err != nil {
+		return fmt.Errorf("page 2: %w", err)
+	}
+	if page, err = s.ListTasks(ctx, where, req, []string{"completed"}); err != nil {
+		return fmt.Errorf("page 2: %w", err)
+	}
+	return expectEqual("page 2", taskTitles(page.Items), []string{"b"})
+}
@@ -0,0 +1,72 @@
+package storetest
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cmerin0/tasky/internal/models"
+	"github.com/cmerin0/tasky/internal/store"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func checkSearch(ctx context.Context, s store.Storage, tag string) error {
+	// The tag is a word of its own, so only these tasks match it
+	word := "zq" + tag
+	owner := primitive.NewObjectID()
+	for _, task := range []models.Task{
+		{Title: "Release " + word, Description: "ship the installer"},
+		{Title: "Plan the launch", Description: "after the " + word + " releases"},
+		{Title: "Archive " + word + " notes", Completed: true},
+		{Title: "Unrelated", Description: "nothing to see"},
+	} {
+		task.UserID = owner
+		if _, err := s.CreateTask(ctx, task, tag); err != nil {
+			return fmt.Errorf("create: %w", err)
+		}
+	}
+
+	searches := []struct {
+		text  string
+		where string
+		limit int
+		want  []string
+	}{
+		// Matches in titles rank first, in shorter fields next
+		{text: word, limit: 10, want: []string{"Release " + word, "Archive " + word + " notes", "Plan the launch"}},
+		{text: word, limit: 1, want: []string{"Release " + word}},
+		// Words match their other forms
+		{text: "releasing", limit: 10, want: []string{"Release " + word, "Plan the launch"}},
+		{text: word + " -archive", limit: 10, want: []string{"Release " + word, "Plan the launch"}},
+		{text: word, where: "completed:true", limit: 10, want: []string{"Archive " + word + " notes"}},
+		{text: `"the ` + word + `"`, limit: 10, want: []string{"Plan the launch"}},
+		{text: word + ` -"ship the"`, limit: 10, want: []string{"Archive " + word + " notes", "Plan the launch"}},
+		{text: "zq" + primitive.NewObjectID().Hex(), limit: 10, want: []string{}},
+	}
+	for _, search := range searches {
+		where := mustParse(`userId:`+owner.Hex(), store.TaskFilterFields)
+		if search.where != "" {
+			where = mustParse(`userId:`+owner.Hex()+` and `+search.where, store.TaskFilterFields)
+		}
+		matches, err := s.SearchTasks(ctx, search.text, where, search.limit)
+		if err != nil {
+			return fmt.Errorf("search %s: %w", search.text, err)
+		}
+
+		titles := []string{}
+		for i, match := range matches {
+			if match.Score <= 0 {
+				return fmt.Errorf("search %s: got score %v for %q", search.text, match.Score, match.Title)
+			}
+			if i > 0 && match.Score > matches[i-1].Score {
+				return fmt.Errorf("search %s: results aren't ordered by score", search.text)
+			}
+			titles = append(titles, match.Title)
+		}
+
+		if err := expectEqual("search "+search.text, titles, search.want); err != nil {
+			return err
+		}
+	}
+	return nil
+}
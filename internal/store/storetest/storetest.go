@@ -0,0 +1,113 @@
+// Package storetest checks that a storage backend behaves like the MongoDB
+// one: validation, versioning, not-found errors, pagination, projection,
+// filtering, history, transactions, bulk writes and search, and saved views
+// on backends which store them. Every backend must pass it.
+//
+// Checks only look at the users and tasks they create, which are tagged
+// with a unique run ID, so the suite can run against a shared database.
+package storetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cmerin0/tasky/internal/store"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// check is one behaviour every backend shares, tag is unique to the run
+type check struct {
+	name string
+	run  func(ctx context.Context, s store.Storage, tag string) error
+}
+
+var checks = []check{
+	{"users", checkUsers},
+	{"user updates", checkUserUpdates},
+	{"tasks", checkTasks},
+	{"task updates", checkTaskUpdates},
+	{"validation", checkValidation},
+	{"pagination", checkPagination},
+	{"user pagination", checkUserPagination},
+	{"projection", checkProjection},
+	{"filters", checkFilters},
+	{"find", checkFind},
+	{"history", checkHistory},
+	{"revert", checkRevert},
+	{"transactions", checkTransactions},
+	{"bulk", checkBulk},
+	{"search", checkSearch},
+	{"views", checkViews},
+}
+
+// Run runs every check as a subtest, each against a new backend from
+// newRepo. Backends that need cleaning up register it with t.Cleanup.
+func Run(t *testing.T, newRepo func(t *testing.T) store.Storage) {
+	tag := primitive.NewObjectID().Hex()
+
+	for _, c := range checks {
+		t.Run(c.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			if err := c.run(ctx, newRepo(t), tag); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+// Check runs every check against s, returning their failures joined
+func Check(ctx context.Context, s store.Storage) error {
+	tag := primitive.NewObjectID().Hex()
+
+	var errs []error
+	for _, c := range checks {
+		if err := c.run(ctx, s, tag); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// expectErr fails unless err is want
+func expectErr(what string, err, want error) error {
+	if !errors.Is(err, want) {
+		return fmt.Errorf("%s: got error %v, want %v", what, err, want)
+	}
+	return nil
+}
+
+// expectEqual fails unless got and want are deeply equal
+func expectEqual(what string, got, want interface{}) error {
+	if !reflect.DeepEqual(got, want) {
+		return fmt.Errorf("%s: got %+v, want %+v", what, got, want)
+	}
+	return nil
+}
+
+// first returns the first failure
+func first(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dueAt is a due date with more precision than backends keep
+func dueAt(days int) *time.Time {
+	t := time.Date(2030, time.January, 1, 12, 0, 0, 123456789, time.FixedZone("CET", 3600)).AddDate(0, 0, days)
+	return &t
+}
+
+// stored is a time as backends return it: in UTC, to the millisecond
+func stored(t *time.Time) *time.Time {
+	s := t.UTC().Truncate(time.Millisecond)
+	return &s
+}
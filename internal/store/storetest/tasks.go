@@ -0,0 +1,211 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/cmerin0/tasky/internal/models"
+	"github.com/cmerin0/tasky/internal/store"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// expectTask fails unless two tasks hold the same values, due dates are
+// compared as instants
+func expectTask(what string, got, want models.Task) error {
+	gotDue, wantDue := got.DueAt, want.DueAt
+	got.DueAt, want.DueAt = nil, nil
+	if err := expectEqual(what, got, want); err != nil {
+		return err
+	}
+	if (gotDue == nil) != (wantDue == nil) || (gotDue != nil && !gotDue.Equal(*wantDue)) {
+		return fmt.Errorf("%s: got due date %v, want %v", what, gotDue, wantDue)
+	}
+	return nil
+}
+
+func checkTasks(ctx context.Context, s store.Storage, tag string) error {
+	owner := primitive.NewObjectID()
+	created, err := s.CreateTask(ctx, models.Task{
+		Title:       "write the " + tag + " report",
+		Description: "quarterly numbers",
+		UserID:      owner,
+		DueAt:       dueAt(0),
+	}, tag)
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	if created.ID.IsZero() || created.Version != 1 {
+		return fmt.Errorf("create: got ID %s at version %d, want a new ID at version 1", created.ID.Hex(), created.Version)
+	}
+	if err := expectEqual("stored due date", created.DueAt.Equal(*stored(dueAt(0))), true); err != nil {
+		return err
+	}
+
+	got, err := s.GetTask(ctx, created.ID)
+	if err != nil {
+		return fmt.Errorf("get: %w", err)
+	}
+	if err := expectTask("get", *got, *created); err != nil {
+		return err
+	}
+
+	replacement := models.Task{Title: "review the " + tag + " report", Completed: true, UserID: owner}
+	_, err = s.ReplaceTask(ctx, created.ID, replacement, []int64{3, 4}, tag)
+	if err := expectErr("replace at other versions", err, store.ErrStale); err != nil {
+		return err
+	}
+	replaced, err := s.ReplaceTask(ctx, created.ID, replacement, []int64{0, 1}, tag)
+	if err != nil {
+		return fmt.Errorf("replace: %w", err)
+	}
+	replacement.ID, replacement.Version = created.ID, 2
+	if err := expectTask("replace", *replaced, replacement); err != nil {
+		return err
+	}
+	got, err = s.GetTask(ctx, created.ID)
+	if err != nil {
+		return fmt.Errorf("get replaced: %w", err)
+	}
+	if err := expectTask("get replaced", *got, replacement); err != nil {
+		return err
+	}
+
+	err = s.DeleteTask(ctx, created.ID, []int64{1}, tag)
+	if err := expectErr("delete at another version", err, store.ErrStale); err != nil {
+		return err
+	}
+	if err := s.DeleteTask(ctx, created.ID, []int64{2}, tag); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	missing := created.ID
+	_, getErr := s.GetTask(ctx, missing)
+	_, replaceErr := s.ReplaceTask(ctx, missing, replacement, nil, tag)
+	_, updateErr := s.UpdateTask(ctx, missing, nil, func(*models.Task) error { return nil }, tag)
+	return first(
+		expectErr("get deleted", getErr, store.ErrNotFound),
+		expectErr("replace deleted", replaceErr, store.ErrNotFound),
+		expectErr("update deleted", updateErr, store.ErrNotFound),
+		expectErr("delete deleted", s.DeleteTask(ctx, missing, nil, tag), store.ErrNotFound),
+		expectErr("delete never created", s.DeleteTask(ctx, primitive.NewObjectID(), nil, tag), store.ErrNotFound),
+	)
+}
+
+func checkTaskUpdates(ctx context.Context, s store.Storage, tag string) error {
+	created, err := s.CreateTask(ctx, models.Task{Title: "plan", UserID: primitive.NewObjectID()}, tag)
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+
+	unchanged, err := s.UpdateTask(ctx, created.ID, []int64{1}, func(task *models.Task) error {
+		task.Title = "plan"
+		return nil
+	}, tag)
+	if err != nil {
+		return fmt.Errorf("update without changes: %w", err)
+	}
+	if err := expectTask("update without changes", *unchanged, *created); err != nil {
+		return err
+	}
+
+	updated, err := s.UpdateTask(ctx, created.ID, []int64{1}, func(task *models.Task) error {
+		task.Completed = true
+		task.DueAt = stored(dueAt(1))
+		task.ID = primitive.NewObjectID()
+		return nil
+	}, tag)
+	if err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+	want := *created
+	want.Completed, want.DueAt, want.Version = true, stored(dueAt(1)), 2
+	if err := expectTask("update", *updated, want); err != nil {
+		return err
+	}
+
+	// Clearing the due date removes it
+	cleared, err := s.UpdateTask(ctx, created.ID, []int64{2}, func(task *models.Task) error {
+		task.DueAt = nil
+		return nil
+	}, tag)
+	if err != nil {
+		return fmt.Errorf("clear due date: %w", err)
+	}
+	want.DueAt, want.Version = nil, 3
+	if err := expectTask("clear due date", *cleared, want); err != nil {
+		return err
+	}
+
+	refused := errors.New("refused")
+	_, err = s.UpdateTask(ctx, created.ID, nil, func(task *models.Task) error {
+		task.Title = "abandon"
+		return refused
+	}, tag)
+	_, staleErr := s.UpdateTask(ctx, created.ID, []int64{2}, func(*models.Task) error { return nil }, tag)
+	got, getErr := s.GetTask(ctx, created.ID)
+	if getErr != nil {
+		return fmt.Errorf("get: %w", getErr)
+	}
+	return first(
+		expectErr("update returning an error", err, refused),
+		expectErr("update at another version", staleErr, store.ErrStale),
+		expectTask("after failed updates", *got, want),
+	)
+}
+
+func checkFind(ctx context.Context, s store.Storage, tag string) error {
+	owner := primitive.NewObjectID()
+	var ids []primitive.ObjectID
+	for _, title := range []string{"c", "a", "b"} {
+		task, err := s.CreateTask(ctx, models.Task{Title: title, UserID: owner}, tag)
+		if err != nil {
+			return fmt.Errorf("create: %w", err)
+		}
+		ids = append(ids, task.ID)
+	}
+
+	// Oldest first, whatever the order of the IDs asked for
+	tasks, err := s.FindTasks(ctx, store.IDIn([]primitive.ObjectID{ids[2], ids[0], primitive.NewObjectID()}))
+	if err != nil {
+		return fmt.Errorf("find tasks by ID: %w", err)
+	}
+	if err := expectEqual("find tasks by ID", taskTitles(tasks), []string{"c", "b"}); err != nil {
+		return err
+	}
+	tasks, err = s.FindTasks(ctx, store.FieldIs(store.TaskFilterFields, "userId", owner))
+	if err != nil {
+		return fmt.Errorf("find tasks by user: %w", err)
+	}
+	if err := expectEqual("find tasks by user", taskTitles(tasks), []string{"c", "a", "b"}); err != nil {
+		return err
+	}
+
+	var userIDs []primitive.ObjectID
+	for _, name := range []string{"yoshi", "xena"} {
+		user, err := s.CreateUser(ctx, newUser(tag, name))
+		if err != nil {
+			return fmt.Errorf("create user: %w", err)
+		}
+		userIDs = append(userIDs, user.ID)
+	}
+	users, err := s.FindUsers(ctx, store.IDIn([]primitive.ObjectID{userIDs[1], userIDs[0]}))
+	if err != nil {
+		return fmt.Errorf("find users: %w", err)
+	}
+	var names []string
+	for _, user := range users {
+		names = append(names, user.Name)
+	}
+	return expectEqual("find users", names, []string{"yoshi", "xena"})
+}
+
+// taskTitles returns the titles of tasks, in order
+func taskTitles(tasks []models.Task) []string {
+	titles := []string{}
+	for _, task := range tasks {
+		titles = append(titles, task.Title)
+	}
+	return titles
+}
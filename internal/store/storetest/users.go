@@ -0,0 +1,157 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/cmerin0/tasky/internal/models"
+	"github.com/cmerin0/tasky/internal/store"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// newUser returns a valid user tagged for the run
+func newUser(tag, name string) models.User {
+	return models.User{Name: name, Email: name + "." + tag + "@example.com", Password: "secret"}
+}
+
+func checkUsers(ctx context.Context, s store.Storage, tag string) error {
+	created, err := s.CreateUser(ctx, newUser(tag, "ada"))
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	if created.ID.IsZero() || created.Version != 1 {
+		return fmt.Errorf("create: got ID %s at version %d, want a new ID at version 1", created.ID.Hex(), created.Version)
+	}
+
+	got, err := s.GetUser(ctx, created.ID)
+	if err != nil {
+		return fmt.Errorf("get: %w", err)
+	}
+	if err := expectEqual("get", *got, *created); err != nil {
+		return err
+	}
+
+	_, err = s.ReplaceUser(ctx, created.ID, newUser(tag, "grace"), []int64{2})
+	if err := expectErr("replace at another version", err, store.ErrStale); err != nil {
+		return err
+	}
+	replaced, err := s.ReplaceUser(ctx, created.ID, newUser(tag, "grace"), []int64{1})
+	if err != nil {
+		return fmt.Errorf("replace: %w", err)
+	}
+	want := models.UserResponse{ID: created.ID, Name: "grace", Email: newUser(tag, "grace").Email, Version: 2}
+	if err := expectEqual("replace", *replaced, want); err != nil {
+		return err
+	}
+
+	err = s.DeleteUser(ctx, created.ID, []int64{1})
+	if err := expectErr("delete at another version", err, store.ErrStale); err != nil {
+		return err
+	}
+	if err := s.DeleteUser(ctx, created.ID, nil); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	missing := created.ID
+	_, getErr := s.GetUser(ctx, missing)
+	_, replaceErr := s.ReplaceUser(ctx, missing, newUser(tag, "ada"), nil)
+	_, updateErr := s.UpdateUser(ctx, missing, nil, func(*models.User) error { return nil })
+	return first(
+		expectErr("get deleted", getErr, store.ErrNotFound),
+		expectErr("replace deleted", replaceErr, store.ErrNotFound),
+		expectErr("update deleted", updateErr, store.ErrNotFound),
+		expectErr("delete deleted", s.DeleteUser(ctx, missing, nil), store.ErrNotFound),
+		expectErr("delete never created", s.DeleteUser(ctx, primitive.NewObjectID(), nil), store.ErrNotFound),
+	)
+}
+
+func checkUserUpdates(ctx context.Context, s store.Storage, tag string) error {
+	created, err := s.CreateUser(ctx, newUser(tag, "alan"))
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+
+	// The password is only handed to updates
+	var password string
+	unchanged, err := s.UpdateUser(ctx, created.ID, []int64{1}, func(user *models.User) error {
+		password = user.Password
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("update without changes: %w", err)
+	}
+	if err := first(
+		expectEqual("password read by update", password, "secret"),
+		expectEqual("update without changes", *unchanged, *created),
+	); err != nil {
+		return err
+	}
+
+	updated, err := s.UpdateUser(ctx, created.ID, []int64{1}, func(user *models.User) error {
+		user.Name = "alonzo"
+		user.Version = 42
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+	want := models.UserResponse{ID: created.ID, Name: "alonzo", Email: created.Email, Version: 2}
+	if err := expectEqual("update", *updated, want); err != nil {
+		return err
+	}
+
+	refused := errors.New("refused")
+	_, err = s.UpdateUser(ctx, created.ID, nil, func(user *models.User) error {
+		user.Name = "kurt"
+		return refused
+	})
+	_, staleErr := s.UpdateUser(ctx, created.ID, []int64{1}, func(*models.User) error { return nil })
+	got, getErr := s.GetUser(ctx, created.ID)
+	return first(
+		expectErr("update returning an error", err, refused),
+		expectErr("update at another version", staleErr, store.ErrStale),
+		getErr,
+		expectEqual("after failed updates", *got, want),
+	)
+}
+
+func checkValidation(ctx context.Context, s store.Storage, tag string) error {
+	var invalid *store.ValidationError
+
+	_, err := s.CreateUser(ctx, models.User{Name: "nobody", Email: "not an email", Password: "secret"})
+	if !errors.As(err, &invalid) {
+		return fmt.Errorf("create invalid user: got error %v, want a validation error", err)
+	}
+	_, err = s.CreateTask(ctx, models.Task{Title: "no owner"}, tag)
+	if !errors.As(err, &invalid) {
+		return fmt.Errorf("create task without user: got error %v, want a validation error", err)
+	}
+
+	user, err := s.CreateUser(ctx, newUser(tag, "edsger"))
+	if err != nil {
+		return fmt.Errorf("create user: %w", err)
+	}
+	_, err = s.UpdateUser(ctx, user.ID, nil, func(user *models.User) error {
+		user.Email = ""
+		return nil
+	})
+	if !errors.As(err, &invalid) {
+		return fmt.Errorf("update user with no email: got error %v, want a validation error", err)
+	}
+
+	task, err := s.CreateTask(ctx, models.Task{Title: "valid", UserID: user.ID}, tag)
+	if err != nil {
+		return fmt.Errorf("create task: %w", err)
+	}
+	_, err = s.ReplaceTask(ctx, task.ID, models.Task{UserID: user.ID}, nil, tag)
+	if !errors.As(err, &invalid) {
+		return fmt.Errorf("replace task without title: got error %v, want a validation error", err)
+	}
+	got, err := s.GetTask(ctx, task.ID)
+	if err != nil {
+		return fmt.Errorf("get task: %w", err)
+	}
+	return expectEqual("task after invalid replace", got.Version, int64(1))
+}
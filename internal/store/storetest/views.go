@@ -0,0 +1,112 @@
+package storetest
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cmerin0/tasky/internal/models"
+	"github.com/cmerin0/tasky/internal/store"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// checkViews checks saved views, on backends which store them
+func checkViews(ctx context.Context, s store.Storage, tag string) error {
+	views, ok := s.(store.ViewRepository)
+	if !ok {
+		return nil
+	}
+
+	owner, other := primitive.NewObjectID(), primitive.NewObjectID()
+	project, workspace := "project-"+tag, "workspace-"+tag
+
+	private, err := views.CreateView(ctx, models.View{
+		Name: "Mine", OwnerID: owner, Filter: "completed:false", Sort: "-title",
+		Columns: []string{"title", "dueAt"}, Visibility: models.ViewPrivate,
+	})
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	if private.ID.IsZero() {
+		return fmt.Errorf("create: the view has no ID")
+	}
+	shared, err := views.CreateView(ctx, models.View{Name: "Team", OwnerID: other, Visibility: models.ViewProject, SharedWith: project})
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	if _, err := views.CreateView(ctx, models.View{Name: "Org", OwnerID: other, Visibility: models.ViewWorkspace, SharedWith: workspace}); err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+
+	got, err := views.GetView(ctx, private.ID)
+	if err != nil {
+		return fmt.Errorf("get: %w", err)
+	}
+	if err := expectEqual("get", *got, *private); err != nil {
+		return err
+	}
+	_, err = views.GetView(ctx, primitive.NewObjectID())
+	if err := expectErr("get missing", err, store.ErrNotFound); err != nil {
+		return err
+	}
+
+	// Shared views are only listed for their project or workspace
+	names := func(project, workspace string) ([]string, error) {
+		list, err := views.ListViews(ctx, owner, project, workspace)
+		if err != nil {
+			return nil, fmt.Errorf("list: %w", err)
+		}
+		names := []string{}
+		for _, view := range list {
+			names = append(names, view.Name)
+		}
+		return names, nil
+	}
+	for _, tt := range []struct {
+		project, workspace string
+		want               []string
+	}{
+		{"", "", []string{"Mine"}},
+		{project, "", []string{"Mine", "Team"}},
+		{project, workspace, []string{"Mine", "Team", "Org"}},
+		{"elsewhere", workspace, []string{"Mine", "Org"}},
+	} {
+		got, err := names(tt.project, tt.workspace)
+		if err != nil {
+			return err
+		}
+		if err := expectEqual(fmt.Sprintf("list for %q and %q", tt.project, tt.workspace), got, tt.want); err != nil {
+			return err
+		}
+	}
+
+	// Only the owner changes a view
+	update := *shared
+	update.Name, update.OwnerID = "Taken", owner
+	if err := expectErr("update by another user", views.UpdateView(ctx, update), store.ErrNotFound); err != nil {
+		return err
+	}
+	update = *private
+	update.Name, update.Columns = "Renamed", []string{"title"}
+	if err := views.UpdateView(ctx, update); err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+	if got, err = views.GetView(ctx, private.ID); err != nil {
+		return fmt.Errorf("get: %w", err)
+	}
+	if err := expectEqual("updated view", *got, update); err != nil {
+		return err
+	}
+
+	if err := expectErr("delete by another user", views.DeleteView(ctx, shared.ID, owner), store.ErrNotFound); err != nil {
+		return err
+	}
+	if err := views.DeleteView(ctx, private.ID, owner); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+	_, err = views.GetView(ctx, private.ID)
+	return first(
+		expectErr("get deleted", err, store.ErrNotFound),
+		expectErr("delete again", views.DeleteView(ctx, private.ID, owner), store.ErrNotFound),
+	)
+}
@@ -0,0 +1,220 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/cmerin0/tasky/internal/filter"
+	"github.com/cmerin0/tasky/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GetTask returns a task by ID
+func (m *Mongo) GetTask(ctx context.Context, id primitive.ObjectID) (*models.Task, error) {
+	var task models.Task
+	err := Tasks().FindOne(ctx, bson.M{"_id": id}).Decode(&task)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// ListTasks returns a page of tasks
+func (m *Mongo) ListTasks(ctx context.Context, where filter.Expr, req PageRequest, fields []string) (*Page[models.Task], error) {
+	var projection bson.M
+	if fields != nil {
+		projection = bson.M{"_id": 1}
+		for _, field := range fields {
+			projection[field] = 1
+		}
+	}
+	return Paginate[models.Task](ctx, Tasks(), mongoFilter(where), projection, req)
+}
+
+// FindTasks returns every matching task
+func (m *Mongo) FindTasks(ctx context.Context, where filter.Expr) ([]models.Task, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	cursor, err := Tasks().Find(ctx, mongoFilter(where), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks := []models.Task{}
+	if err := cursor.All(ctx, &tasks); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// SearchTasks runs a full text search over the tasks_text index.
+// text follows the $text syntax: quoted phrases and -negated words.
+func (m *Mongo) SearchTasks(ctx context.Context, text string, where filter.Expr, limit int) ([]TaskMatch, error) {
+	query := bson.M{"$text": bson.M{"$search": text}}
+	if where != nil {
+		query = bson.M{"$and": bson.A{query, mongoFilter(where)}}
+	}
+
+	score := bson.M{"$meta": "textScore"}
+	cursor, err := Tasks().Find(ctx, query, options.Find().
+		SetProjection(bson.M{"score": score}).
+		SetSort(bson.D{{Key: "score", Value: score}}).
+		SetLimit(int64(limit)))
+	if err != nil {
+		return nil, err
+	}
+
+	matches := []TaskMatch{}
+	if err := cursor.All(ctx, &matches); err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
+
+// CreateTask validates and inserts a new task at version 1 along with its
+// history entry and task.created event
+func (m *Mongo) CreateTask(ctx context.Context, task models.Task, actor string) (*models.Task, error) {
+	if err := Validate(task); err != nil {
+		return nil, err
+	}
+
+	newTask := models.Task{
+		Title:       task.Title,
+		Description: task.Description,
+		Completed:   task.Completed,
+		UserID:      task.UserID,
+		DueAt:       task.DueAt,
+		Version:     1,
+	}
+	err := WithTransaction(ctx, func(sc mongo.SessionContext) error {
+		result, err := Tasks().InsertOne(sc, newTask)
+		if err != nil {
+			return err
+		}
+		newTask.ID = result.InsertedID.(primitive.ObjectID)
+		if err := recordTaskHistory(sc, models.HistoryCreated, actor, nil, &newTask); err != nil {
+			return err
+		}
+		return EnqueueTask(sc, models.EventTaskCreated, nil, &newTask)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &newTask, nil
+}
+
+// ReplaceTask validates and replaces every field of a task, bumping its version,
+// along with its history entry and task.updated event
+func (m *Mongo) ReplaceTask(ctx context.Context, id primitive.ObjectID, task models.Task, versions []int64, actor string) (*models.Task, error) {
+	task.ID = id
+	if err := Validate(task); err != nil {
+		return nil, err
+	}
+
+	var before models.Task
+	coll := Tasks()
+	err := WithTransaction(ctx, func(sc mongo.SessionContext) error {
+		// The version is bumped server side, the one given is ignored
+		if err := coll.FindOne(sc, versionFilter(id, versions)).Decode(&before); err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				return missingOrStale(sc, coll, id)
+			}
+			return err
+		}
+		task.Version = before.Version + 1
+
+		// Replace only the version we read, so concurrent writes aren't lost
+		result, err := coll.ReplaceOne(sc, versionFilter(id, []int64{before.Version}), task)
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return missingOrStale(sc, coll, id)
+		}
+		if err := recordTaskHistory(sc, models.HistoryUpdated, actor, &before, &task); err != nil {
+			return err
+		}
+		return EnqueueTask(sc, models.EventTaskUpdated, &before, &task)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// UpdateTask writes the fields change modified, along with the history
+// entry and task.updated event when anything changed
+func (m *Mongo) UpdateTask(ctx context.Context, id primitive.ObjectID, versions []int64, change func(task *models.Task) error, actor string) (*models.Task, error) {
+	var before, after models.Task
+	coll := Tasks()
+	err := WithTransaction(ctx, func(sc mongo.SessionContext) error {
+		if err := coll.FindOne(sc, versionFilter(id, versions)).Decode(&before); err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				return missingOrStale(sc, coll, id)
+			}
+			return err
+		}
+
+		after = before
+		if err := change(&after); err != nil {
+			return err
+		}
+		after.ID, after.Version = before.ID, before.Version
+		if err := Validate(after); err != nil {
+			return err
+		}
+
+		// Only the fields that actually changed are written
+		changes := DiffTasks(&before, &after)
+		if len(changes) == 0 {
+			return nil
+		}
+		after.Version++
+		update := bson.M{"version": after.Version}
+		for _, change := range changes {
+			update[change.Field] = change.To
+		}
+
+		// Write only over the version the change was applied to
+		result, err := coll.UpdateOne(sc, versionFilter(id, []int64{before.Version}), bson.M{"$set": update})
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return missingOrStale(sc, coll, id)
+		}
+		if err := recordTaskHistory(sc, models.HistoryUpdated, actor, &before, &after); err != nil {
+			return err
+		}
+		return EnqueueTask(sc, models.EventTaskUpdated, &before, &after)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &after, nil
+}
+
+// DeleteTask deletes a task along with its history entry and task.deleted event
+func (m *Mongo) DeleteTask(ctx context.Context, id primitive.ObjectID, versions []int64, actor string) error {
+	var deleted models.Task
+	coll := Tasks()
+	return WithTransaction(ctx, func(sc mongo.SessionContext) error {
+		err := coll.FindOneAndDelete(sc, versionFilter(id, versions)).Decode(&deleted)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return missingOrStale(sc, coll, id)
+		}
+		if err != nil {
+			return err
+		}
+		if err := recordTaskHistory(sc, models.HistoryDeleted, actor, &deleted, nil); err != nil {
+			return err
+		}
+		return EnqueueTask(sc, models.EventTaskDeleted, &deleted, nil)
+	})
+}
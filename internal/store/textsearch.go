@@ -0,0 +1,165 @@
+package store
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/cmerin0/tasky/internal/models"
+)
+
+// Weights of the task fields, as in the tasks_text index
+const (
+	titleWeight       = 10
+	descriptionWeight = 1
+)
+
+// stopWords are ignored by searches, like MongoDB does for English
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "for": true, "from": true, "in": true, "is": true,
+	"it": true, "of": true, "on": true, "or": true, "that": true, "the": true,
+	"this": true, "to": true, "was": true, "with": true,
+}
+
+// TextSearch scores tasks against a text search the way the tasks_text
+// index of MongoDB does, for backends without a text index of their own.
+// Words are stemmed, phrases are matched as is.
+type TextSearch struct {
+	terms          []string
+	phrases        []string
+	negatedTerms   []string
+	negatedPhrases []string
+}
+
+// NewTextSearch parses a text search, stemming its words
+func NewTextSearch(text string) TextSearch {
+	parsed := ParseSearch(text)
+	q := TextSearch{phrases: parsed.Phrases, negatedPhrases: parsed.NegatedPhrases}
+	for _, word := range parsed.Words {
+		q.terms = append(q.terms, tokenize(word)...)
+	}
+	for _, word := range parsed.NegatedWords {
+		q.negatedTerms = append(q.negatedTerms, tokenize(word)...)
+	}
+
+	// Words of phrases count towards the score as well
+	for _, phrase := range q.phrases {
+		q.terms = append(q.terms, tokenize(phrase)...)
+	}
+	return q
+}
+
+// Score reports whether a task matches the search, and how well. Tasks must
+// contain every phrase, or any term when there are no phrases, and no
+// negated term or phrase.
+func (q TextSearch) Score(task models.Task) (float64, bool) {
+	title, description := strings.ToLower(task.Title), strings.ToLower(task.Description)
+	for _, phrase := range q.negatedPhrases {
+		if strings.Contains(title, phrase) || strings.Contains(description, phrase) {
+			return 0, false
+		}
+	}
+	for _, phrase := range q.phrases {
+		if !strings.Contains(title, phrase) && !strings.Contains(description, phrase) {
+			return 0, false
+		}
+	}
+
+	titleTokens, descriptionTokens := tokenize(title), tokenize(description)
+	for _, term := range q.negatedTerms {
+		if contains(titleTokens, term) || contains(descriptionTokens, term) {
+			return 0, false
+		}
+	}
+
+	score := fieldScore(titleTokens, q.terms, titleWeight) + fieldScore(descriptionTokens, q.terms, descriptionWeight)
+	if score == 0 && len(q.phrases) == 0 {
+		return 0, false
+	}
+	return score, true
+}
+
+// RankMatches sorts matches by score, best first, and keeps the first
+// limit. Equal scores keep the order they come in.
+func RankMatches(matches []TaskMatch, limit int) []TaskMatch {
+	slices.SortStableFunc(matches, func(a, b TaskMatch) int {
+		switch {
+		case a.Score > b.Score:
+			return -1
+		case a.Score < b.Score:
+			return 1
+		}
+		return 0
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// fieldScore scores the terms found in the tokens of a field. Like MongoDB
+// each term counts once, more for repeated terms, less in longer fields.
+func fieldScore(tokens, terms []string, weight float64) float64 {
+	score := 0.0
+	seen := map[string]bool{}
+	for _, term := range terms {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+
+		freq := 0
+		for _, token := range tokens {
+			if token == term {
+				freq++
+			}
+		}
+		if freq > 0 {
+			score += weight * (0.5 + 0.5*float64(freq)/float64(len(tokens)))
+		}
+	}
+	return score
+}
+
+// tokenize splits text into lower case stemmed words, without stop words
+func tokenize(text string) []string {
+	var tokens []string
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	for _, word := range words {
+		if !stopWords[word] {
+			tokens = append(tokens, stem(word))
+		}
+	}
+	return tokens
+}
+
+// stem strips common English suffixes, so that "release", "releases" and
+// "released" all match each other
+func stem(word string) string {
+	for _, suffix := range []string{"ing", "ed", "es", "s"} {
+		if suffix == "s" && strings.HasSuffix(word, "ss") {
+			continue
+		}
+		if strings.HasSuffix(word, suffix) && len(word)-len(suffix) >= 3 {
+			word = word[:len(word)-len(suffix)]
+			break
+		}
+	}
+	if strings.HasSuffix(word, "e") && len(word) > 3 {
+		word = word[:len(word)-1]
+	}
+	return word
+}
+
+// contains reports whether a token is among tokens
+func contains(tokens []string, token string) bool {
+	for _, t := range tokens {
+		if t == token {
+			return true
+		}
+	}
+	return false
+}
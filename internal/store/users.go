@@ -0,0 +1,193 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/cmerin0/tasky/internal/filter"
+	"github.com/cmerin0/tasky/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GetUser returns a user by ID, without the password
+func (m *Mongo) GetUser(ctx context.Context, id primitive.ObjectID) (*models.UserResponse, error) {
+	var user models.UserResponse
+	err := Users().FindOne(ctx, bson.M{"_id": id}).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// ListUsers returns a page of users
+func (m *Mongo) ListUsers(ctx context.Context, where filter.Expr, req PageRequest) (*Page[models.UserResponse], error) {
+	return Paginate[models.UserResponse](ctx, Users(), mongoFilter(where), nil, req)
+}
+
+// FindUsers returns every matching user, never selecting the password
+func (m *Mongo) FindUsers(ctx context.Context, where filter.Expr) ([]models.UserResponse, error) {
+	opts := options.Find().SetProjection(bson.M{"password": 0}).SetSort(bson.D{{Key: "_id", Value: 1}})
+	cursor, err := Users().Find(ctx, mongoFilter(where), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	users := []models.UserResponse{}
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// CreateUser validates and inserts a new user at version 1
+// along with its user.created event
+func (m *Mongo) CreateUser(ctx context.Context, user models.User) (*models.UserResponse, error) {
+	if err := Validate(user); err != nil {
+		return nil, err
+	}
+
+	newUser := models.User{
+		Name:     user.Name,
+		Email:    user.Email,
+		Password: user.Password,
+		Version:  1,
+	}
+	var created *models.UserResponse
+	err := WithTransaction(ctx, func(sc mongo.SessionContext) error {
+		result, err := Users().InsertOne(sc, newUser)
+		if err != nil {
+			return err
+		}
+		created = &models.UserResponse{
+			ID:      result.InsertedID.(primitive.ObjectID),
+			Name:    newUser.Name,
+			Email:   newUser.Email,
+			Version: newUser.Version,
+		}
+		return Enqueue(sc, models.EventUserCreated, created)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// ReplaceUser validates and replaces every field of a user, bumping its version,
+// along with its user.updated event
+func (m *Mongo) ReplaceUser(ctx context.Context, id primitive.ObjectID, user models.User, versions []int64) (*models.UserResponse, error) {
+	user.ID = id
+	if err := Validate(user); err != nil {
+		return nil, err
+	}
+
+	var replaced *models.UserResponse
+	coll := Users()
+	err := WithTransaction(ctx, func(sc mongo.SessionContext) error {
+		// The version is bumped server side, the one given is ignored
+		var current models.UserResponse
+		if err := coll.FindOne(sc, versionFilter(id, versions)).Decode(&current); err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				return missingOrStale(sc, coll, id)
+			}
+			return err
+		}
+		user.Version = current.Version + 1
+
+		// Replace only the version we read, so concurrent writes aren't lost
+		result, err := coll.ReplaceOne(sc, versionFilter(id, []int64{current.Version}), user)
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return missingOrStale(sc, coll, id)
+		}
+
+		replaced = &models.UserResponse{ID: id, Name: user.Name, Email: user.Email, Version: user.Version}
+		return Enqueue(sc, models.EventUserUpdated, replaced)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return replaced, nil
+}
+
+// UpdateUser writes the fields change modified, along with the
+// user.updated event when anything changed
+func (m *Mongo) UpdateUser(ctx context.Context, id primitive.ObjectID, versions []int64, change func(user *models.User) error) (*models.UserResponse, error) {
+	var updated *models.UserResponse
+	coll := Users()
+	err := WithTransaction(ctx, func(sc mongo.SessionContext) error {
+		var before models.User
+		if err := coll.FindOne(sc, versionFilter(id, versions)).Decode(&before); err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				return missingOrStale(sc, coll, id)
+			}
+			return err
+		}
+
+		after := before
+		if err := change(&after); err != nil {
+			return err
+		}
+		after.ID, after.Version = before.ID, before.Version
+		if err := Validate(after); err != nil {
+			return err
+		}
+
+		update := bson.M{}
+		if after.Name != before.Name {
+			update["name"] = after.Name
+		}
+		if after.Email != before.Email {
+			update["email"] = after.Email
+		}
+		if after.Password != before.Password {
+			update["password"] = after.Password
+		}
+		if len(update) > 0 {
+			after.Version++
+			update["version"] = after.Version
+		}
+		updated = &models.UserResponse{ID: after.ID, Name: after.Name, Email: after.Email, Version: after.Version}
+		if len(update) == 0 {
+			return nil
+		}
+
+		// Write only over the version the change was applied to
+		result, err := coll.UpdateOne(sc, versionFilter(id, []int64{before.Version}), bson.M{"$set": update})
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return missingOrStale(sc, coll, id)
+		}
+		return Enqueue(sc, models.EventUserUpdated, updated)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// DeleteUser deletes a user along with its user.deleted event
+func (m *Mongo) DeleteUser(ctx context.Context, id primitive.ObjectID, versions []int64) error {
+	coll := Users()
+	return WithTransaction(ctx, func(sc mongo.SessionContext) error {
+		var deleted models.UserResponse
+		err := coll.FindOneAndDelete(sc, versionFilter(id, versions)).Decode(&deleted)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return missingOrStale(sc, coll, id)
+		}
+		if err != nil {
+			return err
+		}
+		return Enqueue(sc, models.EventUserDeleted, deleted)
+	})
+}
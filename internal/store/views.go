@@ -0,0 +1,90 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/cmerin0/tasky/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GetView returns a saved view by ID
+func (m *Mongo) GetView(ctx context.Context, id primitive.ObjectID) (*models.View, error) {
+	var view models.View
+	err := Views().FindOne(ctx, bson.M{"_id": id}).Decode(&view)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &view, nil
+}
+
+// ListViews returns the views of owner plus those shared with project or workspace
+func (m *Mongo) ListViews(ctx context.Context, owner primitive.ObjectID, project, workspace string) ([]models.View, error) {
+	visible := bson.A{bson.M{"ownerId": owner}}
+	if project != "" {
+		visible = append(visible, bson.M{"visibility": models.ViewProject, "sharedWith": project})
+	}
+	if workspace != "" {
+		visible = append(visible, bson.M{"visibility": models.ViewWorkspace, "sharedWith": workspace})
+	}
+
+	cursor, err := Views().Find(ctx, bson.M{"$or": visible}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	views := []models.View{}
+	if err := cursor.All(ctx, &views); err != nil {
+		return nil, err
+	}
+	return views, nil
+}
+
+// CreateView stores a new view
+func (m *Mongo) CreateView(ctx context.Context, view models.View) (*models.View, error) {
+	view.ID = primitive.NilObjectID
+	result, err := Views().InsertOne(ctx, view)
+	if err != nil {
+		return nil, err
+	}
+	view.ID = result.InsertedID.(primitive.ObjectID)
+	return &view, nil
+}
+
+// UpdateView replaces a view of its owner
+func (m *Mongo) UpdateView(ctx context.Context, view models.View) error {
+	update := bson.M{
+		"name":       view.Name,
+		"filter":     view.Filter,
+		"sort":       view.Sort,
+		"columns":    view.Columns,
+		"visibility": view.Visibility,
+		"sharedWith": view.SharedWith,
+	}
+	result, err := Views().UpdateOne(ctx, bson.M{"_id": view.ID, "ownerId": view.OwnerID}, bson.M{"$set": update})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteView deletes a view of its owner
+func (m *Mongo) DeleteView(ctx context.Context, id, owner primitive.ObjectID) error {
+	result, err := Views().DeleteOne(ctx, bson.M{"_id": id, "ownerId": owner})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
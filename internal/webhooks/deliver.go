@@ -0,0 +1,182 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cmerin0/tasky/internal/jobs"
+	"github.com/cmerin0/tasky/internal/models"
+	"github.com/cmerin0/tasky/internal/store"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// JobDeliver is the job making the attempts at a delivery
+const JobDeliver = "webhook.deliver"
+
+// MaxAttempts is how many attempts are made before a delivery is marked as failed
+const MaxAttempts = 8
+
+// deliveryJob is the payload of a delivery job
+type deliveryJob struct {
+	DeliveryID primitive.ObjectID `json:"deliveryId"`
+}
+
+// enqueue queues the job delivering a delivery, once
+func enqueue(ctx context.Context, deliveryID primitive.ObjectID) error {
+	_, err := jobs.EnqueueOnce(ctx, "webhook:"+deliveryID.Hex(), JobDeliver, deliveryJob{DeliveryID: deliveryID})
+	return err
+}
+
+// Queue stores a new delivery and queues its job, in one transaction
+func Queue(ctx context.Context, delivery *models.WebhookDelivery) error {
+	return store.WithTransaction(ctx, func(sc mongo.SessionContext) error {
+		result, err := store.WebhookDeliveries().InsertOne(sc, delivery)
+		if err != nil {
+			return err
+		}
+		delivery.ID = result.InsertedID.(primitive.ObjectID)
+		return enqueue(sc, delivery.ID)
+	})
+}
+
+// Requeue queues the jobs of pending deliveries that don't have one,
+// such as those created before deliveries ran on the job queue
+func Requeue(ctx context.Context) error {
+	cursor, err := store.WebhookDeliveries().Find(ctx, bson.M{"status": models.DeliveryPending},
+		options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		var delivery models.WebhookDelivery
+		if err := cursor.Decode(&delivery); err != nil {
+			return err
+		}
+		if err := enqueue(ctx, delivery.ID); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+// Deliver is the handler of delivery jobs, making one attempt at the
+// delivery. Failed attempts are retried by the job queue until the last
+// one marks the delivery as failed.
+func Deliver(ctx context.Context, job *models.Job) error {
+	var payload deliveryJob
+	if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+		return err
+	}
+
+	var delivery models.WebhookDelivery
+	err := store.WebhookDeliveries().FindOne(ctx, bson.M{"_id": payload.DeliveryID}).Decode(&delivery)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	// A job run again after its worker died may find the delivery done
+	if delivery.Status != models.DeliveryPending {
+		return nil
+	}
+
+	var webhook models.Webhook
+	err = store.Webhooks().FindOne(ctx, bson.M{"_id": delivery.WebhookID}).Decode(&webhook)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return err
+	}
+
+	var attempt models.DeliveryAttempt
+	switch {
+	case webhook.ID.IsZero():
+		attempt = models.DeliveryAttempt{At: time.Now().UTC(), Error: "webhook was deleted"}
+	case !webhook.Active:
+		attempt = models.DeliveryAttempt{At: time.Now().UTC(), Error: "webhook is inactive"}
+	default:
+		attempt = send(ctx, &webhook, &delivery)
+	}
+
+	set := bson.M{"nextAttemptAt": nil}
+	var result error
+	switch {
+	case attempt.Error == "":
+		set["status"] = models.DeliverySucceeded
+	case !webhook.Active:
+		set["status"] = models.DeliveryFailed
+	case job.Attempts >= MaxAttempts:
+		set["status"] = models.DeliveryFailed
+		result = errors.New(attempt.Error)
+	default:
+		retry := time.Now().UTC().Add(jobs.Backoff(job.Attempts))
+		set["nextAttemptAt"] = retry
+		result = &jobs.RetryAt{At: retry, Err: errors.New(attempt.Error)}
+	}
+
+	_, err = store.WebhookDeliveries().UpdateOne(ctx,
+		bson.M{"_id": delivery.ID, "status": models.DeliveryPending},
+		bson.M{"$set": set, "$push": bson.M{"attempts": attempt}},
+	)
+	if err != nil {
+		return err
+	}
+	return result
+}
+
+// Reset is the retry hook of delivery jobs. Deliver skips deliveries that
+// aren't pending, so the failed delivery of a dead job is set back to
+// pending for the retried job to attempt it again. Its attempt log is kept.
+func Reset(ctx context.Context, job *models.Job) error {
+	var payload deliveryJob
+	if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+		return err
+	}
+	_, err := store.WebhookDeliveries().UpdateOne(ctx,
+		bson.M{"_id": payload.DeliveryID, "status": models.DeliveryFailed},
+		bson.M{"$set": bson.M{"status": models.DeliveryPending, "nextAttemptAt": time.Now().UTC()}},
+	)
+	return err
+}
+
+// send POSTs a delivery to its webhook, any status but 2xx is a failure
+func send(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery) models.DeliveryAttempt {
+	start := time.Now()
+	attempt := models.DeliveryAttempt{At: start.UTC()}
+	body := []byte(delivery.Payload)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Tasky-Webhooks/1")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, delivery.ID.Hex())
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(start.Unix(), 10))
+	req.Header.Set(HeaderSignature, Sign(webhook.Secret, start.Unix(), body))
+
+	resp, err := client.Do(req)
+	attempt.DurationMs = time.Since(start).Milliseconds()
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	resp.Body.Close()
+
+	attempt.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		attempt.Error = resp.Status
+	}
+	return attempt
+}
@@ -0,0 +1,94 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/cmerin0/tasky/internal/db"
+	"github.com/cmerin0/tasky/internal/jobs"
+	"github.com/cmerin0/tasky/internal/models"
+	"github.com/cmerin0/tasky/internal/store"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TestRetryDeadDelivery runs against the MongoDB at TEST_MONGO_URI, in
+// the database named by MONGO_DBNAME or tasky_test
+func TestRetryDeadDelivery(t *testing.T) {
+	uri := os.Getenv("TEST_MONGO_URI")
+	if uri == "" {
+		t.Skip("TEST_MONGO_URI is not set")
+	}
+	if os.Getenv("MONGO_DBNAME") == "" {
+		t.Setenv("MONGO_DBNAME", "tasky_test")
+	}
+	db.ConnectDB(uri)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// An inactive webhook fails its deliveries without sending anything
+	now := time.Now().UTC()
+	webhook := models.Webhook{ID: primitive.NewObjectID(), OwnerID: primitive.NewObjectID(), URL: "https://example.com/hook", Active: false}
+	delivery := models.WebhookDelivery{
+		ID:        primitive.NewObjectID(),
+		WebhookID: webhook.ID,
+		EventType: models.EventTaskCreated,
+		Payload:   "{}",
+		Status:    models.DeliveryFailed,
+		Attempts:  []models.DeliveryAttempt{{At: now, Error: "webhook is inactive"}},
+		CreatedAt: now,
+	}
+	payload, _ := json.Marshal(deliveryJob{DeliveryID: delivery.ID})
+	job := models.Job{ID: primitive.NewObjectID(), Type: JobDeliver, Payload: string(payload), Status: models.JobDead, Attempts: MaxAttempts, CreatedAt: now}
+
+	if _, err := store.Webhooks().InsertOne(ctx, webhook); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.WebhookDeliveries().InsertOne(ctx, delivery); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Jobs().InsertOne(ctx, job); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		store.Webhooks().DeleteOne(context.Background(), bson.M{"_id": webhook.ID})
+		store.WebhookDeliveries().DeleteOne(context.Background(), bson.M{"_id": delivery.ID})
+		store.Jobs().DeleteOne(context.Background(), bson.M{"_id": job.ID})
+	})
+
+	queue := jobs.New()
+	queue.Register(JobDeliver, jobs.Type{Handler: Deliver, MaxAttempts: MaxAttempts, OnRetry: Reset})
+	retried, err := queue.RetryJob(ctx, job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if retried.Status != models.JobPending || retried.Attempts != 0 {
+		t.Fatalf("retried job is %s after %d attempts, want pending after 0", retried.Status, retried.Attempts)
+	}
+
+	var reset models.WebhookDelivery
+	if err := store.WebhookDeliveries().FindOne(ctx, bson.M{"_id": delivery.ID}).Decode(&reset); err != nil {
+		t.Fatal(err)
+	}
+	if reset.Status != models.DeliveryPending || reset.NextAttemptAt == nil || len(reset.Attempts) != 1 {
+		t.Fatalf("delivery wasn't set back to pending with its attempts: %+v", reset)
+	}
+
+	// The retried job makes a new attempt instead of finding nothing to do
+	retried.Attempts = 1
+	if err := Deliver(ctx, retried); err != nil {
+		t.Fatal(err)
+	}
+	var attempted models.WebhookDelivery
+	if err := store.WebhookDeliveries().FindOne(ctx, bson.M{"_id": delivery.ID}).Decode(&attempted); err != nil {
+		t.Fatal(err)
+	}
+	if attempted.Status != models.DeliveryFailed || len(attempted.Attempts) != 2 {
+		t.Fatalf("retried job didn't attempt the delivery again: %+v", attempted)
+	}
+}
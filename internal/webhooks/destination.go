@@ -0,0 +1,99 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// ErrPrivateDestination rejects webhooks that would reach into our own network
+var ErrPrivateDestination = errors.New("url must point to a public address")
+
+// reserved lists the ranges that aren't public beyond what netip reports:
+// "this network", carrier-grade NAT, IETF protocol assignments,
+// benchmarking, reserved and broadcast, and NAT64 which could map to any
+// of them
+var reserved = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+}
+
+// client delivers webhooks. It only dials public addresses, so a hostname
+// that resolves somewhere else after the webhook was created, or a redirect,
+// can't reach internal services. Proxies from the environment are ignored
+// since the check would only see the proxy.
+var client = &http.Client{
+	Timeout:   10 * time.Second,
+	Transport: transport(),
+}
+
+func transport() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = nil
+	t.DialContext = (&net.Dialer{Timeout: 5 * time.Second, Control: dialControl}).DialContext
+	return t
+}
+
+// dialControl refuses connections to addresses that aren't public
+func dialControl(network, address string, _ syscall.RawConn) error {
+	addr, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !public(addr.Addr()) {
+		return ErrPrivateDestination
+	}
+	return nil
+}
+
+// public reports whether an address is reachable on the internet, rather
+// than a loopback, private, link-local or otherwise reserved one
+func public(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range reserved {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckDestination rejects webhook URLs whose host is, or resolves to,
+// an address that isn't public. Deliveries check again when they dial.
+func CheckDestination(ctx context.Context, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	host := u.Hostname()
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if !public(addr) {
+			return ErrPrivateDestination
+		}
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return errors.New("url host could not be resolved")
+	}
+	for _, addr := range addrs {
+		if !public(addr) {
+			return ErrPrivateDestination
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestPublic(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"93.184.215.14":          true,
+		"2606:4700::1111":        true,
+		"127.0.0.1":              false,
+		"::1":                    false,
+		"10.1.2.3":               false,
+		"172.16.0.1":             false,
+		"192.168.1.1":            false,
+		"169.254.169.254":        false,
+		"fe80::1":                false,
+		"fd00::1":                false,
+		"0.0.0.0":                false,
+		"::":                     false,
+		"100.64.0.1":             false,
+		"224.0.0.1":              false,
+		"255.255.255.255":        false,
+		"::ffff:127.0.0.1":       false,
+		"64:ff9b::a9fe:a9fe":     false,
+		"::ffff:169.254.169.254": false,
+	} {
+		if got := public(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("public(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestCheckDestination(t *testing.T) {
+	ctx := context.Background()
+	for _, url := range []string{
+		"http://127.0.0.1:8080/hook",
+		"http://[::1]/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://localhost/hook",
+	} {
+		if err := CheckDestination(ctx, url); !errors.Is(err, ErrPrivateDestination) {
+			t.Errorf("CheckDestination(%s) = %v, want %v", url, err, ErrPrivateDestination)
+		}
+	}
+	if err := CheckDestination(ctx, "https://93.184.215.14/hook"); err != nil {
+		t.Errorf("CheckDestination of a public address: %v", err)
+	}
+}
+
+func TestClientRefusesPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	_, err := client.Post(server.URL, "application/json", nil)
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) || !errors.Is(err, ErrPrivateDestination) {
+		t.Fatalf("got error %v, want %v", err, ErrPrivateDestination)
+	}
+}
@@ -0,0 +1,79 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/cmerin0/tasky/internal/models"
+	"github.com/cmerin0/tasky/internal/store"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Fanout is the outbox handler creating and queuing a delivery of the
+// event for every active webhook of its owner subscribed to it
+func Fanout(ctx context.Context, event *models.OutboxEvent) error {
+	owner, err := eventOwner(event)
+	if err != nil {
+		return err
+	}
+	cursor, err := store.Webhooks().Find(ctx, bson.M{"ownerId": owner, "active": true, "events": event.Type})
+	if err != nil {
+		return err
+	}
+	var subscribed []models.Webhook
+	if err := cursor.All(ctx, &subscribed); err != nil {
+		return err
+	}
+
+	// The fanout key is unique, so relaying an event again after a crash
+	// doesn't deliver it twice, it only queues the job it may have missed
+	now := time.Now().UTC()
+	for _, webhook := range subscribed {
+		delivery := models.WebhookDelivery{
+			ID:            primitive.NewObjectID(),
+			WebhookID:     webhook.ID,
+			EventID:       event.ID,
+			EventType:     event.Type,
+			Payload:       string(event.Payload),
+			Status:        models.DeliveryPending,
+			Attempts:      []models.DeliveryAttempt{},
+			NextAttemptAt: &now,
+			FanoutKey:     event.ID.Hex() + ":" + webhook.ID.Hex(),
+			CreatedAt:     now,
+		}
+		_, err := store.WebhookDeliveries().InsertOne(ctx, delivery)
+		if mongo.IsDuplicateKeyError(err) {
+			err = store.WebhookDeliveries().FindOne(ctx, bson.M{"fanoutKey": delivery.FanoutKey}).Decode(&delivery)
+		}
+		if err != nil {
+			return err
+		}
+		if err := enqueue(ctx, delivery.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// eventOwner returns the user an event belongs to: the owner of the task
+// of task events, the user itself of user events
+func eventOwner(event *models.OutboxEvent) (primitive.ObjectID, error) {
+	var body struct {
+		Data struct {
+			ID     primitive.ObjectID `json:"id"`
+			UserID primitive.ObjectID `json:"userId"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(event.Payload, &body); err != nil {
+		return primitive.NilObjectID, err
+	}
+	if strings.HasPrefix(event.Type, "user.") {
+		return body.Data.ID, nil
+	}
+	return body.Data.UserID, nil
+}
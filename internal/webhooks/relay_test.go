@@ -0,0 +1,42 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/cmerin0/tasky/internal/models"
+	"github.com/cmerin0/tasky/internal/store"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestEventOwner(t *testing.T) {
+	owner, task := primitive.NewObjectID(), primitive.NewObjectID()
+	tests := []struct {
+		eventType string
+		data      interface{}
+	}{
+		{models.EventTaskCreated, models.Task{ID: task, Title: "Ship", UserID: owner}},
+		{models.EventTaskDeleted, models.Task{ID: task, UserID: owner}},
+		{models.EventTaskAssigned, models.Task{ID: task, UserID: owner}},
+		{models.EventUserCreated, models.UserResponse{ID: owner, Name: "Ada"}},
+		{models.EventUserDeleted, models.UserResponse{ID: owner}},
+	}
+	for _, test := range tests {
+		payload, err := json.Marshal(store.EventBody{ID: primitive.NewObjectID(), Type: test.eventType, Data: test.data})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := eventOwner(&models.OutboxEvent{Type: test.eventType, Payload: payload})
+		if err != nil {
+			t.Fatalf("%s: %v", test.eventType, err)
+		}
+		if got != owner {
+			t.Errorf("%s: owner %s, want %s", test.eventType, got.Hex(), owner.Hex())
+		}
+	}
+
+	if _, err := eventOwner(&models.OutboxEvent{Type: models.EventTaskCreated, Payload: []byte("{")}); err == nil {
+		t.Error("a malformed payload has an owner")
+	}
+}
@@ -0,0 +1,150 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/cmerin0/tasky/internal/models"
+	"github.com/cmerin0/tasky/internal/store"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Repository stores webhooks and their deliveries in MongoDB, queuing
+// deliveries on the job queue. db.ConnectDB must have been called.
+type Repository struct{}
+
+// NewRepository returns the MongoDB webhook repository
+func NewRepository() *Repository {
+	return &Repository{}
+}
+
+var _ store.WebhookRepository = (*Repository)(nil)
+
+// withoutSecret leaves the secret out of the webhooks read back
+var withoutSecret = bson.M{"secret": 0}
+
+// ListWebhooks returns the webhooks of owner
+func (r *Repository) ListWebhooks(ctx context.Context, owner primitive.ObjectID) ([]models.Webhook, error) {
+	cursor, err := store.Webhooks().Find(ctx, bson.M{"ownerId": owner}, options.Find().SetProjection(withoutSecret))
+	if err != nil {
+		return nil, err
+	}
+	hooks := []models.Webhook{}
+	if err := cursor.All(ctx, &hooks); err != nil {
+		return nil, err
+	}
+	return hooks, nil
+}
+
+// GetWebhook returns a webhook of owner by ID
+func (r *Repository) GetWebhook(ctx context.Context, id, owner primitive.ObjectID) (*models.Webhook, error) {
+	var webhook models.Webhook
+	err := store.Webhooks().FindOne(ctx, bson.M{"_id": id, "ownerId": owner}, options.FindOne().SetProjection(withoutSecret)).Decode(&webhook)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, store.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// CreateWebhook stores a new webhook
+func (r *Repository) CreateWebhook(ctx context.Context, webhook models.Webhook) (*models.Webhook, error) {
+	webhook.ID = primitive.NilObjectID
+	result, err := store.Webhooks().InsertOne(ctx, webhook)
+	if err != nil {
+		return nil, err
+	}
+	webhook.ID = result.InsertedID.(primitive.ObjectID)
+	return &webhook, nil
+}
+
+// UpdateWebhook replaces a webhook of webhook.OwnerID, keeping its secret
+// unless a new one is given
+func (r *Repository) UpdateWebhook(ctx context.Context, id primitive.ObjectID, webhook models.Webhook) (*models.Webhook, error) {
+	update := bson.M{
+		"url":    webhook.URL,
+		"events": webhook.Events,
+		"active": webhook.Active,
+	}
+	if webhook.Secret != "" {
+		update["secret"] = webhook.Secret
+	}
+
+	var updated models.Webhook
+	opts := options.FindOneAndUpdate().
+		SetProjection(withoutSecret).
+		SetReturnDocument(options.After)
+	err := store.Webhooks().FindOneAndUpdate(ctx, bson.M{"_id": id, "ownerId": webhook.OwnerID}, bson.M{"$set": update}, opts).Decode(&updated)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, store.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// DeleteWebhook deletes a webhook of owner with its deliveries
+func (r *Repository) DeleteWebhook(ctx context.Context, id, owner primitive.ObjectID) error {
+	result, err := store.Webhooks().DeleteOne(ctx, bson.M{"_id": id, "ownerId": owner})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return store.ErrNotFound
+	}
+
+	// Pending deliveries would fail anyway once the webhook is gone
+	_, err = store.WebhookDeliveries().DeleteMany(ctx, bson.M{"webhookId": id})
+	return err
+}
+
+// ListDeliveries returns a page of the deliveries of a webhook
+func (r *Repository) ListDeliveries(ctx context.Context, webhookId primitive.ObjectID, status string, req store.PageRequest) (*store.Page[models.WebhookDelivery], error) {
+	query := bson.M{"webhookId": webhookId}
+	if status != "" {
+		query["status"] = status
+	}
+	return store.Paginate[models.WebhookDelivery](ctx, store.WebhookDeliveries(), query, nil, req)
+}
+
+// GetDelivery returns a delivery of a webhook
+func (r *Repository) GetDelivery(ctx context.Context, webhookId, id primitive.ObjectID) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	err := store.WebhookDeliveries().FindOne(ctx, bson.M{"_id": id, "webhookId": webhookId}).Decode(&delivery)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, store.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// Redeliver queues a new delivery of the same event and payload, with a
+// fresh set of attempts
+func (r *Repository) Redeliver(ctx context.Context, original *models.WebhookDelivery) (*models.WebhookDelivery, error) {
+	now := time.Now().UTC()
+	delivery := models.WebhookDelivery{
+		WebhookID:     original.WebhookID,
+		EventID:       original.EventID,
+		EventType:     original.EventType,
+		Payload:       original.Payload,
+		Status:        models.DeliveryPending,
+		Attempts:      []models.DeliveryAttempt{},
+		NextAttemptAt: &now,
+		RedeliveryOf:  &original.ID,
+		CreatedAt:     now,
+	}
+	if err := Queue(ctx, &delivery); err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
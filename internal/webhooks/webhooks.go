@@ -0,0 +1,43 @@
+// Package webhooks delivers outbox events to webhook subscriptions.
+//
+// Fanout turns each outbox event into one delivery per subscribed webhook,
+// and queues a job for each. Delivery jobs POST with an HMAC signature and
+// the job queue retries failures with exponential backoff, so any number
+// of app replicas can deliver side by side.
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+// Headers sent with every delivery
+const (
+	HeaderEvent     = "X-Tasky-Event"
+	HeaderDelivery  = "X-Tasky-Delivery"
+	HeaderTimestamp = "X-Tasky-Timestamp"
+	HeaderSignature = "X-Tasky-Signature"
+)
+
+// Sign returns the signature of a delivery: the hex HMAC-SHA256 of
+// "<timestamp>.<body>" keyed with the webhook secret, prefixed with "sha256=".
+// Receivers recompute it and reject old timestamps to stop replays.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewSecret returns a random signing secret
+func NewSecret() string {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(raw)
+}
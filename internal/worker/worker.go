@@ -0,0 +1,35 @@
+// Package worker runs the background loops of the app
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// Poll calls next until it reports there's nothing left or fails, then waits
+// for interval before trying again. It returns once ctx is done.
+// what names the work in error logs.
+func Poll(ctx context.Context, interval time.Duration, what string, next func(ctx context.Context) (bool, error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			more, err := next(ctx)
+			if err != nil {
+				log.Error("Error "+what+": ", err)
+			}
+			if !more || err != nil || ctx.Err() != nil {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}